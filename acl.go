@@ -0,0 +1,116 @@
+package imap
+
+// Right describes a set of operations controlled by the IMAP ACL extension.
+//
+// See RFC 4314 section 2.1.
+type Right byte
+
+const (
+	RightLookup         Right = 'l' // mailbox is visible to LIST/LSUB commands, SUBSCRIBE mailbox
+	RightRead           Right = 'r' // SELECT the mailbox, perform STATUS
+	RightSeen           Right = 's' // keep seen/unseen information across sessions (set or clear \SEEN flag via STORE, also set \SEEN during APPEND/COPY/FETCH BODY[...])
+	RightWrite          Right = 'w' // set or clear flags other than \SEEN and \DELETED via STORE, also set them during APPEND/COPY
+	RightInsert         Right = 'i' // perform APPEND, COPY into mailbox
+	RightPost           Right = 'p' // send mail to submission address for mailbox
+	RightCreateMailbox  Right = 'k' // CREATE new sub-mailboxes in any implementation-defined hierarchy, parent mailbox for the new mailbox name in RENAME
+	RightDeleteMailbox  Right = 'x' // DELETE mailbox, old mailbox name in RENAME
+	RightDeleteMessages Right = 't' // set or clear \DELETED flag via STORE, set \DELETED flag during APPEND/COPY
+	RightExpunge        Right = 'e' // perform EXPUNGE and expunge as a part of CLOSE
+	RightAdminister     Right = 'a' // perform SETACL/DELETEACL/GETACL/LISTRIGHTS
+)
+
+// RightSetAll contains all standard rights.
+var RightSetAll = RightSet{
+	RightLookup,
+	RightRead,
+	RightSeen,
+	RightWrite,
+	RightInsert,
+	RightPost,
+	RightCreateMailbox,
+	RightDeleteMailbox,
+	RightDeleteMessages,
+	RightExpunge,
+	RightAdminister,
+}
+
+// RightSet is a set of rights.
+type RightSet []Right
+
+// String returns a string representation of the right set.
+func (r RightSet) String() string {
+	b := make([]byte, len(r))
+	for i, right := range r {
+		b[i] = byte(right)
+	}
+	return string(b)
+}
+
+// Has checks whether the right set contains a right.
+func (r RightSet) Has(right Right) bool {
+	for _, other := range r {
+		if other == right {
+			return true
+		}
+	}
+	return false
+}
+
+// Add returns a new right set containing rights from both sets.
+func (r RightSet) Add(rights RightSet) RightSet {
+	newRights := make(RightSet, len(r), len(r)+len(rights))
+	copy(newRights, r)
+	for _, right := range rights {
+		if !newRights.Has(right) {
+			newRights = append(newRights, right)
+		}
+	}
+	return newRights
+}
+
+// Remove returns a new right set containing all rights in r except these in
+// the provided set.
+func (r RightSet) Remove(rights RightSet) RightSet {
+	var newRights RightSet
+	for _, right := range r {
+		if !rights.Has(right) {
+			newRights = append(newRights, right)
+		}
+	}
+	return newRights
+}
+
+// RightModification indicates how to mutate a right set.
+type RightModification byte
+
+const (
+	RightModificationReplace RightModification = 0
+	RightModificationAdd     RightModification = '+'
+	RightModificationRemove  RightModification = '-'
+)
+
+// RightsIdentifier is an ACL identifier.
+type RightsIdentifier string
+
+// RightsIdentifierAnyone is the universal identity (matches everyone).
+const RightsIdentifierAnyone RightsIdentifier = "anyone"
+
+// GetACLData is the data returned by the GETACL command.
+type GetACLData struct {
+	Mailbox string
+	Rights  map[RightsIdentifier]RightSet
+}
+
+// ListRightsData is the data returned by the LISTRIGHTS command.
+type ListRightsData struct {
+	Mailbox        string
+	Identifier     RightsIdentifier
+	RequiredRights RightSet
+	OptionalRights []RightSet
+}
+
+// MyRightsData is the data returned by the MYRIGHTS command.
+type MyRightsData struct {
+	Mailbox string
+	Rights  RightSet
+}
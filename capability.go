@@ -40,7 +40,10 @@ const (
 	CapBinary           Cap = "BINARY"             // RFC 3516
 	CapCatenate         Cap = "CATENATE"           // RFC 4469
 	CapChildren         Cap = "CHILDREN"           // RFC 3348
+	CapCompressDeflate  Cap = "COMPRESS=DEFLATE"   // RFC 4978
 	CapCondStore        Cap = "CONDSTORE"          // RFC 7162
+	CapContextSearch    Cap = "CONTEXT=SEARCH"     // RFC 5267
+	CapContextSort      Cap = "CONTEXT=SORT"       // RFC 5267
 	CapConvert          Cap = "CONVERT"            // RFC 5259
 	CapCreateSpecialUse Cap = "CREATE-SPECIAL-USE" // RFC 6154
 	CapESort            Cap = "ESORT"              // RFC 5267
@@ -57,6 +60,7 @@ const (
 	CapMultiSearch      Cap = "MULTISEARCH"        // RFC 7377
 	CapNotify           Cap = "NOTIFY"             // RFC 5465
 	CapObjectID         Cap = "OBJECTID"           // RFC 8474
+	CapPartial          Cap = "PARTIAL"            // RFC 9394
 	CapPreview          Cap = "PREVIEW"            // RFC 8970
 	CapQResync          Cap = "QRESYNC"            // RFC 7162
 	CapQuota            Cap = "QUOTA"              // RFC 9208
@@ -67,6 +71,7 @@ const (
 	CapSort             Cap = "SORT"               // RFC 5256
 	CapSortDisplay      Cap = "SORT=DISPLAY"       // RFC 5957
 	CapSpecialUse       Cap = "SPECIAL-USE"        // RFC 6154
+	CapUIDOnly          Cap = "UIDONLY"            // RFC 9586
 	CapUnauthenticate   Cap = "UNAUTHENTICATE"     // RFC 8437
 	CapURLPartial       Cap = "URL-PARTIAL"        // RFC 5550
 	CapURLAuth          Cap = "URLAUTH"            // RFC 4467
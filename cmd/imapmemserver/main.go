@@ -21,6 +21,7 @@ var (
 	password     string
 	debug        bool
 	insecureAuth bool
+	specialUse   bool
 )
 
 func main() {
@@ -31,6 +32,7 @@ func main() {
 	flag.StringVar(&password, "password", "user", "Password")
 	flag.BoolVar(&debug, "debug", false, "Print all commands and responses")
 	flag.BoolVar(&insecureAuth, "insecure-auth", false, "Allow authentication without TLS")
+	flag.BoolVar(&specialUse, "special-use", true, "Create the standard special-use mailboxes")
 	flag.Parse()
 
 	var tlsConfig *tls.Config
@@ -55,6 +57,9 @@ func main() {
 	if username != "" || password != "" {
 		user := imapmemserver.NewUser(username, password)
 		user.Create("INBOX", nil)
+		if specialUse {
+			createSpecialUseMailboxes(user)
+		}
 		memServer.AddUser(user)
 	}
 
@@ -68,8 +73,38 @@ func main() {
 			return memServer.NewSession(), nil, nil
 		},
 		Caps: imap.CapSet{
-			imap.CapIMAP4rev1: {},
-			imap.CapIMAP4rev2: {},
+			imap.CapIMAP4rev1:        {},
+			imap.CapIMAP4rev2:        {},
+			imap.CapCondStore:        {},
+			imap.CapQResync:          {},
+			imap.CapSort:             {},
+			"THREAD=ORDEREDSUBJECT":  {},
+			"THREAD=REFERENCES":      {},
+			imap.CapESort:            {},
+			imap.CapQuota:            {},
+			imap.CapMetadata:         {},
+			imap.CapACL:              {},
+			imap.CapID:               {},
+			imap.CapNotify:           {},
+			imap.CapMultiAppend:      {},
+			imap.CapCatenate:         {},
+			imap.CapURLAuth:          {},
+			imap.CapURLPartial:       {},
+			imap.CapReplace:          {},
+			imap.CapPreview:          {},
+			imap.CapObjectID:         {},
+			imap.CapSaveDate:         {},
+			imap.CapSpecialUse:       {},
+			imap.CapCreateSpecialUse: {},
+			imap.CapWithin:           {},
+			imap.CapSearchFuzzy:      {},
+			imap.CapPartial:          {},
+			imap.CapContextSearch:    {},
+			imap.CapContextSort:      {},
+			imap.CapFilters:          {},
+			imap.CapMultiSearch:      {},
+			imap.CapUIDOnly:          {},
+			imap.CapCompressDeflate:  {},
 		},
 		TLSConfig:    tlsConfig,
 		InsecureAuth: insecureAuth,
@@ -79,3 +114,22 @@ func main() {
 		log.Fatalf("Serve() = %v", err)
 	}
 }
+
+func createSpecialUseMailboxes(user *imapmemserver.User) {
+	mailboxes := []struct {
+		name string
+		attr imap.MailboxAttr
+	}{
+		{"Sent", imap.MailboxAttrSent},
+		{"Drafts", imap.MailboxAttrDrafts},
+		{"Trash", imap.MailboxAttrTrash},
+		{"Junk", imap.MailboxAttrJunk},
+		{"Archive", imap.MailboxAttrArchive},
+	}
+	for _, mbox := range mailboxes {
+		options := imap.CreateOptions{SpecialUse: []imap.MailboxAttr{mbox.attr}}
+		if err := user.Create(mbox.name, &options); err != nil {
+			log.Fatalf("Failed to create mailbox %q: %v", mbox.name, err)
+		}
+	}
+}
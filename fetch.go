@@ -19,8 +19,14 @@ type FetchOptions struct {
 	BinarySection     []*FetchItemBinarySection     // requires IMAP4rev2 or BINARY
 	BinarySectionSize []*FetchItemBinarySectionSize // requires IMAP4rev2 or BINARY
 	ModSeq            bool                          // requires CONDSTORE
-
-	ChangedSince uint64 // requires CONDSTORE
+	Preview           *FetchItemPreview             // requires PREVIEW
+	EmailID           bool                          // requires OBJECTID
+	ThreadID          bool                          // requires OBJECTID
+	SaveDate          bool                          // requires SAVEDATE
+
+	ChangedSince uint64        // requires CONDSTORE
+	Vanished     bool          // requires QRESYNC and ChangedSince
+	Partial      *PartialRange // requires PARTIAL and a UID set
 }
 
 // FetchItemBodyStructure contains FETCH options for the body structure.
@@ -28,6 +34,12 @@ type FetchItemBodyStructure struct {
 	Extended bool
 }
 
+// FetchItemPreview contains FETCH options for the message preview.
+type FetchItemPreview struct {
+	// Only return the preview if it's readily available, NIL otherwise
+	Lazy bool
+}
+
 // PartSpecifier describes whether to fetch a part's header, body, or both.
 type PartSpecifier string
 
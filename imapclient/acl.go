@@ -0,0 +1,226 @@
+package imapclient
+
+import (
+	"fmt"
+
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/internal/imapwire"
+)
+
+// MyRights sends a MYRIGHTS command.
+//
+// This command requires support for the ACL extension.
+func (c *Client) MyRights(mailbox string) *MyRightsCommand {
+	cmd := &MyRightsCommand{mailbox: mailbox}
+	enc := c.beginCommand("MYRIGHTS", cmd)
+	enc.SP().Mailbox(mailbox)
+	enc.end()
+	return cmd
+}
+
+// SetACL sends a SETACL command.
+//
+// This command requires support for the ACL extension.
+func (c *Client) SetACL(mailbox string, ri imap.RightsIdentifier, rm imap.RightModification, rs imap.RightSet) *Command {
+	cmd := &Command{}
+	enc := c.beginCommand("SETACL", cmd)
+	rights := rs.String()
+	if rm != imap.RightModificationReplace {
+		rights = string(rm) + rights
+	}
+	enc.SP().Mailbox(mailbox).SP().String(string(ri)).SP().String(rights)
+	enc.end()
+	return cmd
+}
+
+// DeleteACL sends a DELETEACL command.
+//
+// This command requires support for the ACL extension.
+func (c *Client) DeleteACL(mailbox string, ri imap.RightsIdentifier) *Command {
+	cmd := &Command{}
+	enc := c.beginCommand("DELETEACL", cmd)
+	enc.SP().Mailbox(mailbox).SP().String(string(ri))
+	enc.end()
+	return cmd
+}
+
+// GetACL sends a GETACL command.
+//
+// This command requires support for the ACL extension.
+func (c *Client) GetACL(mailbox string) *GetACLCommand {
+	cmd := &GetACLCommand{mailbox: mailbox}
+	enc := c.beginCommand("GETACL", cmd)
+	enc.SP().Mailbox(mailbox)
+	enc.end()
+	return cmd
+}
+
+// ListRights sends a LISTRIGHTS command.
+//
+// This command requires support for the ACL extension.
+func (c *Client) ListRights(mailbox string, ri imap.RightsIdentifier) *ListRightsCommand {
+	cmd := &ListRightsCommand{mailbox: mailbox, identifier: ri}
+	enc := c.beginCommand("LISTRIGHTS", cmd)
+	enc.SP().Mailbox(mailbox).SP().String(string(ri))
+	enc.end()
+	return cmd
+}
+
+func (c *Client) handleMyRights() error {
+	data, err := readMyRights(c.dec)
+	if err != nil {
+		return fmt.Errorf("in myrights-response: %v", err)
+	}
+	cmd := c.findPendingCmdFunc(func(anyCmd command) bool {
+		cmd, ok := anyCmd.(*MyRightsCommand)
+		return ok && cmd.mailbox == data.Mailbox
+	})
+	if cmd != nil {
+		cmd := cmd.(*MyRightsCommand)
+		cmd.data = data
+	}
+	return nil
+}
+
+func (c *Client) handleGetACL() error {
+	data, err := readGetACL(c.dec)
+	if err != nil {
+		return fmt.Errorf("in acl-response: %v", err)
+	}
+	cmd := c.findPendingCmdFunc(func(anyCmd command) bool {
+		cmd, ok := anyCmd.(*GetACLCommand)
+		return ok && cmd.mailbox == data.Mailbox
+	})
+	if cmd != nil {
+		cmd := cmd.(*GetACLCommand)
+		cmd.data = data
+	}
+	return nil
+}
+
+func (c *Client) handleListRights() error {
+	data, err := readListRights(c.dec)
+	if err != nil {
+		return fmt.Errorf("in listrights-response: %v", err)
+	}
+	cmd := c.findPendingCmdFunc(func(anyCmd command) bool {
+		cmd, ok := anyCmd.(*ListRightsCommand)
+		return ok && cmd.mailbox == data.Mailbox && cmd.identifier == data.Identifier
+	})
+	if cmd != nil {
+		cmd := cmd.(*ListRightsCommand)
+		cmd.data = data
+	}
+	return nil
+}
+
+// MyRightsCommand is a MYRIGHTS command.
+type MyRightsCommand struct {
+	cmd
+	mailbox string
+	data    *imap.MyRightsData
+}
+
+func (cmd *MyRightsCommand) Wait() (*imap.MyRightsData, error) {
+	if err := cmd.cmd.Wait(); err != nil {
+		return nil, err
+	}
+	return cmd.data, nil
+}
+
+// GetACLCommand is a GETACL command.
+type GetACLCommand struct {
+	cmd
+	mailbox string
+	data    *imap.GetACLData
+}
+
+func (cmd *GetACLCommand) Wait() (*imap.GetACLData, error) {
+	if err := cmd.cmd.Wait(); err != nil {
+		return nil, err
+	}
+	return cmd.data, nil
+}
+
+// ListRightsCommand is a LISTRIGHTS command.
+type ListRightsCommand struct {
+	cmd
+	mailbox    string
+	identifier imap.RightsIdentifier
+	data       *imap.ListRightsData
+}
+
+func (cmd *ListRightsCommand) Wait() (*imap.ListRightsData, error) {
+	if err := cmd.cmd.Wait(); err != nil {
+		return nil, err
+	}
+	return cmd.data, nil
+}
+
+func readMyRights(dec *imapwire.Decoder) (*imap.MyRightsData, error) {
+	var data imap.MyRightsData
+	if !dec.ExpectMailbox(&data.Mailbox) || !dec.ExpectSP() {
+		return nil, dec.Err()
+	}
+	rights, err := readRightSet(dec)
+	if err != nil {
+		return nil, err
+	}
+	data.Rights = rights
+	return &data, nil
+}
+
+func readGetACL(dec *imapwire.Decoder) (*imap.GetACLData, error) {
+	data := imap.GetACLData{Rights: make(map[imap.RightsIdentifier]imap.RightSet)}
+	if !dec.ExpectMailbox(&data.Mailbox) {
+		return nil, dec.Err()
+	}
+	for dec.SP() {
+		var ri string
+		if !dec.ExpectAString(&ri) || !dec.ExpectSP() {
+			return nil, dec.Err()
+		}
+		rights, err := readRightSet(dec)
+		if err != nil {
+			return nil, err
+		}
+		data.Rights[imap.RightsIdentifier(ri)] = rights
+	}
+	return &data, nil
+}
+
+func readListRights(dec *imapwire.Decoder) (*imap.ListRightsData, error) {
+	var (
+		data imap.ListRightsData
+		ri   string
+	)
+	if !dec.ExpectMailbox(&data.Mailbox) || !dec.ExpectSP() || !dec.ExpectAString(&ri) || !dec.ExpectSP() {
+		return nil, dec.Err()
+	}
+	data.Identifier = imap.RightsIdentifier(ri)
+	rights, err := readRightSet(dec)
+	if err != nil {
+		return nil, err
+	}
+	data.RequiredRights = rights
+	for dec.SP() {
+		rights, err := readRightSet(dec)
+		if err != nil {
+			return nil, err
+		}
+		data.OptionalRights = append(data.OptionalRights, rights)
+	}
+	return &data, nil
+}
+
+func readRightSet(dec *imapwire.Decoder) (imap.RightSet, error) {
+	var s string
+	if !dec.ExpectAString(&s) {
+		return nil, dec.Err()
+	}
+	rights := make(imap.RightSet, len(s))
+	for i := 0; i < len(s); i++ {
+		rights[i] = imap.Right(s[i])
+	}
+	return rights, nil
+}
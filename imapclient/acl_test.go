@@ -0,0 +1,156 @@
+package imapclient_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/imapclient"
+	"github.com/emersion/go-imap/v2/imapserver/imapmemserver"
+)
+
+func TestACL(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateAuthenticated)
+	defer client.Close()
+	defer server.Close()
+
+	if !client.Caps().Has(imap.CapACL) {
+		t.Skip("ACL not supported")
+	}
+
+	myRights, err := client.MyRights("INBOX").Wait()
+	if err != nil {
+		t.Fatalf("MyRights().Wait() = %v", err)
+	} else if !myRights.Rights.Has(imap.RightAdminister) {
+		t.Fatalf("MyRights().Wait() = %v, want administer right", myRights.Rights)
+	}
+
+	rights := imap.RightSet{imap.RightLookup, imap.RightRead}
+	if err := client.SetACL("INBOX", "other", imap.RightModificationReplace, rights).Wait(); err != nil {
+		t.Fatalf("SetACL().Wait() = %v", err)
+	}
+
+	acl, err := client.GetACL("INBOX").Wait()
+	if err != nil {
+		t.Fatalf("GetACL().Wait() = %v", err)
+	} else if got := acl.Rights["other"].String(); got != rights.String() {
+		t.Errorf("GetACL().Wait() rights for other = %q, want %q", got, rights.String())
+	}
+
+	if _, err := client.ListRights("INBOX", "other").Wait(); err != nil {
+		t.Fatalf("ListRights().Wait() = %v", err)
+	}
+
+	if err := client.DeleteACL("INBOX", "other").Wait(); err != nil {
+		t.Fatalf("DeleteACL().Wait() = %v", err)
+	}
+
+	// Revoke our own insert right
+	noInsert := imap.RightSet{imap.RightInsert}
+	if err := client.SetACL("INBOX", testUsername, imap.RightModificationRemove, noInsert).Wait(); err != nil {
+		t.Fatalf("SetACL().Wait() = %v", err)
+	}
+
+	appendCmd := client.Append("INBOX", int64(len(simpleRawMessage)), nil)
+	appendCmd.Write([]byte(simpleRawMessage))
+	appendCmd.Close()
+	_, err = appendCmd.Wait()
+	var imapErr *imap.Error
+	if !errors.As(err, &imapErr) || imapErr.Code != imap.ResponseCodeNoPerm {
+		t.Errorf("AppendCommand.Wait() = %v, want NOPERM error", err)
+	}
+}
+
+func TestACL_shared(t *testing.T) {
+	const otherUsername, otherPassword = "other-user", "other-password"
+
+	memServer := imapmemserver.New()
+	owner := imapmemserver.NewUser(testUsername, testPassword)
+	owner.Create("INBOX", nil)
+	memServer.AddUser(owner)
+	other := imapmemserver.NewUser(otherUsername, otherPassword)
+	other.Create("INBOX", nil)
+	memServer.AddUser(other)
+
+	shared := imapmemserver.NewMailbox("Shared", 1)
+	owner.AddMailbox(shared)
+	other.AddMailbox(shared)
+
+	login := func(username, password string) *imapclient.Client {
+		conn, server := serveMemServer(t, memServer)
+		t.Cleanup(func() { server.Close() })
+		client := imapclient.New(conn, nil)
+		t.Cleanup(func() { client.Close() })
+		if err := client.Login(username, password).Wait(); err != nil {
+			t.Fatalf("Login().Wait() = %v", err)
+		}
+		return client
+	}
+	appendMessage := func(client *imapclient.Client, mailbox string) {
+		appendCmd := client.Append(mailbox, int64(len(simpleRawMessage)), nil)
+		appendCmd.Write([]byte(simpleRawMessage))
+		appendCmd.Close()
+		if _, err := appendCmd.Wait(); err != nil {
+			t.Fatalf("AppendCommand.Wait() = %v", err)
+		}
+	}
+	checkNoPerm := func(name string, err error) {
+		var imapErr *imap.Error
+		if !errors.As(err, &imapErr) || imapErr.Code != imap.ResponseCodeNoPerm {
+			t.Errorf("%v = %v, want NOPERM error", name, err)
+		}
+	}
+
+	ownerClient := login(testUsername, testPassword)
+	appendMessage(ownerClient, "Shared")
+	rights := imap.RightSet{imap.RightLookup, imap.RightRead}
+	if err := ownerClient.SetACL("Shared", otherUsername, imap.RightModificationReplace, rights).Wait(); err != nil {
+		t.Fatalf("SetACL().Wait() = %v", err)
+	}
+
+	otherClient := login(otherUsername, otherPassword)
+	appendMessage(otherClient, "INBOX")
+
+	if _, err := otherClient.Select("INBOX", nil).Wait(); err != nil {
+		t.Fatalf("Select().Wait() = %v", err)
+	}
+	_, err := otherClient.Copy(imap.SeqSetNum(1), "Shared").Wait()
+	checkNoPerm("Copy().Wait()", err)
+
+	if _, err := otherClient.Select("Shared", nil).Wait(); err != nil {
+		t.Fatalf("Select().Wait() = %v", err)
+	}
+	storeFlags := imap.StoreFlags{
+		Op:     imap.StoreFlagsAdd,
+		Flags:  []imap.Flag{imap.FlagDeleted},
+		Silent: true,
+	}
+	checkNoPerm("Store().Close()", otherClient.Store(imap.SeqSetNum(1), &storeFlags, nil).Close())
+	checkNoPerm("Expunge().Close()", otherClient.Expunge().Close())
+	_, err = otherClient.Move(imap.SeqSetNum(1), "INBOX").Wait()
+	checkNoPerm("Move().Wait()", err)
+	if err := otherClient.Unselect().Wait(); err != nil {
+		t.Fatalf("Unselect().Wait() = %v", err)
+	}
+
+	if _, err := otherClient.Status("Shared", &imap.StatusOptions{NumMessages: true}).Wait(); err != nil {
+		t.Errorf("Status().Wait() = %v", err)
+	}
+	if _, err := otherClient.GetMetadata("Shared", []string{"/shared/comment"}, nil).Wait(); err != nil {
+		t.Errorf("GetMetadata().Wait() = %v", err)
+	}
+	comment := []byte("Hijacked")
+	checkNoPerm("SetMetadata().Wait()", otherClient.SetMetadata("Shared", map[string]*[]byte{"/shared/comment": &comment}).Wait())
+	checkNoPerm("Rename().Wait()", otherClient.Rename("Shared", "Stolen").Wait())
+	checkNoPerm("Delete().Wait()", otherClient.Delete("Shared").Wait())
+
+	if err := ownerClient.SetACL("Shared", otherUsername, imap.RightModificationReplace, imap.RightSet{imap.RightLookup}).Wait(); err != nil {
+		t.Fatalf("SetACL().Wait() = %v", err)
+	}
+	_, err = otherClient.Select("Shared", nil).Wait()
+	checkNoPerm("Select().Wait()", err)
+	_, err = otherClient.Status("Shared", &imap.StatusOptions{NumMessages: true}).Wait()
+	checkNoPerm("Status().Wait()", err)
+	_, err = otherClient.GetMetadata("Shared", []string{"/shared/comment"}, nil).Wait()
+	checkNoPerm("GetMetadata().Wait()", err)
+}
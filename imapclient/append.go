@@ -16,18 +16,22 @@ func (c *Client) Append(mailbox string, size int64, options *imap.AppendOptions)
 	cmd := &AppendCommand{}
 	cmd.enc = c.beginCommand("APPEND", cmd)
 	cmd.enc.SP().Mailbox(mailbox).SP()
+	writeAppendOptions(cmd.enc, options)
+	// TODO: literal8 for BINARY
+	// TODO: UTF8 data ext for UTF8=ACCEPT, with literal8
+	cmd.wc = cmd.enc.Literal(size)
+	return cmd
+}
+
+func writeAppendOptions(enc *commandEncoder, options *imap.AppendOptions) {
 	if options != nil && len(options.Flags) > 0 {
-		cmd.enc.List(len(options.Flags), func(i int) {
-			cmd.enc.Flag(options.Flags[i])
+		enc.List(len(options.Flags), func(i int) {
+			enc.Flag(options.Flags[i])
 		}).SP()
 	}
 	if options != nil && !options.Time.IsZero() {
-		cmd.enc.String(options.Time.Format(internal.DateTimeLayout)).SP()
+		enc.String(options.Time.Format(internal.DateTimeLayout)).SP()
 	}
-	// TODO: literal8 for BINARY
-	// TODO: UTF8 data ext for UTF8=ACCEPT, with literal8
-	cmd.wc = cmd.enc.Literal(size)
-	return cmd
 }
 
 // AppendCommand is an APPEND command.
@@ -56,3 +60,60 @@ func (cmd *AppendCommand) Close() error {
 func (cmd *AppendCommand) Wait() (*imap.AppendData, error) {
 	return &cmd.data, cmd.cmd.Wait()
 }
+
+// MultiAppend sends an APPEND command with multiple messages.
+//
+// For each message, the caller must call MultiAppendCommand.CreateMessage,
+// write the message contents and close the returned writer. Once all messages
+// have been written, the caller must call MultiAppendCommand.Close.
+//
+// This command requires support for the MULTIAPPEND extension.
+func (c *Client) MultiAppend(mailbox string) *MultiAppendCommand {
+	cmd := &MultiAppendCommand{}
+	cmd.enc = c.beginCommand("APPEND", cmd)
+	cmd.enc.SP().Mailbox(mailbox)
+	return cmd
+}
+
+// MultiAppendCommand is an APPEND command with multiple messages.
+type MultiAppendCommand struct {
+	cmd
+	enc  *commandEncoder
+	n    int
+	data imap.MultiAppendData
+}
+
+// CreateMessage appends a new message to the command.
+//
+// The caller must write the message contents and close the returned writer
+// before calling CreateMessage again.
+//
+// The options are optional.
+func (cmd *MultiAppendCommand) CreateMessage(size int64, options *imap.AppendOptions) io.WriteCloser {
+	cmd.n++
+	cmd.enc.SP()
+	writeAppendOptions(cmd.enc, options)
+	return cmd.enc.Literal(size)
+}
+
+// Close ends the command.
+//
+// At least one message must have been created.
+func (cmd *MultiAppendCommand) Close() error {
+	if cmd.n == 0 {
+		panic("imapclient: MultiAppendCommand.Close called without any message")
+	}
+	if cmd.enc != nil {
+		cmd.enc.end()
+		cmd.enc = nil
+	}
+	return nil
+}
+
+// Wait blocks until the command has completed.
+//
+// If the server supports UIDPLUS, the UIDs of the appended messages are
+// returned in order.
+func (cmd *MultiAppendCommand) Wait() (*imap.MultiAppendData, error) {
+	return &cmd.data, cmd.cmd.Wait()
+}
@@ -26,3 +26,47 @@ func TestAppend(t *testing.T) {
 
 	// TODO: fetch back message and check body
 }
+
+func TestMultiAppend(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateAuthenticated)
+	defer client.Close()
+	defer server.Close()
+
+	if !client.Caps().Has(imap.CapMultiAppend) {
+		t.Skip("MULTIAPPEND not supported")
+	}
+
+	bodies := []string{"First message.", "Second message.", "Third message."}
+
+	appendCmd := client.MultiAppend("INBOX")
+	for i, body := range bodies {
+		options := &imap.AppendOptions{}
+		if i == 1 {
+			options.Flags = []imap.Flag{imap.FlagSeen}
+		}
+		w := appendCmd.CreateMessage(int64(len(body)), options)
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatalf("Write() = %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close() = %v", err)
+		}
+	}
+	if err := appendCmd.Close(); err != nil {
+		t.Fatalf("MultiAppendCommand.Close() = %v", err)
+	}
+	data, err := appendCmd.Wait()
+	if err != nil {
+		t.Fatalf("MultiAppendCommand.Wait() = %v", err)
+	}
+	if uids, ok := data.UIDs.Nums(); !ok || len(uids) != len(bodies) {
+		t.Errorf("UIDs = %v, want %v UIDs", data.UIDs, len(bodies))
+	}
+
+	selectData, err := client.Select("INBOX", nil).Wait()
+	if err != nil {
+		t.Fatalf("Select().Wait() = %v", err)
+	} else if selectData.NumMessages != uint32(1+len(bodies)) {
+		t.Errorf("NumMessages = %v, want %v", selectData.NumMessages, 1+len(bodies))
+	}
+}
@@ -0,0 +1,76 @@
+package imapclient
+
+import (
+	"io"
+
+	"github.com/emersion/go-imap/v2"
+)
+
+// Catenate sends an APPEND command with a message composed of multiple
+// parts. Each part is either text sent by the client, or an IMAP URL
+// referencing a message or message section on the server.
+//
+// The caller must add at least one part with CatenateCommand.WriteURL or
+// CatenateCommand.CreateText, then call CatenateCommand.Close.
+//
+// The options are optional.
+//
+// This command requires support for the CATENATE extension.
+func (c *Client) Catenate(mailbox string, options *imap.AppendOptions) *CatenateCommand {
+	cmd := &CatenateCommand{}
+	cmd.enc = c.beginCommand("APPEND", cmd)
+	cmd.enc.SP().Mailbox(mailbox).SP()
+	writeAppendOptions(cmd.enc, options)
+	cmd.enc.Atom("CATENATE").SP().Special('(')
+	return cmd
+}
+
+// CatenateCommand is an APPEND command with a CATENATE message.
+type CatenateCommand struct {
+	cmd
+	enc  *commandEncoder
+	n    int
+	data imap.AppendData
+}
+
+func (cmd *CatenateCommand) beginPart(typ string) {
+	if cmd.n > 0 {
+		cmd.enc.SP()
+	}
+	cmd.n++
+	cmd.enc.Atom(typ).SP()
+}
+
+// WriteURL adds a part referencing a message or message section with an IMAP
+// URL.
+func (cmd *CatenateCommand) WriteURL(url string) {
+	cmd.beginPart("URL")
+	cmd.enc.String(url)
+}
+
+// CreateText adds a text part.
+//
+// The caller must write the text and close the returned writer before adding
+// another part.
+func (cmd *CatenateCommand) CreateText(size int64) io.WriteCloser {
+	cmd.beginPart("TEXT")
+	return cmd.enc.Literal(size)
+}
+
+// Close ends the command.
+func (cmd *CatenateCommand) Close() error {
+	if cmd.n == 0 {
+		panic("imapclient: CatenateCommand.Close called without any part")
+	}
+	if cmd.enc != nil {
+		cmd.enc.Special(')')
+		cmd.enc.end()
+		cmd.enc = nil
+	}
+	return nil
+}
+
+// Wait blocks until the command has completed.
+func (cmd *CatenateCommand) Wait() (*imap.AppendData, error) {
+	return &cmd.data, cmd.cmd.Wait()
+}
@@ -0,0 +1,80 @@
+package imapclient_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/emersion/go-imap/v2"
+)
+
+func TestCatenate(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateSelected)
+	defer client.Close()
+	defer server.Close()
+
+	if !client.Caps().Has(imap.CapCatenate) {
+		t.Skip("CATENATE not supported")
+	}
+
+	header := "Subject: Fwd: Your Name.\r\n\r\n"
+	appendCmd := client.Catenate("INBOX", nil)
+	w := appendCmd.CreateText(int64(len(header)))
+	if _, err := w.Write([]byte(header)); err != nil {
+		t.Fatalf("Write() = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() = %v", err)
+	}
+	appendCmd.WriteURL("/INBOX/;UID=1/;SECTION=TEXT")
+	if err := appendCmd.Close(); err != nil {
+		t.Fatalf("CatenateCommand.Close() = %v", err)
+	}
+	if _, err := appendCmd.Wait(); err != nil {
+		t.Fatalf("CatenateCommand.Wait() = %v", err)
+	}
+
+	fetchOptions := &imap.FetchOptions{
+		BodySection: []*imap.FetchItemBodySection{{}},
+	}
+	msgs, err := client.Fetch(imap.SeqSetNum(2), fetchOptions).Collect()
+	if err != nil {
+		t.Fatalf("Fetch().Collect() = %v", err)
+	} else if len(msgs) != 1 {
+		t.Fatalf("len(msgs) = %v, want 1", len(msgs))
+	}
+	var body []byte
+	for _, buf := range msgs[0].BodySection {
+		body = buf
+	}
+	rawMessage := strings.ReplaceAll(simpleRawMessage, "\n", "\r\n")
+	want := header + rawMessage[strings.Index(rawMessage, "\r\n\r\n")+4:]
+	if string(body) != want {
+		t.Errorf("body = %q, want %q", body, want)
+	}
+
+	appendCmd = client.Catenate("INBOX", nil)
+	appendCmd.WriteURL("/INBOX/;UID=42")
+	if err := appendCmd.Close(); err != nil {
+		t.Fatalf("CatenateCommand.Close() = %v", err)
+	}
+	_, err = appendCmd.Wait()
+	var imapErr *imap.Error
+	if !errors.As(err, &imapErr) || imapErr.Code != imap.ResponseCodeBadURL {
+		t.Errorf("CatenateCommand.Wait() = %v, want BADURL error", err)
+	}
+
+	appendCmd = client.Catenate("INBOX", nil)
+	appendCmd.WriteURL("/INBOX/;UID=42] Injected")
+	if err := appendCmd.Close(); err != nil {
+		t.Fatalf("CatenateCommand.Close() = %v", err)
+	}
+	_, err = appendCmd.Wait()
+	if !errors.As(err, &imapErr) || imapErr.Type != imap.StatusResponseTypeBad {
+		t.Errorf("CatenateCommand.Wait() = %v, want BAD error", err)
+	}
+
+	if err := client.Noop().Wait(); err != nil {
+		t.Errorf("Noop().Wait() = %v", err)
+	}
+}
@@ -131,7 +131,7 @@ func (options *Options) tlsConfig() *tls.Config {
 // pipelining (see above). Additionally, some commands (e.g. StartTLS,
 // Authenticate, Idle) block the client during their execution.
 type Client struct {
-	conn     net.Conn
+	conn     net.Conn // written by the reader goroutine with mutex held
 	options  Options
 	br       *bufio.Reader
 	bw       *bufio.Writer
@@ -248,19 +248,33 @@ func DialStartTLS(address string, options *Options) (*Client, error) {
 	return NewStartTLS(conn, &newOptions)
 }
 
+// netConn returns the current underlying connection, which may be replaced by
+// STARTTLS or COMPRESS.
+func (c *Client) netConn() net.Conn {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.conn
+}
+
+func (c *Client) setConn(conn net.Conn) {
+	c.mutex.Lock()
+	c.conn = conn
+	c.mutex.Unlock()
+}
+
 func (c *Client) setReadTimeout(dur time.Duration) {
 	if dur > 0 {
-		c.conn.SetReadDeadline(time.Now().Add(dur))
+		c.netConn().SetReadDeadline(time.Now().Add(dur))
 	} else {
-		c.conn.SetReadDeadline(time.Time{})
+		c.netConn().SetReadDeadline(time.Time{})
 	}
 }
 
 func (c *Client) setWriteTimeout(dur time.Duration) {
 	if dur > 0 {
-		c.conn.SetWriteDeadline(time.Now().Add(dur))
+		c.netConn().SetWriteDeadline(time.Now().Add(dur))
 	} else {
-		c.conn.SetWriteDeadline(time.Time{})
+		c.netConn().SetWriteDeadline(time.Time{})
 	}
 }
 
@@ -356,10 +370,11 @@ func (c *Client) Close() error {
 	c.mutex.Lock()
 	alreadyClosed := c.closed
 	c.closed = true
+	conn := c.conn
 	c.mutex.Unlock()
 
 	// Ignore net.ErrClosed here, because we also call conn.Close in c.read
-	if err := c.conn.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+	if err := conn.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
 		return err
 	}
 
@@ -531,7 +546,7 @@ func (c *Client) registerContReq(cmd command) *imapwire.ContinuationRequest {
 }
 
 func (c *Client) closeWithError(err error) {
-	c.conn.Close()
+	c.netConn().Close()
 
 	c.mutex.Lock()
 	c.state = imap.ConnStateLogout
@@ -601,13 +616,13 @@ func (c *Client) readResponse() error {
 	}
 
 	var (
-		token    string
-		err      error
-		startTLS *startTLSCommand
+		token   string
+		err     error
+		upgrade command
 	)
 	if tag != "" {
 		token = "response-tagged"
-		startTLS, err = c.readResponseTagged(tag, typ)
+		upgrade, err = c.readResponseTagged(tag, typ)
 	} else {
 		token = "response-data"
 		err = c.readResponseData(typ)
@@ -620,9 +635,13 @@ func (c *Client) readResponse() error {
 		return fmt.Errorf("in response: %v", c.dec.Err())
 	}
 
-	if startTLS != nil {
-		c.upgradeStartTLS(startTLS.tlsConfig)
-		close(startTLS.upgradeDone)
+	switch upgrade := upgrade.(type) {
+	case *startTLSCommand:
+		c.upgradeStartTLS(upgrade.tlsConfig)
+		close(upgrade.upgradeDone)
+	case *compressCommand:
+		c.upgradeCompress()
+		close(upgrade.upgradeDone)
 	}
 
 	return nil
@@ -653,7 +672,9 @@ func (c *Client) readContinueReq() error {
 	return nil
 }
 
-func (c *Client) readResponseTagged(tag, typ string) (startTLS *startTLSCommand, err error) {
+// readResponseTagged reads a tagged response. If the connection transport
+// needs to be upgraded (e.g. for STARTTLS), the completed command is returned.
+func (c *Client) readResponseTagged(tag, typ string) (upgrade command, err error) {
 	cmd := c.deletePendingCmdByTag(tag)
 	if cmd == nil {
 		return nil, fmt.Errorf("received tagged response with unknown tag %q", tag)
@@ -685,6 +706,13 @@ func (c *Client) readResponseTagged(tag, typ string) (startTLS *startTLSCommand,
 			}
 			c.setCaps(caps)
 		case "APPENDUID":
+			if cmd, ok := cmd.(*MultiAppendCommand); ok {
+				if !c.dec.ExpectSP() || !c.dec.ExpectNumber(&cmd.data.UIDValidity) || !c.dec.ExpectSP() || !c.dec.ExpectUIDSet(&cmd.data.UIDs) {
+					return nil, fmt.Errorf("in resp-code-apnd: %v", c.dec.Err())
+				}
+				break
+			}
+
 			var (
 				uidValidity uint32
 				uid         imap.UID
@@ -692,7 +720,11 @@ func (c *Client) readResponseTagged(tag, typ string) (startTLS *startTLSCommand,
 			if !c.dec.ExpectSP() || !c.dec.ExpectNumber(&uidValidity) || !c.dec.ExpectSP() || !c.dec.ExpectUID(&uid) {
 				return nil, fmt.Errorf("in resp-code-apnd: %v", c.dec.Err())
 			}
-			if cmd, ok := cmd.(*AppendCommand); ok {
+			switch cmd := cmd.(type) {
+			case *AppendCommand:
+				cmd.data.UID = uid
+				cmd.data.UIDValidity = uidValidity
+			case *CatenateCommand:
 				cmd.data.UID = uid
 				cmd.data.UIDValidity = uidValidity
 			}
@@ -709,6 +741,14 @@ func (c *Client) readResponseTagged(tag, typ string) (startTLS *startTLSCommand,
 				cmd.data.SourceUIDs = srcUIDs
 				cmd.data.DestUIDs = dstUIDs
 			}
+		case "MAILBOXID":
+			var id string
+			if !c.dec.ExpectSP() || !expectObjectID(c.dec, &id) {
+				return nil, fmt.Errorf("in resp-code-mailboxid: %v", c.dec.Err())
+			}
+			if cmd, ok := cmd.(*CreateCommand); ok {
+				cmd.mailboxID = id
+			}
 		default: // [SP 1*<any TEXT-CHAR except "]">]
 			if c.dec.SP() {
 				c.dec.DiscardUntilByte(']')
@@ -740,8 +780,11 @@ func (c *Client) readResponseTagged(tag, typ string) (startTLS *startTLSCommand,
 
 	c.completeCommand(cmd, cmdErr)
 
-	if cmd, ok := cmd.(*startTLSCommand); ok && cmdErr == nil {
-		startTLS = cmd
+	if cmdErr == nil {
+		switch cmd.(type) {
+		case *startTLSCommand, *compressCommand:
+			upgrade = cmd
+		}
 	}
 
 	if cmdErr == nil && code != "CAPABILITY" {
@@ -752,11 +795,11 @@ func (c *Client) readResponseTagged(tag, typ string) (startTLS *startTLSCommand,
 		}
 	}
 
-	return startTLS, nil
+	return upgrade, nil
 }
 
 func (c *Client) readResponseData(typ string) error {
-	// number SP ("EXISTS" / "RECENT" / "FETCH" / "EXPUNGE")
+	// number SP ("EXISTS" / "RECENT" / "FETCH" / "UIDFETCH" / "EXPUNGE")
 	var num uint32
 	if typ[0] >= '0' && typ[0] <= '9' {
 		v, err := strconv.ParseUint(typ, 10, 32)
@@ -838,6 +881,19 @@ func (c *Client) readResponseData(typ string) error {
 					cmd.data.SourceUIDs = srcUIDs
 					cmd.data.DestUIDs = dstUIDs
 				}
+			case "APPENDUID":
+				var (
+					uidValidity uint32
+					uid         imap.UID
+				)
+				if !c.dec.ExpectSP() || !c.dec.ExpectNumber(&uidValidity) || !c.dec.ExpectSP() || !c.dec.ExpectUID(&uid) {
+					return fmt.Errorf("in resp-code-apnd: %v", c.dec.Err())
+				}
+				// Sent by REPLACE
+				if cmd := findPendingCmdByType[*AppendCommand](c); cmd != nil {
+					cmd.data.UID = uid
+					cmd.data.UIDValidity = uidValidity
+				}
 			case "HIGHESTMODSEQ":
 				var modSeq uint64
 				if !c.dec.ExpectSP() || !c.dec.ExpectModSeq(&modSeq) {
@@ -848,6 +904,14 @@ func (c *Client) readResponseData(typ string) error {
 				}
 			case "NOMODSEQ":
 				// ignore
+			case "MAILBOXID":
+				var id string
+				if !c.dec.ExpectSP() || !expectObjectID(c.dec, &id) {
+					return c.dec.Err()
+				}
+				if cmd := findPendingCmdByType[*SelectCommand](c); cmd != nil {
+					cmd.data.MailboxID = id
+				}
 			default: // [SP 1*<any TEXT-CHAR except "]">]
 				if c.dec.SP() {
 					c.dec.DiscardUntilByte(']')
@@ -920,9 +984,19 @@ func (c *Client) readResponseData(typ string) error {
 		if !c.dec.ExpectSP() {
 			return c.dec.Err()
 		}
-		return c.handleFetch(num)
+		return c.handleFetch(num, 0)
+	case "UIDFETCH":
+		if !c.dec.ExpectSP() {
+			return c.dec.Err()
+		}
+		return c.handleFetch(0, imap.UID(num))
 	case "EXPUNGE":
 		return c.handleExpunge(num)
+	case "VANISHED":
+		if !c.dec.ExpectSP() {
+			return c.dec.Err()
+		}
+		return c.handleVanished()
 	case "SEARCH":
 		return c.handleSearch()
 	case "ESEARCH":
@@ -946,6 +1020,30 @@ func (c *Client) readResponseData(typ string) error {
 			return c.dec.Err()
 		}
 		return c.handleQuotaRoot()
+	case "ID":
+		if !c.dec.ExpectSP() {
+			return c.dec.Err()
+		}
+		return c.handleID()
+	case "GENURLAUTH":
+		return c.handleGenURLAuth()
+	case "URLFETCH":
+		return c.handleURLFetch()
+	case "MYRIGHTS":
+		if !c.dec.ExpectSP() {
+			return c.dec.Err()
+		}
+		return c.handleMyRights()
+	case "ACL":
+		if !c.dec.ExpectSP() {
+			return c.dec.Err()
+		}
+		return c.handleGetACL()
+	case "LISTRIGHTS":
+		if !c.dec.ExpectSP() {
+			return c.dec.Err()
+		}
+		return c.handleListRights()
 	default:
 		return fmt.Errorf("unsupported response type %q", typ)
 	}
@@ -1027,6 +1125,24 @@ func (c *Client) Unsubscribe(mailbox string) *Command {
 	return cmd
 }
 
+// checkUIDOnly returns an error if UIDONLY is enabled and the command refers
+// to messages by sequence number.
+func (c *Client) checkUIDOnly(kind imapwire.NumKind) error {
+	c.mutex.Lock()
+	uidOnly := c.enabled.Has(imap.CapUIDOnly)
+	c.mutex.Unlock()
+	if uidOnly && kind == imapwire.NumKindSeq {
+		return fmt.Errorf("imapclient: message sequence numbers cannot be used with UIDONLY")
+	}
+	return nil
+}
+
+// failCommand completes a command without sending it to the server.
+func (c *Client) failCommand(cmd command, err error) {
+	*cmd.base() = Command{done: make(chan error, 1)}
+	c.completeCommand(cmd, err)
+}
+
 func uidCmdName(name string, kind imapwire.NumKind) string {
 	switch kind {
 	case imapwire.NumKindSeq:
@@ -1125,6 +1241,24 @@ type UnilateralDataHandler struct {
 
 	// requires ENABLE METADATA or ENABLE SERVER-METADATA
 	Metadata func(mailbox string, entries []string)
+
+	// requires NOTIFY
+	Status func(data *imap.StatusData)
+	List   func(data *imap.ListData)
+
+	// requires ENABLE QRESYNC or ENABLE UIDONLY
+	//
+	// VANISHED responses replace EXPUNGE responses. If earlier is true, the
+	// messages have been expunged before the current command (e.g. SELECT with
+	// QRESYNC or UID FETCH with VANISHED) and the number of messages in the
+	// mailbox hasn't changed.
+	Vanished func(uids imap.UIDSet, earlier bool)
+
+	// requires CONTEXT=SEARCH or CONTEXT=SORT
+	//
+	// Updates are sent for SEARCH and SORT commands which used the UPDATE
+	// return option.
+	SearchUpdate func(update *imap.SearchUpdate)
 }
 
 // command is an interface for IMAP commands.
@@ -1146,6 +1280,11 @@ func (cmd *Command) base() *Command {
 	return cmd
 }
 
+// Tag returns the command tag.
+func (cmd *Command) Tag() string {
+	return cmd.tag
+}
+
 // Wait blocks until the command has completed.
 func (cmd *Command) Wait() error {
 	if cmd.err == nil {
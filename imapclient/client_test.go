@@ -32,14 +32,50 @@ func newMemClientServerPair(t *testing.T) (net.Conn, io.Closer) {
 
 	memServer.AddUser(user)
 
+	return serveMemServer(t, memServer)
+}
+
+// serveMemServer starts an IMAP server backed by memServer, and connects to
+// it.
+func serveMemServer(t *testing.T, memServer *imapmemserver.Server) (net.Conn, io.Closer) {
 	server := imapserver.New(&imapserver.Options{
 		NewSession: func(conn *imapserver.Conn) (imapserver.Session, *imapserver.GreetingData, error) {
 			return memServer.NewSession(), nil, nil
 		},
 		InsecureAuth: true,
 		Caps: imap.CapSet{
-			imap.CapIMAP4rev1: {},
-			imap.CapIMAP4rev2: {},
+			imap.CapIMAP4rev1:        {},
+			imap.CapIMAP4rev2:        {},
+			imap.CapCondStore:        {},
+			imap.CapQResync:          {},
+			imap.CapSort:             {},
+			"THREAD=ORDEREDSUBJECT":  {},
+			"THREAD=REFERENCES":      {},
+			imap.CapESort:            {},
+			imap.CapQuota:            {},
+			imap.CapMetadata:         {},
+			imap.CapACL:              {},
+			imap.CapID:               {},
+			imap.CapNotify:           {},
+			imap.CapMultiAppend:      {},
+			imap.CapCatenate:         {},
+			imap.CapURLAuth:          {},
+			imap.CapURLPartial:       {},
+			imap.CapReplace:          {},
+			imap.CapPreview:          {},
+			imap.CapObjectID:         {},
+			imap.CapSaveDate:         {},
+			imap.CapSpecialUse:       {},
+			imap.CapCreateSpecialUse: {},
+			imap.CapWithin:           {},
+			imap.CapSearchFuzzy:      {},
+			imap.CapPartial:          {},
+			imap.CapContextSearch:    {},
+			imap.CapContextSort:      {},
+			imap.CapFilters:          {},
+			imap.CapMultiSearch:      {},
+			imap.CapUIDOnly:          {},
+			imap.CapCompressDeflate:  {},
 		},
 	})
 
@@ -0,0 +1,60 @@
+package imapclient
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+
+	"github.com/emersion/go-imap/v2/internal"
+)
+
+// Compress sends a COMPRESS DEFLATE command.
+//
+// Unlike other commands, this method blocks until the command completes. On
+// success, all further data exchanged with the server is compressed.
+//
+// This command requires support for the COMPRESS=DEFLATE extension.
+func (c *Client) Compress() error {
+	upgradeDone := make(chan struct{})
+	cmd := &compressCommand{upgradeDone: upgradeDone}
+	enc := c.beginCommand("COMPRESS", cmd)
+	enc.SP().Atom("DEFLATE")
+	enc.flush()
+	defer enc.end()
+
+	// Commands cannot be sent until the server response is seen and
+	// compression is active
+
+	if err := cmd.Wait(); err != nil {
+		return err
+	}
+
+	// The decoder goroutine will invoke Client.upgradeCompress
+	<-upgradeDone
+	return nil
+}
+
+func (c *Client) upgradeCompress() {
+	// Drain buffered data from our bufio.Reader
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, c.br, int64(c.br.Buffered())); err != nil {
+		panic(err) // unreachable
+	}
+
+	deflateConn := internal.NewDeflateConn(c.conn, buf.Bytes())
+	c.setConn(deflateConn)
+
+	// Wrap the compressed connection, so that the debug output stays
+	// uncompressed
+	rw := c.options.wrapReadWriter(deflateConn)
+
+	c.br.Reset(rw)
+	// Unfortunately we can't re-use the bufio.Writer here, it races with
+	// Client.Compress
+	c.bw = bufio.NewWriter(rw)
+}
+
+type compressCommand struct {
+	cmd
+	upgradeDone chan<- struct{}
+}
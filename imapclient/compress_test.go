@@ -0,0 +1,55 @@
+package imapclient_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/emersion/go-imap/v2"
+)
+
+func TestCompress(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateAuthenticated)
+	defer client.Close()
+	defer server.Close()
+
+	if !client.Caps().Has(imap.CapCompressDeflate) {
+		t.Skip("COMPRESS=DEFLATE not supported")
+	}
+
+	if err := client.Compress(); err != nil {
+		t.Fatalf("Compress() = %v", err)
+	}
+
+	if _, err := client.Select("INBOX", nil).Wait(); err != nil {
+		t.Fatalf("Select().Wait() = %v", err)
+	}
+
+	fetchOptions := &imap.FetchOptions{
+		BodySection: []*imap.FetchItemBodySection{{}},
+	}
+	msgs, err := client.Fetch(imap.SeqSetNum(1), fetchOptions).Collect()
+	if err != nil {
+		t.Fatalf("Fetch().Collect() = %v", err)
+	} else if len(msgs) != 1 {
+		t.Fatalf("len(msgs) = %v, want 1", len(msgs))
+	}
+	var body []byte
+	for _, buf := range msgs[0].BodySection {
+		body = buf
+	}
+	want := strings.ReplaceAll(simpleRawMessage, "\n", "\r\n")
+	if string(body) != want {
+		t.Errorf("body = %q, want %q", body, want)
+	}
+
+	err = client.Compress()
+	var imapErr *imap.Error
+	if !errors.As(err, &imapErr) || imapErr.Code != imap.ResponseCodeCompressionActive {
+		t.Errorf("Compress() = %v, want COMPRESSIONACTIVE error", err)
+	}
+
+	if err := client.Noop().Wait(); err != nil {
+		t.Fatalf("Noop().Wait() = %v", err)
+	}
+}
@@ -0,0 +1,17 @@
+package imapclient
+
+// CancelUpdate sends a CANCELUPDATE command.
+//
+// The server stops sending updates for the SEARCH and SORT commands with the
+// specified tags. See Command.Tag.
+//
+// This command requires support for CONTEXT=SEARCH or CONTEXT=SORT.
+func (c *Client) CancelUpdate(tags ...string) *Command {
+	cmd := &Command{}
+	enc := c.beginCommand("CANCELUPDATE", cmd)
+	for _, tag := range tags {
+		enc.SP().Quoted(tag)
+	}
+	enc.end()
+	return cmd
+}
@@ -0,0 +1,107 @@
+package imapclient_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/imapclient"
+)
+
+func TestSearch_update(t *testing.T) {
+	conn, server := newMemClientServerPair(t)
+	defer server.Close()
+
+	updates := make(chan *imap.SearchUpdate, 16)
+	client := imapclient.New(conn, &imapclient.Options{
+		UnilateralDataHandler: &imapclient.UnilateralDataHandler{
+			SearchUpdate: func(update *imap.SearchUpdate) {
+				updates <- update
+			},
+		},
+	})
+	defer client.Close()
+
+	if err := client.Login(testUsername, testPassword).Wait(); err != nil {
+		t.Fatalf("Login().Wait() = %v", err)
+	}
+	appendCmd := client.Append("INBOX", int64(len(simpleRawMessage)), nil)
+	appendCmd.Write([]byte(simpleRawMessage))
+	appendCmd.Close()
+	if _, err := appendCmd.Wait(); err != nil {
+		t.Fatalf("AppendCommand.Wait() = %v", err)
+	}
+	if _, err := client.Select("INBOX", nil).Wait(); err != nil {
+		t.Fatalf("Select().Wait() = %v", err)
+	}
+
+	criteria := imap.SearchCriteria{Flag: []imap.Flag{imap.FlagFlagged}}
+	searchCmd := client.UIDSearch(&criteria, &imap.SearchOptions{ReturnAll: true, ReturnUpdate: true})
+	if data, err := searchCmd.Wait(); err != nil {
+		t.Fatalf("UIDSearch().Wait() = %v", err)
+	} else if uids := data.AllUIDs(); len(uids) != 0 {
+		t.Errorf("AllUIDs() = %v, want []", uids)
+	}
+
+	storeFlags := imap.StoreFlags{Op: imap.StoreFlagsAdd, Flags: []imap.Flag{imap.FlagFlagged}, Silent: true}
+	if err := client.Store(imap.UIDSetNum(1), &storeFlags, nil).Close(); err != nil {
+		t.Fatalf("Store().Close() = %v", err)
+	}
+	select {
+	case update := <-updates:
+		want := &imap.SearchUpdate{
+			Tag:   searchCmd.Tag(),
+			UID:   true,
+			AddTo: []imap.SearchUpdateRange{{Position: 0, Nums: []uint32{1}}},
+		}
+		if !reflect.DeepEqual(update, want) {
+			t.Errorf("got update %#v, want %#v", update, want)
+		}
+	default:
+		t.Fatalf("no update after STORE")
+	}
+
+	if err := client.CancelUpdate(searchCmd.Tag()).Wait(); err != nil {
+		t.Fatalf("CancelUpdate().Wait() = %v", err)
+	}
+	storeFlags.Op = imap.StoreFlagsDel
+	if err := client.Store(imap.UIDSetNum(1), &storeFlags, nil).Close(); err != nil {
+		t.Fatalf("Store().Close() = %v", err)
+	}
+	select {
+	case update := <-updates:
+		t.Errorf("got update %#v after CANCELUPDATE", update)
+	default:
+	}
+
+	sortCmd := client.UIDSort(&imapclient.SortOptions{
+		SearchCriteria: &imap.SearchCriteria{},
+		SortCriteria:   []imap.SortCriterion{{Key: imap.SortKeyArrival, Reverse: true}},
+		ReturnUpdate:   true,
+	})
+	if nums, err := sortCmd.Wait(); err != nil {
+		t.Fatalf("UIDSort().Wait() = %v", err)
+	} else if !reflect.DeepEqual(nums, []uint32{1}) {
+		t.Errorf("UIDSort().Wait() = %v, want [1]", nums)
+	}
+
+	appendCmd = client.Append("INBOX", int64(len(simpleRawMessage)), nil)
+	appendCmd.Write([]byte(simpleRawMessage))
+	appendCmd.Close()
+	if _, err := appendCmd.Wait(); err != nil {
+		t.Fatalf("AppendCommand.Wait() = %v", err)
+	}
+	select {
+	case update := <-updates:
+		want := &imap.SearchUpdate{
+			Tag:   sortCmd.Tag(),
+			UID:   true,
+			AddTo: []imap.SearchUpdateRange{{Position: 1, Nums: []uint32{2}}},
+		}
+		if !reflect.DeepEqual(update, want) {
+			t.Errorf("got update %#v, want %#v", update, want)
+		}
+	default:
+		t.Fatalf("no update after APPEND")
+	}
+}
@@ -10,7 +10,12 @@ import (
 // Copy sends a COPY command.
 func (c *Client) Copy(numSet imap.NumSet, mailbox string) *CopyCommand {
 	cmd := &CopyCommand{}
-	enc := c.beginCommand(uidCmdName("COPY", imapwire.NumSetKind(numSet)), cmd)
+	numKind := imapwire.NumSetKind(numSet)
+	if err := c.checkUIDOnly(numKind); err != nil {
+		c.failCommand(cmd, err)
+		return cmd
+	}
+	enc := c.beginCommand(uidCmdName("COPY", numKind), cmd)
 	enc.SP().NumSet(numSet).SP().Mailbox(mailbox)
 	enc.end()
 	return cmd
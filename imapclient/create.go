@@ -7,8 +7,8 @@ import (
 // Create sends a CREATE command.
 //
 // A nil options pointer is equivalent to a zero options value.
-func (c *Client) Create(mailbox string, options *imap.CreateOptions) *Command {
-	cmd := &Command{}
+func (c *Client) Create(mailbox string, options *imap.CreateOptions) *CreateCommand {
+	cmd := &CreateCommand{}
 	enc := c.beginCommand("CREATE", cmd)
 	enc.SP().Mailbox(mailbox)
 	if options != nil && len(options.SpecialUse) > 0 {
@@ -19,3 +19,16 @@ func (c *Client) Create(mailbox string, options *imap.CreateOptions) *Command {
 	enc.end()
 	return cmd
 }
+
+// CreateCommand is a CREATE command.
+type CreateCommand struct {
+	cmd
+	mailboxID string
+}
+
+// MailboxID returns the ID assigned by the server to the new mailbox, if any.
+//
+// This requires the OBJECTID extension. It must be called after Wait.
+func (cmd *CreateCommand) MailboxID() string {
+	return cmd.mailboxID
+}
@@ -8,13 +8,16 @@ import (
 
 // Enable sends an ENABLE command.
 //
+// Once UIDONLY is enabled, commands referring to messages by sequence number
+// fail without being sent to the server.
+//
 // This command requires support for IMAP4rev2 or the ENABLE extension.
 func (c *Client) Enable(caps ...imap.Cap) *EnableCommand {
 	// Enabling an extension may change the IMAP syntax, so only allow the
 	// extensions we support here
 	for _, name := range caps {
 		switch name {
-		case imap.CapIMAP4rev2, imap.CapUTF8Accept, imap.CapMetadata, imap.CapMetadataServer:
+		case imap.CapIMAP4rev2, imap.CapUTF8Accept, imap.CapMetadata, imap.CapMetadataServer, imap.CapCondStore, imap.CapQResync, imap.CapUIDOnly:
 			// ok
 		default:
 			done := make(chan error)
@@ -1,10 +1,16 @@
 package imapclient
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/emersion/go-imap/v2"
 )
 
 // Expunge sends an EXPUNGE command.
+//
+// If UIDONLY is enabled, expunged messages are reported via
+// UnilateralDataHandler.Vanished instead.
 func (c *Client) Expunge() *ExpungeCommand {
 	cmd := &ExpungeCommand{seqNums: make(chan uint32, 128)}
 	c.beginCommand("EXPUNGE", cmd).end()
@@ -40,6 +46,44 @@ func (c *Client) handleExpunge(seqNum uint32) error {
 	return nil
 }
 
+func (c *Client) handleVanished() error {
+	earlier := false
+	if c.dec.Special('(') {
+		var tag string
+		if !c.dec.ExpectAtom(&tag) || !c.dec.ExpectSpecial(')') || !c.dec.ExpectSP() {
+			return c.dec.Err()
+		} else if !strings.EqualFold(tag, "EARLIER") {
+			return fmt.Errorf("in vanished-resp: unexpected tag %q", tag)
+		}
+		earlier = true
+	}
+
+	var uids imap.UIDSet
+	if !c.dec.ExpectUIDSet(&uids) {
+		return c.dec.Err()
+	}
+
+	if !earlier {
+		nums, _ := uids.Nums()
+		c.mutex.Lock()
+		if c.state == imap.ConnStateSelected {
+			c.mailbox = c.mailbox.copy()
+			if n := uint32(len(nums)); c.mailbox.NumMessages > n {
+				c.mailbox.NumMessages -= n
+			} else {
+				c.mailbox.NumMessages = 0
+			}
+		}
+		c.mutex.Unlock()
+	}
+
+	if handler := c.options.unilateralDataHandler().Vanished; handler != nil {
+		handler(uids, earlier)
+	}
+
+	return nil
+}
+
 // ExpungeCommand is an EXPUNGE command.
 //
 // The caller must fully consume the ExpungeCommand. A simple way to do so is
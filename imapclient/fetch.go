@@ -30,11 +30,29 @@ func (c *Client) Fetch(numSet imap.NumSet, options *imap.FetchOptions) *FetchCom
 		numSet: numSet,
 		msgs:   make(chan *FetchMessageData, 128),
 	}
+	if err := c.checkUIDOnly(numKind); err != nil {
+		c.failCommand(cmd, err)
+		return cmd
+	}
+	if options.Vanished && options.ChangedSince == 0 {
+		c.failCommand(cmd, fmt.Errorf("imapclient: VANISHED requires CHANGEDSINCE"))
+		return cmd
+	}
 	enc := c.beginCommand(uidCmdName("FETCH", numKind), cmd)
 	enc.SP().NumSet(numSet).SP()
 	writeFetchItems(enc.Encoder, numKind, options)
-	if options.ChangedSince != 0 {
-		enc.SP().Special('(').Atom("CHANGEDSINCE").SP().ModSeq(options.ChangedSince).Special(')')
+	if options.ChangedSince != 0 || options.Partial != nil {
+		listEnc := enc.SP().BeginList()
+		if options.ChangedSince != 0 {
+			listEnc.Item().Atom("CHANGEDSINCE").SP().ModSeq(options.ChangedSince)
+			if options.Vanished {
+				listEnc.Item().Atom("VANISHED")
+			}
+		}
+		if options.Partial != nil {
+			listEnc.Item().Atom("PARTIAL").SP().PartialRange(*options.Partial)
+		}
+		listEnc.End()
 	}
 	enc.end()
 	return cmd
@@ -57,6 +75,9 @@ func writeFetchItems(enc *imapwire.Encoder, numKind imapwire.NumKind, options *i
 		"INTERNALDATE":  options.InternalDate,
 		"RFC822.SIZE":   options.RFC822Size,
 		"MODSEQ":        options.ModSeq,
+		"EMAILID":       options.EmailID,
+		"THREADID":      options.ThreadID,
+		"SAVEDATE":      options.SaveDate,
 	}
 	for k, req := range m {
 		if req {
@@ -73,6 +94,14 @@ func writeFetchItems(enc *imapwire.Encoder, numKind imapwire.NumKind, options *i
 	for _, bss := range options.BinarySectionSize {
 		writeFetchItemBinarySectionSize(listEnc.Item(), bss)
 	}
+	if options.Preview != nil {
+		enc := listEnc.Item().Atom("PREVIEW")
+		if options.Preview.Lazy {
+			enc.SP().List(1, func(i int) {
+				enc.Atom("LAZY")
+			})
+		}
+	}
 
 	listEnc.End()
 }
@@ -238,7 +267,7 @@ func (cmd *FetchCommand) Collect() ([]*FetchMessageBuffer, error) {
 
 // FetchMessageData contains a message's FETCH data.
 type FetchMessageData struct {
-	SeqNum uint32
+	SeqNum uint32 // zero if UIDONLY is enabled
 
 	items chan FetchItemData
 	prev  FetchItemData
@@ -397,6 +426,44 @@ type FetchItemDataModSeq struct {
 
 func (FetchItemDataModSeq) fetchItemData() {}
 
+// FetchItemDataEmailID holds data returned by FETCH EMAILID.
+//
+// This requires the OBJECTID extension.
+type FetchItemDataEmailID struct {
+	EmailID string
+}
+
+func (FetchItemDataEmailID) fetchItemData() {}
+
+// FetchItemDataThreadID holds data returned by FETCH THREADID.
+//
+// This requires the OBJECTID extension.
+type FetchItemDataThreadID struct {
+	ThreadID string // empty if the server doesn't support thread IDs
+}
+
+func (FetchItemDataThreadID) fetchItemData() {}
+
+// FetchItemDataSaveDate holds data returned by FETCH SAVEDATE.
+//
+// This requires the SAVEDATE extension.
+type FetchItemDataSaveDate struct {
+	Time time.Time // zero if the server doesn't support save dates
+}
+
+func (FetchItemDataSaveDate) fetchItemData() {}
+
+// FetchItemDataPreview holds data returned by FETCH PREVIEW.
+//
+// This requires the PREVIEW extension.
+type FetchItemDataPreview struct {
+	// Preview is nil if the server didn't return a preview (e.g. because
+	// FetchItemPreview.Lazy was set)
+	Preview *string
+}
+
+func (FetchItemDataPreview) fetchItemData() {}
+
 // FetchMessageBuffer is a buffer for the data returned by FetchMessageData.
 //
 // The SeqNum field is always populated. All remaining fields are optional.
@@ -411,7 +478,11 @@ type FetchMessageBuffer struct {
 	BodySection       map[*imap.FetchItemBodySection][]byte
 	BinarySection     map[*imap.FetchItemBinarySection][]byte
 	BinarySectionSize []FetchItemDataBinarySectionSize
-	ModSeq            uint64 // requires CONDSTORE
+	ModSeq            uint64    // requires CONDSTORE
+	Preview           *string   // requires PREVIEW
+	EmailID           string    // requires OBJECTID
+	ThreadID          string    // requires OBJECTID
+	SaveDate          time.Time // requires SAVEDATE
 }
 
 func (buf *FetchMessageBuffer) populateItemData(item FetchItemData) error {
@@ -450,13 +521,23 @@ func (buf *FetchMessageBuffer) populateItemData(item FetchItemData) error {
 		buf.BinarySectionSize = append(buf.BinarySectionSize, item)
 	case FetchItemDataModSeq:
 		buf.ModSeq = item.ModSeq
+	case FetchItemDataPreview:
+		buf.Preview = item.Preview
+	case FetchItemDataEmailID:
+		buf.EmailID = item.EmailID
+	case FetchItemDataThreadID:
+		buf.ThreadID = item.ThreadID
+	case FetchItemDataSaveDate:
+		buf.SaveDate = item.Time
 	default:
 		panic(fmt.Errorf("unsupported fetch item data %T", item))
 	}
 	return nil
 }
 
-func (c *Client) handleFetch(seqNum uint32) error {
+// handleFetch handles a FETCH response, or a UIDFETCH response if uid is
+// non-zero.
+func (c *Client) handleFetch(seqNum uint32, uid imap.UID) error {
 	dec := c.dec
 
 	items := make(chan FetchItemData, 32)
@@ -464,12 +545,19 @@ func (c *Client) handleFetch(seqNum uint32) error {
 
 	msg := &FetchMessageData{SeqNum: seqNum, items: items}
 
+	// The UID of UIDFETCH responses is returned as the first data item
+	uidFetch := uid != 0
+	numAtts := 0
+	if uidFetch {
+		items <- FetchItemDataUID{UID: uid}
+		numAtts++
+	}
+
 	// We're in a tricky situation: to know whether this FETCH response needs
 	// to be handled by a pending command, we may need to look at the UID in
 	// the response data. But the response data comes in in a streaming
 	// fashion: it can contain literals. Assume that the UID will be returned
 	// before any literal.
-	var uid imap.UID
 	handled := false
 	handleMsg := func() {
 		if handled {
@@ -502,7 +590,6 @@ func (c *Client) handleFetch(seqNum uint32) error {
 	}
 	defer handleMsg()
 
-	numAtts := 0
 	return dec.ExpectList(func() error {
 		var attName string
 		if !dec.Expect(dec.Func(&attName, isMsgAttNameChar), "msg-att name") {
@@ -559,6 +646,9 @@ func (c *Client) handleFetch(seqNum uint32) error {
 			if !dec.ExpectSP() || !dec.ExpectUID(&uid) {
 				return dec.Err()
 			}
+			if uidFetch {
+				return nil // already returned
+			}
 
 			item = FetchItemDataUID{UID: uid}
 		case "BODY", "BINARY":
@@ -658,6 +748,49 @@ func (c *Client) handleFetch(seqNum uint32) error {
 				return dec.Err()
 			}
 			item = FetchItemDataModSeq{ModSeq: modSeq}
+		case "SAVEDATE":
+			if !dec.ExpectSP() {
+				return dec.Err()
+			}
+			t, err := internal.DecodeDateTime(dec)
+			if err != nil {
+				return err
+			}
+			if t.IsZero() && !dec.ExpectNIL() {
+				return dec.Err()
+			}
+			item = FetchItemDataSaveDate{Time: t}
+		case "EMAILID":
+			var id string
+			if !dec.ExpectSP() || !expectObjectID(dec, &id) {
+				return dec.Err()
+			}
+			item = FetchItemDataEmailID{EmailID: id}
+		case "THREADID":
+			var id string
+			if !dec.ExpectSP() {
+				return dec.Err()
+			}
+			if !dec.Special('(') {
+				if !dec.ExpectNIL() {
+					return dec.Err()
+				}
+			} else if !dec.ExpectAtom(&id) || !dec.ExpectSpecial(')') {
+				return dec.Err()
+			}
+			item = FetchItemDataThreadID{ThreadID: id}
+		case "PREVIEW":
+			if !dec.ExpectSP() {
+				return dec.Err()
+			}
+			var preview string
+			if dec.String(&preview) {
+				item = FetchItemDataPreview{Preview: &preview}
+			} else if dec.ExpectNIL() {
+				item = FetchItemDataPreview{}
+			} else {
+				return dec.Err()
+			}
 		default:
 			return fmt.Errorf("unsupported msg-att name: %q", attName)
 		}
@@ -1181,3 +1314,8 @@ func (lit *fetchLiteralReader) Read(b []byte) (int, error) {
 	}
 	return n, err
 }
+
+// expectObjectID reads a parenthesized object ID, as defined in RFC 8474.
+func expectObjectID(dec *imapwire.Decoder, ptr *string) bool {
+	return dec.ExpectSpecial('(') && dec.ExpectAtom(ptr) && dec.ExpectSpecial(')')
+}
@@ -0,0 +1,117 @@
+package imapclient
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/internal/imapwire"
+)
+
+// FilterData is a named search filter.
+type FilterData struct {
+	Name    string
+	Program string // search program
+	Shared  bool
+}
+
+func filterEntry(name string, shared bool) string {
+	if shared {
+		return "/shared/filters/values/" + name
+	}
+	return "/private/filters/values/" + name
+}
+
+// SetFilter defines a named search filter, which can then be referenced with
+// SearchCriteria.Filter.
+//
+// The filter is stored in the /private/filters/values/<name> server metadata
+// entry, or in /shared/filters/values/<name> if shared is true.
+//
+// This command requires support for the FILTERS and METADATA-SERVER
+// extensions.
+func (c *Client) SetFilter(name string, criteria *imap.SearchCriteria, shared bool) *Command {
+	program, err := encodeSearchProgram(criteria)
+	if err != nil {
+		done := make(chan error)
+		close(done)
+		return &Command{done: done, err: err}
+	}
+	b := []byte(program)
+	return c.SetMetadata("", map[string]*[]byte{filterEntry(name, shared): &b})
+}
+
+// DeleteFilter deletes a named search filter.
+//
+// This command requires support for the FILTERS and METADATA-SERVER
+// extensions.
+func (c *Client) DeleteFilter(name string, shared bool) *Command {
+	return c.SetMetadata("", map[string]*[]byte{filterEntry(name, shared): nil})
+}
+
+// ListFilters lists private and shared named search filters.
+//
+// This command requires support for the FILTERS and METADATA-SERVER
+// extensions.
+func (c *Client) ListFilters() *ListFiltersCommand {
+	cmd := c.GetMetadata("", []string{
+		"/private/filters/values",
+		"/shared/filters/values",
+	}, &GetMetadataOptions{Depth: GetMetadataDepthOne})
+	return &ListFiltersCommand{cmd: cmd}
+}
+
+// ListFiltersCommand is a GETMETADATA command listing filters.
+type ListFiltersCommand struct {
+	cmd *GetMetadataCommand
+}
+
+func (cmd *ListFiltersCommand) Wait() ([]FilterData, error) {
+	data, err := cmd.cmd.Wait()
+	if err != nil {
+		return nil, err
+	}
+
+	var filters []FilterData
+	for entry, value := range data.Entries {
+		if value == nil {
+			continue
+		}
+		var filter FilterData
+		if name := strings.TrimPrefix(entry, "/private/filters/values/"); name != entry {
+			filter.Name = name
+		} else if name := strings.TrimPrefix(entry, "/shared/filters/values/"); name != entry {
+			filter.Name = name
+			filter.Shared = true
+		} else {
+			continue
+		}
+		filter.Program = string(*value)
+		filters = append(filters, filter)
+	}
+	sort.Slice(filters, func(i, j int) bool {
+		if filters[i].Name != filters[j].Name {
+			return filters[i].Name < filters[j].Name
+		}
+		return !filters[i].Shared && filters[j].Shared
+	})
+	return filters, nil
+}
+
+// encodeSearchProgram formats search criteria into a search program, as
+// stored in filters.
+func encodeSearchProgram(criteria *imap.SearchCriteria) (string, error) {
+	var buf bytes.Buffer
+	bw := bufio.NewWriter(&buf)
+	enc := imapwire.NewEncoder(bw, imapwire.ConnSideClient)
+	enc.QuotedUTF8 = true
+	enc.LiteralPlus = true
+	writeSearchKey(enc, criteria)
+	if err := enc.CRLF(); err != nil {
+		return "", fmt.Errorf("imapclient: failed to encode search program: %w", err)
+	}
+	return strings.TrimSuffix(buf.String(), "\r\n"), nil
+}
@@ -0,0 +1,57 @@
+package imapclient_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/emersion/go-imap/v2"
+)
+
+func TestFilter(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateSelected)
+	defer client.Close()
+	defer server.Close()
+
+	if !client.Caps().Has(imap.CapFilters) {
+		t.Skip("FILTERS not supported")
+	}
+
+	criteria := imap.SearchCriteria{Body: []string{"letter"}}
+	if err := client.SetFilter("letters", &criteria, false).Wait(); err != nil {
+		t.Fatalf("SetFilter().Wait() = %v", err)
+	}
+
+	filters, err := client.ListFilters().Wait()
+	if err != nil {
+		t.Fatalf("ListFilters().Wait() = %v", err)
+	} else if len(filters) != 1 || filters[0].Name != "letters" || filters[0].Shared {
+		t.Fatalf("ListFilters().Wait() = %v, want a single private filter named letters", filters)
+	}
+
+	tests := []struct {
+		name     string
+		criteria imap.SearchCriteria
+		want     uint32
+	}{
+		{"FILTER", imap.SearchCriteria{Filter: []string{"letters"}}, 1},
+		{"NOT FILTER", imap.SearchCriteria{Not: []imap.SearchCriteria{{Filter: []string{"letters"}}}}, 0},
+		{"SMALLER FILTER", imap.SearchCriteria{Smaller: 10, Filter: []string{"letters"}}, 0},
+	}
+	for _, tc := range tests {
+		data, err := client.Search(&tc.criteria, &imap.SearchOptions{ReturnCount: true}).Wait()
+		if err != nil {
+			t.Errorf("Search(%v).Wait() = %v", tc.name, err)
+		} else if data.Count != tc.want {
+			t.Errorf("Search(%v): Count = %v, want %v", tc.name, data.Count, tc.want)
+		}
+	}
+
+	if err := client.DeleteFilter("letters", false).Wait(); err != nil {
+		t.Fatalf("DeleteFilter().Wait() = %v", err)
+	}
+	_, err = client.Search(&imap.SearchCriteria{Filter: []string{"letters"}}, nil).Wait()
+	var imapErr *imap.Error
+	if !errors.As(err, &imapErr) || imapErr.Code != imap.ResponseCodeUndefinedFilter {
+		t.Errorf("Search().Wait() = %v, want UNDEFINED-FILTER error", err)
+	}
+}
@@ -0,0 +1,80 @@
+package imapclient_test
+
+import (
+	"testing"
+
+	"github.com/emersion/go-imap/v2"
+)
+
+func TestSearch_fuzzy(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateSelected)
+	defer client.Close()
+	defer server.Close()
+
+	if !client.Caps().Has(imap.CapSearchFuzzy) {
+		t.Skip("SEARCH=FUZZY not supported")
+	}
+
+	criteria := imap.SearchCriteria{
+		Fuzzy: []imap.SearchCriteria{{Body: []string{"leter"}}},
+	}
+	data, err := client.Search(&criteria, &imap.SearchOptions{ReturnRelevancy: true}).Wait()
+	if err != nil {
+		t.Fatalf("Search().Wait() = %v", err)
+	}
+	if seqNums := data.AllSeqNums(); len(seqNums) != 1 || seqNums[0] != 1 {
+		t.Errorf("AllSeqNums() = %v, want [1]", seqNums)
+	}
+	if len(data.Relevancy) != 1 || data.Relevancy[0] == 0 || data.Relevancy[0] > 100 {
+		t.Errorf("Relevancy = %v, want one score between 1 and 100", data.Relevancy)
+	}
+
+	criteria = imap.SearchCriteria{
+		Fuzzy: []imap.SearchCriteria{{Body: []string{"xylophone"}}},
+	}
+	data, err = client.Search(&criteria, nil).Wait()
+	if err != nil {
+		t.Fatalf("Search().Wait() = %v", err)
+	} else if seqNums := data.AllSeqNums(); len(seqNums) != 0 {
+		t.Errorf("AllSeqNums() = %v, want []", seqNums)
+	}
+
+	// Encoded attachments aren't searched
+	const attachmentRawMessage = "MIME-Version: 1.0\r\n" +
+		"Content-Type: multipart/mixed; boundary=sep\r\n" +
+		"\r\n" +
+		"--sep\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"\r\n" +
+		"See the attached picture\r\n" +
+		"--sep\r\n" +
+		"Content-Type: application/octet-stream\r\n" +
+		"Content-Transfer-Encoding: base64\r\n" +
+		"\r\n" +
+		"cXVpY2tzaWx2ZXI=\r\n" +
+		"--sep--\r\n"
+	appendCmd := client.Append("INBOX", int64(len(attachmentRawMessage)), nil)
+	appendCmd.Write([]byte(attachmentRawMessage))
+	appendCmd.Close()
+	if _, err := appendCmd.Wait(); err != nil {
+		t.Fatalf("AppendCommand.Wait() = %v", err)
+	}
+	for _, tc := range []struct {
+		text string
+		want int
+	}{
+		{"pictre", 1},
+		{"cXVpY2tzaWx2ZXI", 0},
+	} {
+		criteria = imap.SearchCriteria{
+			Fuzzy: []imap.SearchCriteria{{Text: []string{tc.text}}},
+		}
+		data, err = client.Search(&criteria, &imap.SearchOptions{ReturnRelevancy: true}).Wait()
+		if err != nil {
+			t.Fatalf("Search().Wait() = %v", err)
+		}
+		if seqNums := data.AllSeqNums(); len(seqNums) != tc.want || len(data.Relevancy) != tc.want {
+			t.Errorf("Search(%q): AllSeqNums() = %v, Relevancy = %v, want %v results", tc.text, seqNums, data.Relevancy, tc.want)
+		}
+	}
+}
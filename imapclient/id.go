@@ -0,0 +1,81 @@
+package imapclient
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/emersion/go-imap/v2/internal/imapwire"
+)
+
+// ID sends an ID command.
+//
+// The ID fields describe the client, see RFC 2971 section 3.3 for a list of
+// common keys. If idData is empty, the client doesn't disclose any
+// information.
+//
+// This command requires support for the ID extension.
+func (c *Client) ID(idData map[string]string) *IDCommand {
+	cmd := &IDCommand{}
+	enc := c.beginCommand("ID", cmd)
+	enc.SP()
+	if len(idData) == 0 {
+		enc.NIL()
+	} else {
+		var keys []string
+		for k := range idData {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		enc.List(len(keys), func(i int) {
+			enc.String(keys[i]).SP().String(idData[keys[i]])
+		})
+	}
+	enc.end()
+	return cmd
+}
+
+func (c *Client) handleID() error {
+	data, err := readID(c.dec)
+	if err != nil {
+		return fmt.Errorf("in id-response: %v", err)
+	}
+	if cmd := findPendingCmdByType[*IDCommand](c); cmd != nil {
+		cmd.data = data
+	}
+	return nil
+}
+
+// IDCommand is an ID command.
+type IDCommand struct {
+	cmd
+	data map[string]string
+}
+
+// Wait blocks until the command has completed, and returns the server's ID
+// fields. If the server didn't disclose any information, a nil map is
+// returned.
+func (cmd *IDCommand) Wait() (map[string]string, error) {
+	return cmd.data, cmd.cmd.Wait()
+}
+
+func readID(dec *imapwire.Decoder) (map[string]string, error) {
+	var data map[string]string
+	isList, err := dec.List(func() error {
+		var key, value string
+		if !dec.ExpectString(&key) || !dec.ExpectSP() || !dec.ExpectNString(&value) {
+			return dec.Err()
+		}
+		if data == nil {
+			data = make(map[string]string)
+		}
+		data[key] = value
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	} else if !isList && !dec.ExpectNIL() {
+		return nil, dec.Err()
+	}
+	return data, nil
+}
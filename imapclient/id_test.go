@@ -0,0 +1,36 @@
+package imapclient_test
+
+import (
+	"testing"
+
+	"github.com/emersion/go-imap/v2"
+)
+
+func TestID(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateNotAuthenticated)
+	defer client.Close()
+	defer server.Close()
+
+	if !client.Caps().Has(imap.CapID) {
+		t.Skip("ID not supported")
+	}
+
+	serverID, err := client.ID(map[string]string{
+		"name":    "go-imap",
+		"version": "2",
+	}).Wait()
+	if err != nil {
+		t.Fatalf("ID().Wait() = %v", err)
+	} else if serverID["name"] != "imapmemserver" {
+		t.Errorf("ID().Wait() = %v, want name imapmemserver", serverID)
+	}
+
+	if _, err := client.ID(nil).Wait(); err != nil {
+		t.Fatalf("ID(nil).Wait() = %v", err)
+	}
+
+	// ID must not count as an unknown command before authentication
+	if err := client.Login(testUsername, testPassword).Wait(); err != nil {
+		t.Fatalf("Login().Wait() = %v", err)
+	}
+}
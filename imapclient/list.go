@@ -117,6 +117,10 @@ func (c *Client) handleList() error {
 		}
 	case *SelectCommand:
 		cmd.data.List = data
+	default:
+		if handler := c.options.unilateralDataHandler().List; handler != nil {
+			handler(data)
+		}
 	}
 
 	return nil
@@ -3,37 +3,23 @@ package imapclient
 import (
 	"fmt"
 
+	"github.com/emersion/go-imap/v2"
 	"github.com/emersion/go-imap/v2/internal/imapwire"
 )
 
-type GetMetadataDepth int
+type (
+	GetMetadataDepth   = imap.GetMetadataDepth
+	GetMetadataOptions = imap.GetMetadataOptions
+	GetMetadataData    = imap.GetMetadataData
+)
 
 const (
-	GetMetadataDepthZero     GetMetadataDepth = 0
-	GetMetadataDepthOne      GetMetadataDepth = 1
-	GetMetadataDepthInfinity GetMetadataDepth = -1
+	GetMetadataDepthZero     = imap.GetMetadataDepthZero
+	GetMetadataDepthOne      = imap.GetMetadataDepthOne
+	GetMetadataDepthInfinity = imap.GetMetadataDepthInfinity
 )
 
-func (depth GetMetadataDepth) String() string {
-	switch depth {
-	case GetMetadataDepthZero:
-		return "0"
-	case GetMetadataDepthOne:
-		return "1"
-	case GetMetadataDepthInfinity:
-		return "infinity"
-	default:
-		panic(fmt.Errorf("imapclient: unknown GETMETADATA depth %d", depth))
-	}
-}
-
-// GetMetadataOptions contains options for the GETMETADATA command.
-type GetMetadataOptions struct {
-	MaxSize *uint32
-	Depth   GetMetadataDepth
-}
-
-func (options *GetMetadataOptions) names() []string {
+func getMetadataOptionNames(options *GetMetadataOptions) []string {
 	if options == nil {
 		return nil
 	}
@@ -54,7 +40,7 @@ func (c *Client) GetMetadata(mailbox string, entries []string, options *GetMetad
 	cmd := &GetMetadataCommand{mailbox: mailbox}
 	enc := c.beginCommand("GETMETADATA", cmd)
 	enc.SP().Mailbox(mailbox)
-	if opts := options.names(); len(opts) > 0 {
+	if opts := getMetadataOptionNames(options); len(opts) > 0 {
 		enc.SP().List(len(opts), func(i int) {
 			opt := opts[i]
 			enc.Atom(opt).SP()
@@ -141,12 +127,6 @@ func (cmd *GetMetadataCommand) Wait() (*GetMetadataData, error) {
 	return &cmd.data, cmd.cmd.Wait()
 }
 
-// GetMetadataData is the data returned by the GETMETADATA command.
-type GetMetadataData struct {
-	Mailbox string
-	Entries map[string]*[]byte
-}
-
 type metadataResp struct {
 	Mailbox     string
 	EntryList   []string
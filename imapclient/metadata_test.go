@@ -0,0 +1,92 @@
+package imapclient_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/imapclient"
+	"github.com/emersion/go-imap/v2/imapserver/imapmemserver"
+)
+
+func TestMetadata(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateAuthenticated)
+	defer client.Close()
+	defer server.Close()
+
+	if !client.Caps().Has(imap.CapMetadata) {
+		t.Skip("METADATA not supported")
+	}
+
+	comment := []byte("My comment")
+	long := []byte("This value is quite long")
+	entries := map[string]*[]byte{
+		"/private/comment":      &comment,
+		"/shared/vendor/a/long": &long,
+	}
+	if err := client.SetMetadata("INBOX", entries).Wait(); err != nil {
+		t.Fatalf("SetMetadata().Wait() = %v", err)
+	}
+
+	maxSize := uint32(16)
+	data, err := client.GetMetadata("INBOX", []string{"/private/comment", "/shared"}, &imapclient.GetMetadataOptions{
+		MaxSize: &maxSize,
+		Depth:   imapclient.GetMetadataDepthInfinity,
+	}).Wait()
+	if err != nil {
+		t.Fatalf("GetMetadata().Wait() = %v", err)
+	}
+	if v := data.Entries["/private/comment"]; v == nil || string(*v) != string(comment) {
+		t.Errorf("/private/comment = %v, want %q", v, comment)
+	}
+	if _, ok := data.Entries["/shared/vendor/a/long"]; ok {
+		t.Errorf("/shared/vendor/a/long should be omitted because of MAXSIZE")
+	}
+}
+
+func TestMetadata_server(t *testing.T) {
+	const otherUsername, otherPassword = "other-user", "other-password"
+
+	memServer := imapmemserver.New()
+	memServer.AddUser(imapmemserver.NewUser(testUsername, testPassword))
+	memServer.AddUser(imapmemserver.NewUser(otherUsername, otherPassword))
+
+	login := func(username, password string) *imapclient.Client {
+		conn, server := serveMemServer(t, memServer)
+		t.Cleanup(func() { server.Close() })
+		client := imapclient.New(conn, nil)
+		t.Cleanup(func() { client.Close() })
+		if err := client.Login(username, password).Wait(); err != nil {
+			t.Fatalf("Login().Wait() = %v", err)
+		}
+		return client
+	}
+
+	client := login(testUsername, testPassword)
+	shared, private := []byte("Shared comment"), []byte("Private comment")
+	err := client.SetMetadata("", map[string]*[]byte{"/shared/comment": &private}).Wait()
+	var imapErr *imap.Error
+	if !errors.As(err, &imapErr) || imapErr.Code != imap.ResponseCodeNoPerm {
+		t.Errorf("SetMetadata(/shared).Wait() = %v, want NOPERM error", err)
+	}
+	if err := client.SetMetadata("", map[string]*[]byte{"/private/comment": &private}).Wait(); err != nil {
+		t.Fatalf("SetMetadata().Wait() = %v", err)
+	}
+	if err := memServer.SetMetadata(map[string]*[]byte{"/shared/comment": &shared}); err != nil {
+		t.Fatalf("Server.SetMetadata() = %v", err)
+	}
+
+	otherClient := login(otherUsername, otherPassword)
+	data, err := otherClient.GetMetadata("", []string{"/shared/comment", "/private/comment"}, &imapclient.GetMetadataOptions{
+		Depth: imapclient.GetMetadataDepthOne,
+	}).Wait()
+	if err != nil {
+		t.Fatalf("GetMetadata().Wait() = %v", err)
+	}
+	if v := data.Entries["/shared/comment"]; v == nil || string(*v) != string(shared) {
+		t.Errorf("/shared/comment = %v, want %q", v, shared)
+	}
+	if v := data.Entries["/private/comment"]; v != nil {
+		t.Errorf("/private/comment = %q, want NIL", *v)
+	}
+}
@@ -18,7 +18,12 @@ func (c *Client) Move(numSet imap.NumSet, mailbox string) *MoveCommand {
 	}
 
 	cmd := &MoveCommand{}
-	enc := c.beginCommand(uidCmdName(cmdName, imapwire.NumSetKind(numSet)), cmd)
+	numKind := imapwire.NumSetKind(numSet)
+	if err := c.checkUIDOnly(numKind); err != nil {
+		c.failCommand(cmd, err)
+		return cmd
+	}
+	enc := c.beginCommand(uidCmdName(cmdName, numKind), cmd)
 	enc.SP().NumSet(numSet).SP().Mailbox(mailbox)
 	enc.end()
 
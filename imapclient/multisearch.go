@@ -0,0 +1,54 @@
+package imapclient
+
+import (
+	"fmt"
+
+	"github.com/emersion/go-imap/v2"
+)
+
+// MultiSearch sends an ESEARCH command.
+//
+// The mailboxes matching sources are searched without being selected. If
+// sources is empty, the selected mailbox is searched. Messages are identified
+// by UID.
+//
+// This command requires support for the MULTISEARCH extension.
+func (c *Client) MultiSearch(sources []imap.MultiSearchSource, criteria *imap.SearchCriteria, options *imap.SearchOptions) *MultiSearchCommand {
+	cmd := &MultiSearchCommand{}
+	enc := c.beginCommand("ESEARCH", cmd)
+	if len(sources) > 0 {
+		enc.SP().Atom("IN").SP().Special('(')
+		for i, source := range sources {
+			if i > 0 {
+				enc.SP()
+			}
+			enc.Atom(string(source.Filter))
+			switch source.Filter {
+			case imap.MultiSearchFilterSubtree, imap.MultiSearchFilterSubtreeOne, imap.MultiSearchFilterMailboxes:
+				if len(source.Mailboxes) == 0 {
+					panic(fmt.Errorf("imapclient: ESEARCH filter %v requires at least one mailbox", source.Filter))
+				}
+				enc.SP().List(len(source.Mailboxes), func(i int) {
+					enc.Mailbox(source.Mailboxes[i])
+				})
+			}
+		}
+		enc.Special(')')
+	}
+	c.writeSearchProgram(enc, criteria, options)
+	enc.end()
+	return cmd
+}
+
+// MultiSearchCommand is an ESEARCH command.
+type MultiSearchCommand struct {
+	cmd
+	data []imap.MultiSearchData
+}
+
+// Wait blocks until the command has completed, and returns the results for
+// each mailbox containing matching messages.
+func (cmd *MultiSearchCommand) Wait() ([]imap.MultiSearchData, error) {
+	err := cmd.cmd.Wait()
+	return cmd.data, err
+}
@@ -0,0 +1,77 @@
+package imapclient_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/emersion/go-imap/v2"
+)
+
+func TestMultiSearch(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateSelected)
+	defer client.Close()
+	defer server.Close()
+
+	if !client.Caps().Has(imap.CapMultiSearch) {
+		t.Skip("MULTISEARCH not supported")
+	}
+
+	if err := client.Create("Archive", nil).Wait(); err != nil {
+		t.Fatalf("Create() = %v", err)
+	}
+	appendCmd := client.Append("Archive", int64(len(simpleRawMessage)), nil)
+	appendCmd.Write([]byte(simpleRawMessage))
+	appendCmd.Close()
+	if _, err := appendCmd.Wait(); err != nil {
+		t.Fatalf("AppendCommand.Wait() = %v", err)
+	}
+	statusData, err := client.Status("Archive", &imap.StatusOptions{UIDValidity: true}).Wait()
+	if err != nil {
+		t.Fatalf("Status() = %v", err)
+	}
+
+	criteria := imap.SearchCriteria{Body: []string{"letter"}}
+	tests := []struct {
+		name    string
+		sources []imap.MultiSearchSource
+		want    []string
+	}{
+		{"personal", []imap.MultiSearchSource{{Filter: imap.MultiSearchFilterPersonal}}, []string{"Archive", "INBOX"}},
+		{"mailboxes", []imap.MultiSearchSource{{Filter: imap.MultiSearchFilterMailboxes, Mailboxes: []string{"Archive"}}}, []string{"Archive"}},
+		{"selected", nil, []string{"INBOX"}},
+	}
+	for _, tc := range tests {
+		l, err := client.MultiSearch(tc.sources, &criteria, nil).Wait()
+		if err != nil {
+			t.Errorf("MultiSearch(%v).Wait() = %v", tc.name, err)
+			continue
+		}
+		if len(l) != len(tc.want) {
+			t.Errorf("MultiSearch(%v) returned %v mailboxes, want %v", tc.name, len(l), len(tc.want))
+			continue
+		}
+		for i, data := range l {
+			if data.Mailbox != tc.want[i] {
+				t.Errorf("MultiSearch(%v): Mailbox = %v, want %v", tc.name, data.Mailbox, tc.want[i])
+			}
+			if uids := data.Data.AllUIDs(); len(uids) != 1 || uids[0] != 1 {
+				t.Errorf("MultiSearch(%v): AllUIDs() = %v, want [1]", tc.name, uids)
+			}
+			if data.Mailbox == "Archive" && data.UIDValidity != statusData.UIDValidity {
+				t.Errorf("MultiSearch(%v): UIDValidity = %v, want %v", tc.name, data.UIDValidity, statusData.UIDValidity)
+			}
+		}
+	}
+
+	// Message sequence numbers refer to the selected mailbox
+	seqCriteria := imap.SearchCriteria{SeqNum: []imap.SeqSet{imap.SeqSetNum(1)}}
+	personal := []imap.MultiSearchSource{{Filter: imap.MultiSearchFilterPersonal}}
+	_, err = client.MultiSearch(personal, &seqCriteria, nil).Wait()
+	var imapErr *imap.Error
+	if !errors.As(err, &imapErr) || imapErr.Type != imap.StatusResponseTypeBad {
+		t.Errorf("MultiSearch(personal, seq).Wait() = %v, want BAD", err)
+	}
+	if _, err := client.MultiSearch(nil, &seqCriteria, nil).Wait(); err != nil {
+		t.Errorf("MultiSearch(selected, seq).Wait() = %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package imapclient
+
+import (
+	"fmt"
+
+	"github.com/emersion/go-imap/v2"
+)
+
+// Notify sends a NOTIFY SET command.
+//
+// Notifications for mailboxes other than the selected one are delivered via
+// UnilateralDataHandler.Status and UnilateralDataHandler.List.
+//
+// This command requires support for the NOTIFY extension.
+func (c *Client) Notify(options *imap.NotifyOptions) *Command {
+	cmd := &Command{}
+	enc := c.beginCommand("NOTIFY", cmd)
+	enc.SP().Atom("SET")
+	if options.Status {
+		enc.SP().Atom("STATUS")
+	}
+	for _, group := range options.Groups {
+		enc.SP().Special('(').Atom(string(group.Filter))
+		switch group.Filter {
+		case imap.NotifyFilterSubtree, imap.NotifyFilterMailboxes:
+			if len(group.Mailboxes) == 0 {
+				panic(fmt.Errorf("imapclient: NOTIFY filter %v requires at least one mailbox", group.Filter))
+			}
+			enc.SP().List(len(group.Mailboxes), func(i int) {
+				enc.Mailbox(group.Mailboxes[i])
+			})
+		}
+		enc.SP()
+		if len(group.Events) == 0 {
+			enc.Atom("NONE")
+		} else {
+			enc.List(len(group.Events), func(i int) {
+				enc.Atom(string(group.Events[i]))
+			})
+		}
+		enc.Special(')')
+	}
+	enc.end()
+	return cmd
+}
+
+// NotifyNone sends a NOTIFY NONE command.
+//
+// This disables all notifications.
+//
+// This command requires support for the NOTIFY extension.
+func (c *Client) NotifyNone() *Command {
+	cmd := &Command{}
+	enc := c.beginCommand("NOTIFY", cmd)
+	enc.SP().Atom("NONE")
+	enc.end()
+	return cmd
+}
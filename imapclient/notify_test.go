@@ -0,0 +1,124 @@
+package imapclient_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/imapclient"
+)
+
+func TestNotify(t *testing.T) {
+	conn, server := newMemClientServerPair(t)
+	defer server.Close()
+
+	statusCh := make(chan *imap.StatusData, 16)
+	listCh := make(chan *imap.ListData, 16)
+	client := imapclient.New(conn, &imapclient.Options{
+		UnilateralDataHandler: &imapclient.UnilateralDataHandler{
+			Status: func(data *imap.StatusData) {
+				statusCh <- data
+			},
+			List: func(data *imap.ListData) {
+				listCh <- data
+			},
+		},
+	})
+	defer client.Close()
+
+	if err := client.Login(testUsername, testPassword).Wait(); err != nil {
+		t.Fatalf("Login().Wait() = %v", err)
+	}
+
+	err := client.Notify(&imap.NotifyOptions{
+		Status: true,
+		Groups: []imap.NotifyGroup{
+			{
+				Filter: imap.NotifyFilterInboxes,
+				Events: []imap.NotifyEvent{
+					imap.NotifyEventMessageNew,
+					imap.NotifyEventMessageExpunge,
+				},
+			},
+			{
+				Filter: imap.NotifyFilterPersonal,
+				Events: []imap.NotifyEvent{imap.NotifyEventMailboxName},
+			},
+		},
+	}).Wait()
+	if err != nil {
+		t.Fatalf("Notify().Wait() = %v", err)
+	}
+	select {
+	case data := <-statusCh:
+		if data.Mailbox != "INBOX" || data.NumMessages == nil || *data.NumMessages != 0 {
+			t.Errorf("got STATUS %v, want INBOX with 0 messages", data)
+		}
+	default:
+		t.Fatalf("no STATUS response after NOTIFY SET STATUS")
+	}
+
+	appendCmd := client.Append("INBOX", int64(len(simpleRawMessage)), nil)
+	appendCmd.Write([]byte(simpleRawMessage))
+	appendCmd.Close()
+	if _, err := appendCmd.Wait(); err != nil {
+		t.Fatalf("AppendCommand.Wait() = %v", err)
+	}
+	select {
+	case data := <-statusCh:
+		if data.Mailbox != "INBOX" || data.NumMessages == nil || *data.NumMessages != 1 {
+			t.Errorf("got STATUS %v, want INBOX with 1 message", data)
+		}
+	default:
+		t.Fatalf("no STATUS response after APPEND")
+	}
+
+	if err := client.Create("Archive", nil).Wait(); err != nil {
+		t.Fatalf("Create().Wait() = %v", err)
+	}
+	select {
+	case data := <-listCh:
+		if data.Mailbox != "Archive" {
+			t.Errorf("got LIST %v, want Archive", data.Mailbox)
+		}
+	default:
+		t.Fatalf("no LIST response after CREATE")
+	}
+
+	err = client.Notify(&imap.NotifyOptions{
+		Groups: []imap.NotifyGroup{{
+			Filter: imap.NotifyFilterPersonal,
+			Events: []imap.NotifyEvent{imap.NotifyEventAnnotationChange},
+		}},
+	}).Wait()
+	var imapErr *imap.Error
+	if !errors.As(err, &imapErr) || imapErr.Code != imap.ResponseCodeBadEvent {
+		t.Errorf("Notify(AnnotationChange).Wait() = %v, want BADEVENT", err)
+	}
+
+	err = client.Notify(&imap.NotifyOptions{
+		Groups: []imap.NotifyGroup{{
+			Filter: imap.NotifyFilterSelected,
+			Events: []imap.NotifyEvent{
+				imap.NotifyEventMessageNew,
+				imap.NotifyEventMessageExpunge,
+			},
+		}},
+	}).Wait()
+	if !errors.As(err, &imapErr) || imapErr.Type != imap.StatusResponseTypeBad {
+		t.Errorf("Notify(Selected).Wait() = %v, want BAD", err)
+	}
+
+	if err := client.NotifyNone().Wait(); err != nil {
+		t.Fatalf("NotifyNone().Wait() = %v", err)
+	}
+	if err := client.Delete("Archive").Wait(); err != nil {
+		t.Fatalf("Delete().Wait() = %v", err)
+	}
+	select {
+	case data := <-listCh:
+		t.Errorf("got LIST %v after NOTIFY NONE", data.Mailbox)
+	default:
+		// ok
+	}
+}
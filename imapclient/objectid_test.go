@@ -0,0 +1,78 @@
+package imapclient_test
+
+import (
+	"testing"
+
+	"github.com/emersion/go-imap/v2"
+)
+
+func TestObjectID(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateSelected)
+	defer client.Close()
+	defer server.Close()
+
+	if !client.Caps().Has(imap.CapObjectID) {
+		t.Skip("OBJECTID not supported")
+	}
+
+	createCmd := client.Create("Archive", nil)
+	if err := createCmd.Wait(); err != nil {
+		t.Fatalf("Create().Wait() = %v", err)
+	}
+	mailboxID := createCmd.MailboxID()
+	if mailboxID == "" {
+		t.Fatalf("CreateCommand.MailboxID() = %q, want non-empty", mailboxID)
+	}
+
+	if err := client.Rename("Archive", "Archive2").Wait(); err != nil {
+		t.Fatalf("Rename().Wait() = %v", err)
+	}
+	statusData, err := client.Status("Archive2", &imap.StatusOptions{MailboxID: true}).Wait()
+	if err != nil {
+		t.Fatalf("Status().Wait() = %v", err)
+	} else if statusData.MailboxID != mailboxID {
+		t.Errorf("StatusData.MailboxID = %q, want %q", statusData.MailboxID, mailboxID)
+	}
+
+	fetchOptions := &imap.FetchOptions{EmailID: true, ThreadID: true}
+	msgs, err := client.Fetch(imap.SeqSetNum(1), fetchOptions).Collect()
+	if err != nil {
+		t.Fatalf("Fetch().Collect() = %v", err)
+	} else if len(msgs) != 1 {
+		t.Fatalf("len(msgs) = %v, want 1", len(msgs))
+	}
+	emailID, threadID := msgs[0].EmailID, msgs[0].ThreadID
+	if emailID == "" || threadID == "" {
+		t.Fatalf("EmailID = %q, ThreadID = %q, want non-empty", emailID, threadID)
+	}
+
+	searchData, err := client.UIDSearch(&imap.SearchCriteria{EmailID: []string{emailID}}, nil).Wait()
+	if err != nil {
+		t.Fatalf("UIDSearch().Wait() = %v", err)
+	} else if uids := searchData.AllUIDs(); len(uids) != 1 || uids[0] != 1 {
+		t.Errorf("UIDSearch().Wait().AllUIDs() = %v, want [1]", uids)
+	}
+
+	if _, err := client.Move(imap.SeqSetNum(1), "Archive2").Wait(); err != nil {
+		t.Fatalf("Move().Wait() = %v", err)
+	}
+	selectData, err := client.Select("Archive2", nil).Wait()
+	if err != nil {
+		t.Fatalf("Select().Wait() = %v", err)
+	} else if selectData.MailboxID != mailboxID {
+		t.Errorf("SelectData.MailboxID = %q, want %q", selectData.MailboxID, mailboxID)
+	}
+
+	msgs, err = client.Fetch(imap.SeqSetNum(1), fetchOptions).Collect()
+	if err != nil {
+		t.Fatalf("Fetch().Collect() = %v", err)
+	} else if len(msgs) != 1 {
+		t.Fatalf("len(msgs) = %v, want 1", len(msgs))
+	}
+	if msgs[0].EmailID != emailID {
+		t.Errorf("EmailID = %q, want %q", msgs[0].EmailID, emailID)
+	}
+	if msgs[0].ThreadID != threadID {
+		t.Errorf("ThreadID = %q, want %q", msgs[0].ThreadID, threadID)
+	}
+}
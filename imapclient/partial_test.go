@@ -0,0 +1,89 @@
+package imapclient_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/emersion/go-imap/v2"
+)
+
+func TestPartial(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateSelected)
+	defer client.Close()
+	defer server.Close()
+
+	if !client.Caps().Has(imap.CapPartial) {
+		t.Skip("PARTIAL not supported")
+	}
+
+	for i := 0; i < 4; i++ {
+		appendCmd := client.Append("INBOX", int64(len(simpleRawMessage)), nil)
+		appendCmd.Write([]byte(simpleRawMessage))
+		appendCmd.Close()
+		if _, err := appendCmd.Wait(); err != nil {
+			t.Fatalf("AppendCommand.Wait() = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		r    imap.PartialRange
+		want []imap.UID
+	}{
+		{"1:2", imap.PartialRange{Offset: 0, Count: 2}, []imap.UID{1, 2}},
+		{"4:10", imap.PartialRange{Offset: 3, Count: 7}, []imap.UID{4, 5}},
+		{"-1:-2", imap.PartialRange{Offset: -1, Count: 2}, []imap.UID{4, 5}},
+		{"-6:-10", imap.PartialRange{Offset: -6, Count: 5}, nil},
+		{"3000000001:3000000010", imap.PartialRange{Offset: 3000000000, Count: 10}, nil},
+	}
+	for _, tc := range tests {
+		r := tc.r
+		data, err := client.UIDSearch(&imap.SearchCriteria{}, &imap.SearchOptions{ReturnPartial: &r}).Wait()
+		if err != nil {
+			t.Errorf("UIDSearch(%v).Wait() = %v", tc.name, err)
+			continue
+		} else if data.Partial == nil {
+			t.Errorf("UIDSearch(%v): Partial = nil", tc.name)
+			continue
+		}
+		if data.Partial.Range != tc.r {
+			t.Errorf("UIDSearch(%v): Partial.Range = %v, want %v", tc.name, data.Partial.Range, tc.r)
+		}
+		var uids []imap.UID
+		if data.Partial.All != nil {
+			uids, _ = data.Partial.All.(imap.UIDSet).Nums()
+		}
+		if !reflect.DeepEqual(uids, tc.want) {
+			t.Errorf("UIDSearch(%v): Partial.All = %v, want %v", tc.name, uids, tc.want)
+		}
+	}
+
+	msgs, err := client.Fetch(imap.UIDSetNum(2, 3, 4), &imap.FetchOptions{
+		Partial: &imap.PartialRange{Offset: -1, Count: 2},
+	}).Collect()
+	if err != nil {
+		t.Fatalf("Fetch().Collect() = %v", err)
+	}
+	var uids []imap.UID
+	for _, msg := range msgs {
+		uids = append(uids, msg.UID)
+	}
+	if want := []imap.UID{3, 4}; !reflect.DeepEqual(uids, want) {
+		t.Errorf("Fetch(): UIDs = %v, want %v", uids, want)
+	}
+
+	_, err = client.Fetch(imap.SeqSetNum(1), &imap.FetchOptions{
+		Partial: &imap.PartialRange{Offset: 0, Count: 1},
+	}).Collect()
+	var imapErr *imap.Error
+	if !errors.As(err, &imapErr) || imapErr.Type != imap.StatusResponseTypeBad {
+		t.Errorf("Fetch(seq, PARTIAL).Collect() = %v, want BAD error", err)
+	}
+
+	// Invalid ranges can't be encoded
+	emptyRange := imap.PartialRange{Offset: 0, Count: 0}
+	if _, err := client.UIDSearch(&imap.SearchCriteria{}, &imap.SearchOptions{ReturnPartial: &emptyRange}).Wait(); err == nil {
+		t.Errorf("UIDSearch(empty range).Wait() = nil, want error")
+	}
+}
@@ -0,0 +1,54 @@
+package imapclient_test
+
+import (
+	"testing"
+
+	"github.com/emersion/go-imap/v2"
+)
+
+const htmlRawMessage = "MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/alternative; boundary=b\r\n" +
+	"\r\n" +
+	"--b\r\n" +
+	"Content-Type: text/html; charset=iso-8859-1\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"<html><head><style>p { color: red; }</style></head>\r\n" +
+	"<body><p>Caf=E9 at <b>noon</b>?</p><p>Tom &amp; Jerry</p></body></html>\r\n" +
+	"--b--\r\n"
+
+func TestFetch_preview(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateSelected)
+	defer client.Close()
+	defer server.Close()
+
+	if !client.Caps().Has(imap.CapPreview) {
+		t.Skip("PREVIEW not supported")
+	}
+
+	appendCmd := client.Append("INBOX", int64(len(htmlRawMessage)), nil)
+	appendCmd.Write([]byte(htmlRawMessage))
+	appendCmd.Close()
+	if _, err := appendCmd.Wait(); err != nil {
+		t.Fatalf("AppendCommand.Wait() = %v", err)
+	}
+
+	options := imap.FetchOptions{
+		Preview: &imap.FetchItemPreview{Lazy: true},
+	}
+	msgs, err := client.Fetch(imap.SeqSetNum(1, 2), &options).Collect()
+	if err != nil {
+		t.Fatalf("Fetch().Collect() = %v", err)
+	} else if len(msgs) != 2 {
+		t.Fatalf("len(msgs) = %v, want 2", len(msgs))
+	}
+
+	want := []string{"This is my letter!", "Café at noon? Tom & Jerry"}
+	for i, msg := range msgs {
+		if msg.Preview == nil {
+			t.Errorf("msgs[%v].Preview = nil, want %q", i, want[i])
+		} else if *msg.Preview != want[i] {
+			t.Errorf("msgs[%v].Preview = %q, want %q", i, *msg.Preview, want[i])
+		}
+	}
+}
@@ -129,17 +129,10 @@ func (cmd *GetQuotaRootCommand) Wait() ([]QuotaData, error) {
 	return cmd.data, nil
 }
 
-// QuotaData is the data returned by a QUOTA response.
-type QuotaData struct {
-	Root      string
-	Resources map[imap.QuotaResourceType]QuotaResourceData
-}
-
-// QuotaResourceData contains the usage and limit for a quota resource.
-type QuotaResourceData struct {
-	Usage int64
-	Limit int64
-}
+type (
+	QuotaData         = imap.QuotaData
+	QuotaResourceData = imap.QuotaResourceData
+)
 
 func readQuotaResponse(dec *imapwire.Decoder) (*QuotaData, error) {
 	var data QuotaData
@@ -0,0 +1,140 @@
+package imapclient_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/imapclient"
+	"github.com/emersion/go-imap/v2/imapserver/imapmemserver"
+)
+
+func TestQuota(t *testing.T) {
+	memServer := imapmemserver.New()
+	user := imapmemserver.NewUser(testUsername, testPassword)
+	user.Create("INBOX", nil)
+	user.Create("Archive", nil)
+	memServer.AddUser(user)
+
+	conn, server := serveMemServer(t, memServer)
+	defer server.Close()
+
+	client := imapclient.New(conn, nil)
+	defer client.Close()
+
+	if err := client.Login(testUsername, testPassword).Wait(); err != nil {
+		t.Fatalf("Login().Wait() = %v", err)
+	}
+	appendCmd := client.Append("INBOX", int64(len(simpleRawMessage)), nil)
+	appendCmd.Write([]byte(simpleRawMessage))
+	appendCmd.Close()
+	if _, err := appendCmd.Wait(); err != nil {
+		t.Fatalf("AppendCommand.Wait() = %v", err)
+	}
+
+	// Users can't change their own limits, QUOTASET isn't advertised
+	limits := map[imap.QuotaResourceType]int64{imap.QuotaResourceMessage: 1}
+	if client.Caps().Has(imap.CapQuotaSet) {
+		t.Errorf("QUOTASET is advertised")
+	}
+	if err := client.SetQuota("", limits).Wait(); err == nil {
+		t.Errorf("SetQuota().Wait() = nil, want error")
+	}
+
+	if _, err := user.SetQuota("", limits); err != nil {
+		t.Fatalf("User.SetQuota() = %v", err)
+	}
+
+	l, err := client.GetQuotaRoot("INBOX").Wait()
+	if err != nil {
+		t.Fatalf("GetQuotaRoot().Wait() = %v", err)
+	} else if len(l) != 1 {
+		t.Fatalf("len(GetQuotaRoot().Wait()) = %v, want 1", len(l))
+	}
+	res := l[0].Resources[imap.QuotaResourceMessage]
+	if res.Usage != 1 || res.Limit != 1 {
+		t.Errorf("MESSAGE quota resource = %+v, want usage = 1, limit = 1", res)
+	}
+
+	appendCmd = client.Append("INBOX", int64(len(simpleRawMessage)), nil)
+	appendCmd.Write([]byte(simpleRawMessage))
+	appendCmd.Close()
+	_, err = appendCmd.Wait()
+	var imapErr *imap.Error
+	if !errors.As(err, &imapErr) || imapErr.Code != imap.ResponseCodeOverQuota {
+		t.Errorf("AppendCommand.Wait() = %v, want OVERQUOTA error", err)
+	}
+
+	if _, err := client.Select("INBOX", nil).Wait(); err != nil {
+		t.Fatalf("Select().Wait() = %v", err)
+	}
+	if _, err := client.Copy(imap.SeqSetNum(1), "Archive").Wait(); !errors.As(err, &imapErr) || imapErr.Code != imap.ResponseCodeOverQuota {
+		t.Errorf("Copy().Wait() = %v, want OVERQUOTA error", err)
+	}
+	// Moving messages doesn't increase usage
+	if _, err := client.Move(imap.SeqSetNum(1), "Archive").Wait(); err != nil {
+		t.Errorf("Move().Wait() = %v", err)
+	}
+}
+
+func TestQuota_shared(t *testing.T) {
+	const otherUsername, otherPassword = "other-user", "other-password"
+
+	memServer := imapmemserver.New()
+	owner := imapmemserver.NewUser(testUsername, testPassword)
+	memServer.AddUser(owner)
+	other := imapmemserver.NewUser(otherUsername, otherPassword)
+	other.Create("INBOX", nil)
+	memServer.AddUser(other)
+
+	shared := imapmemserver.NewMailbox("Shared", 1)
+	owner.AddMailbox(shared)
+	other.AddMailbox(shared)
+
+	limits := map[imap.QuotaResourceType]int64{imap.QuotaResourceMessage: 1}
+	if _, err := owner.SetQuota("", limits); err != nil {
+		t.Fatalf("User.SetQuota() = %v", err)
+	}
+	if _, err := other.SetQuota("", limits); err != nil {
+		t.Fatalf("User.SetQuota() = %v", err)
+	}
+
+	conn, server := serveMemServer(t, memServer)
+	defer server.Close()
+
+	client := imapclient.New(conn, nil)
+	defer client.Close()
+
+	if err := client.Login(otherUsername, otherPassword).Wait(); err != nil {
+		t.Fatalf("Login().Wait() = %v", err)
+	}
+	for _, mailbox := range []string{"Shared", "INBOX"} {
+		appendCmd := client.Append(mailbox, int64(len(simpleRawMessage)), nil)
+		appendCmd.Write([]byte(simpleRawMessage))
+		appendCmd.Close()
+		if _, err := appendCmd.Wait(); err != nil {
+			t.Fatalf("AppendCommand.Wait() = %v", err)
+		}
+	}
+
+	// The shared mailbox counts towards its owner's quota only
+	l, err := client.GetQuotaRoot("INBOX").Wait()
+	if err != nil {
+		t.Fatalf("GetQuotaRoot().Wait() = %v", err)
+	} else if len(l) != 1 {
+		t.Fatalf("len(GetQuotaRoot().Wait()) = %v, want 1", len(l))
+	}
+	if res := l[0].Resources[imap.QuotaResourceMessage]; res.Usage != 1 {
+		t.Errorf("MESSAGE quota resource = %+v, want usage = 1", res)
+	}
+
+	// Moving a message into a mailbox with another owner increases their
+	// usage
+	if _, err := client.Select("INBOX", nil).Wait(); err != nil {
+		t.Fatalf("Select().Wait() = %v", err)
+	}
+	var imapErr *imap.Error
+	if _, err := client.Move(imap.SeqSetNum(1), "Shared").Wait(); !errors.As(err, &imapErr) || imapErr.Code != imap.ResponseCodeOverQuota {
+		t.Errorf("Move().Wait() = %v, want OVERQUOTA error", err)
+	}
+}
@@ -0,0 +1,53 @@
+package imapclient
+
+import (
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/internal/imapwire"
+)
+
+// Replace sends a REPLACE command.
+//
+// The message with the sequence number seqNum in the selected mailbox is
+// atomically expunged and replaced with a new message appended to mailbox.
+// The caller must write the message contents, then call AppendCommand.Close.
+//
+// The options are optional.
+//
+// This command requires support for the REPLACE extension.
+func (c *Client) Replace(seqNum uint32, mailbox string, size int64, options *imap.AppendOptions) *AppendCommand {
+	return c.replace(imap.SeqSetNum(seqNum), mailbox, size, options)
+}
+
+// UIDReplace is like Replace, except that the message is identified by its
+// UID.
+func (c *Client) UIDReplace(uid imap.UID, mailbox string, size int64, options *imap.AppendOptions) *AppendCommand {
+	return c.replace(imap.UIDSetNum(uid), mailbox, size, options)
+}
+
+func (c *Client) replace(numSet imap.NumSet, mailbox string, size int64, options *imap.AppendOptions) *AppendCommand {
+	cmd := &AppendCommand{}
+	numKind := imapwire.NumSetKind(numSet)
+	if err := c.checkUIDOnly(numKind); err != nil {
+		cmd.wc = errWriteCloser{err}
+		c.failCommand(cmd, err)
+		return cmd
+	}
+	cmd.enc = c.beginCommand(uidCmdName("REPLACE", numKind), cmd)
+	cmd.enc.SP().NumSet(numSet).SP().Mailbox(mailbox).SP()
+	writeAppendOptions(cmd.enc, options)
+	cmd.wc = cmd.enc.Literal(size)
+	return cmd
+}
+
+// errWriteCloser is an io.WriteCloser which always fails.
+type errWriteCloser struct {
+	err error
+}
+
+func (wc errWriteCloser) Write(b []byte) (int, error) {
+	return 0, wc.err
+}
+
+func (wc errWriteCloser) Close() error {
+	return wc.err
+}
@@ -0,0 +1,94 @@
+package imapclient_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/imapclient"
+	"github.com/emersion/go-imap/v2/imapserver/imapmemserver"
+)
+
+func TestUIDReplace(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateSelected)
+	defer client.Close()
+	defer server.Close()
+
+	if !client.Caps().Has(imap.CapReplace) {
+		t.Skip("REPLACE not supported")
+	}
+
+	body := "This is a replacement message."
+
+	replaceCmd := client.UIDReplace(1, "INBOX", int64(len(body)), &imap.AppendOptions{
+		Flags: []imap.Flag{imap.FlagDraft},
+	})
+	if _, err := replaceCmd.Write([]byte(body)); err != nil {
+		t.Fatalf("AppendCommand.Write() = %v", err)
+	}
+	if err := replaceCmd.Close(); err != nil {
+		t.Fatalf("AppendCommand.Close() = %v", err)
+	}
+	data, err := replaceCmd.Wait()
+	if err != nil {
+		t.Fatalf("AppendCommand.Wait() = %v", err)
+	} else if data.UID != 2 {
+		t.Errorf("AppendData.UID = %v, want 2", data.UID)
+	}
+
+	msgs, err := client.Fetch(imap.SeqSetNum(1, 2), &imap.FetchOptions{UID: true}).Collect()
+	if err != nil {
+		t.Fatalf("Fetch().Collect() = %v", err)
+	} else if len(msgs) != 1 || msgs[0].UID != 2 {
+		t.Errorf("Fetch().Collect() = %v, want a single message with UID 2", msgs)
+	}
+
+	replaceCmd = client.UIDReplace(0, "INBOX", int64(len(body)), nil)
+	replaceCmd.Write([]byte(body))
+	replaceCmd.Close()
+	_, err = replaceCmd.Wait()
+	var imapErr *imap.Error
+	if !errors.As(err, &imapErr) || imapErr.Type != imap.StatusResponseTypeBad {
+		t.Errorf("AppendCommand.Wait() = %v, want BAD error", err)
+	}
+}
+
+func TestUIDReplace_quota(t *testing.T) {
+	memServer := imapmemserver.New()
+	user := imapmemserver.NewUser(testUsername, testPassword)
+	user.Create("INBOX", nil)
+	memServer.AddUser(user)
+
+	conn, server := serveMemServer(t, memServer)
+	defer server.Close()
+
+	client := imapclient.New(conn, nil)
+	defer client.Close()
+
+	if err := client.Login(testUsername, testPassword).Wait(); err != nil {
+		t.Fatalf("Login().Wait() = %v", err)
+	}
+	appendCmd := client.Append("INBOX", int64(len(simpleRawMessage)), nil)
+	appendCmd.Write([]byte(simpleRawMessage))
+	appendCmd.Close()
+	if _, err := appendCmd.Wait(); err != nil {
+		t.Fatalf("AppendCommand.Wait() = %v", err)
+	}
+	if _, err := client.Select("INBOX", nil).Wait(); err != nil {
+		t.Fatalf("Select().Wait() = %v", err)
+	}
+
+	limits := map[imap.QuotaResourceType]int64{imap.QuotaResourceMessage: 1}
+	if _, err := user.SetQuota("", limits); err != nil {
+		t.Fatalf("User.SetQuota() = %v", err)
+	}
+
+	// Replacing a message doesn't increase the number of messages
+	body := "This is a replacement message."
+	replaceCmd := client.UIDReplace(1, "INBOX", int64(len(body)), nil)
+	replaceCmd.Write([]byte(body))
+	replaceCmd.Close()
+	if _, err := replaceCmd.Wait(); err != nil {
+		t.Errorf("AppendCommand.Wait() = %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package imapclient_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/emersion/go-imap/v2"
+)
+
+func TestSaveDate(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateSelected)
+	defer client.Close()
+	defer server.Close()
+
+	if !client.Caps().Has(imap.CapSaveDate) {
+		t.Skip("SAVEDATE not supported")
+	}
+
+	msgs, err := client.Fetch(imap.SeqSetNum(1), &imap.FetchOptions{SaveDate: true}).Collect()
+	if err != nil {
+		t.Fatalf("Fetch().Collect() = %v", err)
+	} else if len(msgs) != 1 {
+		t.Fatalf("len(msgs) = %v, want 1", len(msgs))
+	} else if msgs[0].SaveDate.IsZero() {
+		t.Errorf("SaveDate is zero")
+	}
+
+	twoDaysAgo := time.Now().Add(-48 * time.Hour)
+	twoDaysAgo = time.Date(twoDaysAgo.Year(), twoDaysAgo.Month(), twoDaysAgo.Day(), 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		criteria imap.SearchCriteria
+		want     uint32
+	}{
+		{"SAVEDSINCE", imap.SearchCriteria{SavedSince: twoDaysAgo}, 1},
+		{"SAVEDBEFORE", imap.SearchCriteria{SavedBefore: twoDaysAgo}, 0},
+		{"SAVEDATESUPPORTED", imap.SearchCriteria{SaveDateSupported: true}, 1},
+	}
+	for _, tc := range tests {
+		data, err := client.Search(&tc.criteria, &imap.SearchOptions{ReturnCount: true}).Wait()
+		if err != nil {
+			t.Errorf("Search(%v).Wait() = %v", tc.name, err)
+		} else if data.Count != tc.want {
+			t.Errorf("Search(%v): Count = %v, want %v", tc.name, data.Count, tc.want)
+		}
+	}
+}
@@ -17,10 +17,12 @@ func returnSearchOptions(options *imap.SearchOptions) []string {
 	}
 
 	m := map[string]bool{
-		"MIN":   options.ReturnMin,
-		"MAX":   options.ReturnMax,
-		"ALL":   options.ReturnAll,
-		"COUNT": options.ReturnCount,
+		"MIN":       options.ReturnMin,
+		"MAX":       options.ReturnMax,
+		"ALL":       options.ReturnAll,
+		"COUNT":     options.ReturnCount,
+		"RELEVANCY": options.ReturnRelevancy,
+		"UPDATE":    options.ReturnUpdate,
 	}
 
 	var l []string
@@ -33,6 +35,29 @@ func returnSearchOptions(options *imap.SearchOptions) []string {
 }
 
 func (c *Client) search(numKind imapwire.NumKind, criteria *imap.SearchCriteria, options *imap.SearchOptions) *SearchCommand {
+	var all imap.NumSet
+	switch numKind {
+	case imapwire.NumKindSeq:
+		all = imap.SeqSet(nil)
+	case imapwire.NumKindUID:
+		all = imap.UIDSet(nil)
+	}
+
+	cmd := &SearchCommand{}
+	cmd.data.All = all
+	if err := c.checkUIDOnly(numKind); err != nil {
+		c.failCommand(cmd, err)
+		return cmd
+	}
+	enc := c.beginCommand(uidCmdName("SEARCH", numKind), cmd)
+	c.writeSearchProgram(enc, criteria, options)
+	enc.end()
+	return cmd
+}
+
+// writeSearchProgram writes the search return options, the charset and the
+// search criteria, preceded by a space.
+func (c *Client) writeSearchProgram(enc *commandEncoder, criteria *imap.SearchCriteria, options *imap.SearchOptions) {
 	// The IMAP4rev2 SEARCH charset defaults to UTF-8. When UTF8=ACCEPT is
 	// enabled, specifying any CHARSET is invalid. For IMAP4rev1 the default is
 	// undefined and only US-ASCII support is required. What's more, some
@@ -42,34 +67,29 @@ func (c *Client) search(numKind imapwire.NumKind, criteria *imap.SearchCriteria,
 	// servers even if we only send ASCII characters: the server then must
 	// decode encoded headers and Content-Transfer-Encoding before matching the
 	// criteria.
+	criteria = c.searchCriteriaWithin(criteria)
+
 	var charset string
 	if !c.Caps().Has(imap.CapIMAP4rev2) && !c.enabled.Has(imap.CapUTF8Accept) && !searchCriteriaIsASCII(criteria) {
 		charset = "UTF-8"
 	}
 
-	var all imap.NumSet
-	switch numKind {
-	case imapwire.NumKindSeq:
-		all = imap.SeqSet(nil)
-	case imapwire.NumKindUID:
-		all = imap.UIDSet(nil)
-	}
-
-	cmd := &SearchCommand{}
-	cmd.data.All = all
-	enc := c.beginCommand(uidCmdName("SEARCH", numKind), cmd)
-	if returnOpts := returnSearchOptions(options); len(returnOpts) > 0 {
-		enc.SP().Atom("RETURN").SP().List(len(returnOpts), func(i int) {
-			enc.Atom(returnOpts[i])
-		})
+	returnOpts := returnSearchOptions(options)
+	if len(returnOpts) > 0 || (options != nil && options.ReturnPartial != nil) {
+		listEnc := enc.SP().Atom("RETURN").SP().BeginList()
+		for _, opt := range returnOpts {
+			listEnc.Item().Atom(opt)
+		}
+		if options.ReturnPartial != nil {
+			listEnc.Item().Atom("PARTIAL").SP().PartialRange(*options.ReturnPartial)
+		}
+		listEnc.End()
 	}
 	enc.SP()
 	if charset != "" {
 		enc.Atom("CHARSET").SP().Atom(charset).SP()
 	}
 	writeSearchKey(enc.Encoder, criteria)
-	enc.end()
-	return cmd
 }
 
 // Search sends a SEARCH command.
@@ -124,24 +144,65 @@ func (c *Client) handleESearch() error {
 	if !c.dec.ExpectSP() {
 		return c.dec.Err()
 	}
-	tag, data, err := readESearchResponse(c.dec)
+	resp, err := readESearchResponse(c.dec)
 	if err != nil {
 		return err
 	}
+
+	if len(resp.addTo) > 0 || len(resp.removeFrom) > 0 {
+		if handler := c.options.unilateralDataHandler().SearchUpdate; handler != nil {
+			handler(&imap.SearchUpdate{
+				Tag:        resp.tag,
+				UID:        resp.data.UID,
+				AddTo:      resp.addTo,
+				RemoveFrom: resp.removeFrom,
+			})
+		}
+		return nil
+	}
+
+	if resp.mailbox != "" {
+		cmd := c.findPendingCmdFunc(func(anyCmd command) bool {
+			_, ok := anyCmd.(*MultiSearchCommand)
+			return ok && anyCmd.base().tag == resp.tag
+		})
+		if cmd, ok := cmd.(*MultiSearchCommand); ok {
+			if resp.data.All == nil {
+				resp.data.All = imap.UIDSet(nil)
+			}
+			cmd.data = append(cmd.data, imap.MultiSearchData{
+				Mailbox:     resp.mailbox,
+				UIDValidity: resp.uidValidity,
+				Data:        resp.data,
+			})
+		}
+		return nil
+	}
+
 	cmd := c.findPendingCmdFunc(func(anyCmd command) bool {
-		cmd, ok := anyCmd.(*SearchCommand)
-		if !ok {
+		switch anyCmd.(type) {
+		case *SearchCommand, *SortCommand:
+			// ok
+		default:
 			return false
 		}
-		if tag != "" {
-			return cmd.tag == tag
+		if resp.tag != "" {
+			return anyCmd.base().tag == resp.tag
 		} else {
 			return true
 		}
 	})
-	if cmd != nil {
-		cmd := cmd.(*SearchCommand)
-		cmd.data = *data
+	switch cmd := cmd.(type) {
+	case *SearchCommand:
+		cmd.data = resp.data
+	case *SortCommand:
+		if resp.all != "" {
+			nums, err := parseOrderedNums(resp.all)
+			if err != nil {
+				return err
+			}
+			cmd.nums = nums
+		}
 	}
 	return nil
 }
@@ -196,6 +257,27 @@ func writeSearchKey(enc *imapwire.Encoder, criteria *imap.SearchCriteria) {
 		}
 	}
 
+	if !criteria.SavedSince.IsZero() && !criteria.SavedBefore.IsZero() && criteria.SavedBefore.Sub(criteria.SavedSince) == 24*time.Hour {
+		encodeItem().Atom("SAVEDON").SP().String(criteria.SavedSince.Format(internal.DateLayout))
+	} else {
+		if !criteria.SavedSince.IsZero() {
+			encodeItem().Atom("SAVEDSINCE").SP().String(criteria.SavedSince.Format(internal.DateLayout))
+		}
+		if !criteria.SavedBefore.IsZero() {
+			encodeItem().Atom("SAVEDBEFORE").SP().String(criteria.SavedBefore.Format(internal.DateLayout))
+		}
+	}
+	if criteria.SaveDateSupported {
+		encodeItem().Atom("SAVEDATESUPPORTED")
+	}
+
+	if criteria.Older != 0 {
+		encodeItem().Atom("OLDER").SP().Number(durationSeconds(criteria.Older))
+	}
+	if criteria.Younger != 0 {
+		encodeItem().Atom("YOUNGER").SP().Number(durationSeconds(criteria.Younger))
+	}
+
 	for _, kv := range criteria.Header {
 		switch k := strings.ToUpper(kv.Key); k {
 		case "BCC", "CC", "FROM", "SUBJECT", "TO":
@@ -248,6 +330,13 @@ func writeSearchKey(enc *imapwire.Encoder, criteria *imap.SearchCriteria) {
 		}
 	}
 
+	for _, id := range criteria.EmailID {
+		encodeItem().Atom("EMAILID").SP().Atom(id)
+	}
+	for _, id := range criteria.ThreadID {
+		encodeItem().Atom("THREADID").SP().Atom(id)
+	}
+
 	for _, not := range criteria.Not {
 		encodeItem().Atom("NOT").SP()
 		writeSearchKey(enc, &not)
@@ -258,6 +347,14 @@ func writeSearchKey(enc *imapwire.Encoder, criteria *imap.SearchCriteria) {
 		enc.SP()
 		writeSearchKey(enc, &or[1])
 	}
+	for _, fuzzy := range criteria.Fuzzy {
+		encodeItem().Atom("FUZZY").SP()
+		writeSearchKey(enc, &fuzzy)
+	}
+
+	for _, name := range criteria.Filter {
+		encodeItem().Atom("FILTER").SP().Atom(name)
+	}
 
 	if firstItem {
 		enc.Atom("ALL")
@@ -275,50 +372,83 @@ func flagSearchKey(flag imap.Flag) string {
 	}
 }
 
-func readESearchResponse(dec *imapwire.Decoder) (tag string, data *imap.SearchData, err error) {
-	data = &imap.SearchData{}
+// esearchResponse is a parsed ESEARCH response.
+type esearchResponse struct {
+	tag  string
+	data imap.SearchData
+	// ALL as sent by the server, to preserve the order for ESORT
+	all string
+	// requires CONTEXT=SEARCH or CONTEXT=SORT
+	addTo, removeFrom []imap.SearchUpdateRange
+	// requires MULTISEARCH
+	mailbox     string
+	uidValidity uint32
+}
+
+func readESearchResponse(dec *imapwire.Decoder) (*esearchResponse, error) {
+	resp := &esearchResponse{}
+	data := &resp.data
 	if dec.Special('(') { // search-correlator
-		var correlator string
-		if !dec.ExpectAtom(&correlator) || !dec.ExpectSP() || !dec.ExpectAString(&tag) || !dec.ExpectSpecial(')') {
-			return "", nil, dec.Err()
+		for {
+			var correlator string
+			if !dec.ExpectAtom(&correlator) || !dec.ExpectSP() {
+				return nil, dec.Err()
+			}
+			var ok bool
+			switch strings.ToUpper(correlator) {
+			case "TAG":
+				ok = dec.ExpectAString(&resp.tag)
+			case "MAILBOX":
+				ok = dec.ExpectMailbox(&resp.mailbox)
+			case "UIDVALIDITY":
+				ok = dec.ExpectNumber(&resp.uidValidity)
+			default:
+				return nil, fmt.Errorf("in search-correlator: unknown name %q", correlator)
+			}
+			if !ok {
+				return nil, dec.Err()
+			}
+			if !dec.SP() {
+				break
+			}
 		}
-		if correlator != "TAG" {
-			return "", nil, fmt.Errorf("in search-correlator: name must be TAG, but got %q", correlator)
+		if !dec.ExpectSpecial(')') {
+			return nil, dec.Err()
 		}
 	}
 
 	var name string
 	if !dec.SP() {
-		return tag, data, nil
+		return resp, nil
 	} else if !dec.ExpectAtom(&name) {
-		return "", nil, dec.Err()
+		return nil, dec.Err()
 	}
 	data.UID = name == "UID"
 
 	if data.UID {
 		if !dec.SP() {
-			return tag, data, nil
+			return resp, nil
 		} else if !dec.ExpectAtom(&name) {
-			return "", nil, dec.Err()
+			return nil, dec.Err()
 		}
 	}
 
 	for {
 		if !dec.ExpectSP() {
-			return "", nil, dec.Err()
+			return nil, dec.Err()
 		}
 
 		switch strings.ToUpper(name) {
 		case "MIN":
 			var num uint32
 			if !dec.ExpectNumber(&num) {
-				return "", nil, dec.Err()
+				return nil, dec.Err()
 			}
 			data.Min = num
 		case "MAX":
 			var num uint32
 			if !dec.ExpectNumber(&num) {
-				return "", nil, dec.Err()
+				return nil, dec.Err()
 			}
 			data.Max = num
 		case "ALL":
@@ -326,38 +456,127 @@ func readESearchResponse(dec *imapwire.Decoder) (tag string, data *imap.SearchDa
 			if data.UID {
 				numKind = imapwire.NumKindUID
 			}
-			if !dec.ExpectNumSet(numKind, &data.All) {
-				return "", nil, dec.Err()
+			if !dec.ExpectAtom(&resp.all) {
+				return nil, dec.Err()
+			}
+			all, err := parseNumSet(numKind, resp.all)
+			if err != nil {
+				return nil, fmt.Errorf("in ALL: %w", err)
+			}
+			data.All = all
+		case "ADDTO", "REMOVEFROM":
+			var r imap.SearchUpdateRange
+			var s string
+			if !dec.ExpectSpecial('(') || !dec.ExpectNumber(&r.Position) || !dec.ExpectSP() || !dec.ExpectAtom(&s) || !dec.ExpectSpecial(')') {
+				return nil, dec.Err()
+			}
+			nums, err := parseOrderedNums(s)
+			if err != nil {
+				return nil, fmt.Errorf("in %v: %w", name, err)
 			}
-			if data.All.Dynamic() {
-				return "", nil, fmt.Errorf("imapclient: server returned a dynamic ALL number set in SEARCH response")
+			r.Nums = nums
+			if strings.ToUpper(name) == "ADDTO" {
+				resp.addTo = append(resp.addTo, r)
+			} else {
+				resp.removeFrom = append(resp.removeFrom, r)
 			}
 		case "COUNT":
 			var num uint32
 			if !dec.ExpectNumber(&num) {
-				return "", nil, dec.Err()
+				return nil, dec.Err()
 			}
 			data.Count = num
 		case "MODSEQ":
 			var modSeq uint64
 			if !dec.ExpectModSeq(&modSeq) {
-				return "", nil, dec.Err()
+				return nil, dec.Err()
 			}
 			data.ModSeq = modSeq
+		case "RELEVANCY":
+			err := dec.ExpectList(func() error {
+				var score uint32
+				if !dec.ExpectNumber(&score) {
+					return dec.Err()
+				}
+				data.Relevancy = append(data.Relevancy, uint8(score))
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		case "PARTIAL":
+			numKind := imapwire.NumKindSeq
+			if data.UID {
+				numKind = imapwire.NumKindUID
+			}
+			var partial imap.SearchPartialData
+			if !dec.ExpectSpecial('(') || !dec.ExpectPartialRange(&partial.Range) || !dec.ExpectSP() {
+				return nil, dec.Err()
+			}
+			if dec.PeekFold("NIL") {
+				if !dec.ExpectNIL() {
+					return nil, dec.Err()
+				}
+			} else if !dec.ExpectNumSet(numKind, &partial.All) {
+				return nil, dec.Err()
+			} else if partial.All.Dynamic() {
+				return nil, fmt.Errorf("imapclient: server returned a dynamic PARTIAL number set in SEARCH response")
+			}
+			if !dec.ExpectSpecial(')') {
+				return nil, dec.Err()
+			}
+			data.Partial = &partial
 		default:
 			if !dec.DiscardValue() {
-				return "", nil, dec.Err()
+				return nil, dec.Err()
 			}
 		}
 
 		if !dec.SP() {
 			break
 		} else if !dec.ExpectAtom(&name) {
-			return "", nil, dec.Err()
+			return nil, dec.Err()
 		}
 	}
 
-	return tag, data, nil
+	return resp, nil
+}
+
+func parseNumSet(numKind imapwire.NumKind, s string) (imap.NumSet, error) {
+	var (
+		numSet imap.NumSet
+		err    error
+	)
+	switch numKind {
+	case imapwire.NumKindSeq:
+		numSet, err = imapwire.ParseSeqSet(s)
+	case imapwire.NumKindUID:
+		numSet, err = imapwire.ParseUIDSet(s)
+	}
+	if err != nil {
+		return nil, err
+	} else if numSet.Dynamic() {
+		return nil, fmt.Errorf("imapclient: server returned a dynamic number set in SEARCH response")
+	}
+	return numSet, nil
+}
+
+// parseOrderedNums parses a sequence set, preserving the order of the
+// numbers.
+func parseOrderedNums(s string) ([]uint32, error) {
+	var nums []uint32
+	for _, part := range strings.Split(s, ",") {
+		seqSet, err := imapwire.ParseSeqSet(part)
+		if err != nil {
+			return nil, err
+		}
+		l, ok := seqSet.Nums()
+		if !ok {
+			return nil, fmt.Errorf("imapclient: server returned a dynamic number set in SEARCH response")
+		}
+		nums = append(nums, l...)
+	}
+	return nums, nil
 }
 
 func searchCriteriaIsASCII(criteria *imap.SearchCriteria) bool {
@@ -386,6 +605,11 @@ func searchCriteriaIsASCII(criteria *imap.SearchCriteria) bool {
 			return false
 		}
 	}
+	for _, fuzzy := range criteria.Fuzzy {
+		if !searchCriteriaIsASCII(&fuzzy) {
+			return false
+		}
+	}
 	return true
 }
 
@@ -397,3 +621,85 @@ func isASCII(s string) bool {
 	}
 	return true
 }
+
+func durationSeconds(d time.Duration) uint32 {
+	n := uint32(d / time.Second)
+	if n == 0 {
+		n = 1 // nz-number
+	}
+	return n
+}
+
+// searchCriteriaWithin replaces OLDER and YOUNGER with BEFORE and SINCE if
+// the server doesn't support WITHIN.
+//
+// Since BEFORE and SINCE only operate on dates, the result may not be exact:
+// messages older than the requested interval may be left out from OLDER
+// results, and younger messages may be included in YOUNGER results.
+func (c *Client) searchCriteriaWithin(criteria *imap.SearchCriteria) *imap.SearchCriteria {
+	if c.Caps().Has(imap.CapWithin) || !searchCriteriaHasWithin(criteria) {
+		return criteria
+	}
+	return convertSearchCriteriaWithin(criteria, time.Now())
+}
+
+func convertSearchCriteriaWithin(criteria *imap.SearchCriteria, now time.Time) *imap.SearchCriteria {
+	converted := *criteria
+	converted.Older, converted.Younger = 0, 0
+
+	if criteria.Older != 0 {
+		before := truncateDate(now.Add(-criteria.Older))
+		if converted.Before.IsZero() || before.Before(converted.Before) {
+			converted.Before = before
+		}
+	}
+	if criteria.Younger != 0 {
+		since := truncateDate(now.Add(-criteria.Younger))
+		if converted.Since.IsZero() || since.After(converted.Since) {
+			converted.Since = since
+		}
+	}
+
+	converted.Not = make([]imap.SearchCriteria, len(criteria.Not))
+	for i := range criteria.Not {
+		converted.Not[i] = *convertSearchCriteriaWithin(&criteria.Not[i], now)
+	}
+	converted.Or = make([][2]imap.SearchCriteria, len(criteria.Or))
+	for i := range criteria.Or {
+		converted.Or[i][0] = *convertSearchCriteriaWithin(&criteria.Or[i][0], now)
+		converted.Or[i][1] = *convertSearchCriteriaWithin(&criteria.Or[i][1], now)
+	}
+	converted.Fuzzy = make([]imap.SearchCriteria, len(criteria.Fuzzy))
+	for i := range criteria.Fuzzy {
+		converted.Fuzzy[i] = *convertSearchCriteriaWithin(&criteria.Fuzzy[i], now)
+	}
+
+	return &converted
+}
+
+func searchCriteriaHasWithin(criteria *imap.SearchCriteria) bool {
+	if criteria.Older != 0 || criteria.Younger != 0 {
+		return true
+	}
+	for i := range criteria.Not {
+		if searchCriteriaHasWithin(&criteria.Not[i]) {
+			return true
+		}
+	}
+	for i := range criteria.Or {
+		if searchCriteriaHasWithin(&criteria.Or[i][0]) || searchCriteriaHasWithin(&criteria.Or[i][1]) {
+			return true
+		}
+	}
+	for i := range criteria.Fuzzy {
+		if searchCriteriaHasWithin(&criteria.Fuzzy[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+func truncateDate(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
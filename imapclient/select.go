@@ -3,6 +3,7 @@ package imapclient
 import (
 	"github.com/emersion/go-imap/v2"
 	"github.com/emersion/go-imap/v2/internal"
+	"github.com/emersion/go-imap/v2/internal/imapwire"
 )
 
 // Select sends a SELECT or EXAMINE command.
@@ -17,13 +18,33 @@ func (c *Client) Select(mailbox string, options *imap.SelectOptions) *SelectComm
 	cmd := &SelectCommand{mailbox: mailbox}
 	enc := c.beginCommand(cmdName, cmd)
 	enc.SP().Mailbox(mailbox)
-	if options != nil && options.CondStore {
-		enc.SP().Special('(').Atom("CONDSTORE").Special(')')
+	if options != nil && (options.CondStore || options.QResync != nil) {
+		enc.SP()
+		listEnc := enc.BeginList()
+		if options.CondStore {
+			listEnc.Item().Atom("CONDSTORE")
+		}
+		if options.QResync != nil {
+			listEnc.Item().Atom("QRESYNC").SP()
+			writeSelectQResync(enc.Encoder, options.QResync)
+		}
+		listEnc.End()
 	}
 	enc.end()
 	return cmd
 }
 
+func writeSelectQResync(enc *imapwire.Encoder, qresync *imap.SelectQResync) {
+	enc.Special('(').Number(qresync.UIDValidity).SP().ModSeq(qresync.ModSeq)
+	if qresync.KnownUIDs != nil {
+		enc.SP().NumSet(qresync.KnownUIDs)
+	}
+	if seqMatch := qresync.SeqMatch; seqMatch != nil {
+		enc.SP().Special('(').NumSet(seqMatch.SeqNums).SP().NumSet(seqMatch.UIDs).Special(')')
+	}
+	enc.Special(')')
+}
+
 // Unselect sends an UNSELECT command.
 //
 // This command requires support for IMAP4rev2 or the UNSELECT extension.
@@ -0,0 +1,114 @@
+package imapclient_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/imapclient"
+)
+
+func TestSelect_qresync(t *testing.T) {
+	conn, server := newMemClientServerPair(t)
+	defer server.Close()
+
+	type vanished struct {
+		uids    imap.UIDSet
+		earlier bool
+	}
+	vanishedCh := make(chan vanished, 16)
+	fetchCh := make(chan *imapclient.FetchMessageBuffer, 16)
+	client := imapclient.New(conn, &imapclient.Options{
+		UnilateralDataHandler: &imapclient.UnilateralDataHandler{
+			Vanished: func(uids imap.UIDSet, earlier bool) {
+				vanishedCh <- vanished{uids, earlier}
+			},
+			Fetch: func(msg *imapclient.FetchMessageData) {
+				buf, err := msg.Collect()
+				if err == nil {
+					fetchCh <- buf
+				}
+			},
+		},
+	})
+	defer client.Close()
+
+	if err := client.Login(testUsername, testPassword).Wait(); err != nil {
+		t.Fatalf("Login().Wait() = %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		appendCmd := client.Append("INBOX", int64(len(simpleRawMessage)), nil)
+		appendCmd.Write([]byte(simpleRawMessage))
+		appendCmd.Close()
+		if _, err := appendCmd.Wait(); err != nil {
+			t.Fatalf("AppendCommand.Wait() = %v", err)
+		}
+	}
+	if data, err := client.Enable(imap.CapQResync).Wait(); err != nil {
+		t.Fatalf("Enable(QRESYNC).Wait() = %v", err)
+	} else if _, ok := data.Caps[imap.CapCondStore]; !ok {
+		t.Errorf("Enable(QRESYNC) didn't enable CONDSTORE")
+	}
+
+	selectData, err := client.Select("INBOX", nil).Wait()
+	if err != nil {
+		t.Fatalf("Select().Wait() = %v", err)
+	}
+
+	storeFlags := imap.StoreFlags{
+		Op:     imap.StoreFlagsAdd,
+		Flags:  []imap.Flag{imap.FlagDeleted},
+		Silent: true,
+	}
+	if err := client.Store(imap.UIDSetNum(1), &storeFlags, nil).Close(); err != nil {
+		t.Fatalf("Store().Close() = %v", err)
+	}
+	if err := client.Expunge().Close(); err != nil {
+		t.Fatalf("Expunge().Close() = %v", err)
+	}
+	select {
+	case v := <-vanishedCh:
+		if v.earlier || v.uids.String() != "1" {
+			t.Errorf("got VANISHED %v (earlier = %v), want 1", v.uids, v.earlier)
+		}
+	default:
+		t.Fatalf("no VANISHED response after EXPUNGE")
+	}
+	storeFlags = imap.StoreFlags{
+		Op:     imap.StoreFlagsAdd,
+		Flags:  []imap.Flag{imap.FlagFlagged},
+		Silent: true,
+	}
+	if err := client.Store(imap.UIDSetNum(2), &storeFlags, nil).Close(); err != nil {
+		t.Fatalf("Store().Close() = %v", err)
+	}
+	if err := client.Unselect().Wait(); err != nil {
+		t.Fatalf("Unselect().Wait() = %v", err)
+	}
+
+	_, err = client.Select("INBOX", &imap.SelectOptions{
+		QResync: &imap.SelectQResync{
+			UIDValidity: selectData.UIDValidity,
+			ModSeq:      selectData.HighestModSeq,
+		},
+	}).Wait()
+	if err != nil {
+		t.Fatalf("Select(QRESYNC).Wait() = %v", err)
+	}
+	select {
+	case v := <-vanishedCh:
+		if !v.earlier || v.uids.String() != "1" {
+			t.Errorf("got VANISHED %v (earlier = %v), want (EARLIER) 1", v.uids, v.earlier)
+		}
+	default:
+		t.Fatalf("no VANISHED (EARLIER) response after SELECT")
+	}
+	select {
+	case msg := <-fetchCh:
+		if msg.UID != 2 || msg.ModSeq == 0 {
+			t.Errorf("got FETCH UID %v MODSEQ %v, want UID 2 with MODSEQ", msg.UID, msg.ModSeq)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no FETCH response for changed message after SELECT")
+	}
+}
@@ -5,32 +5,41 @@ import (
 	"github.com/emersion/go-imap/v2/internal/imapwire"
 )
 
-type SortKey string
+type (
+	SortKey       = imap.SortKey
+	SortCriterion = imap.SortCriterion
+)
 
 const (
-	SortKeyArrival SortKey = "ARRIVAL"
-	SortKeyCc      SortKey = "CC"
-	SortKeyDate    SortKey = "DATE"
-	SortKeyFrom    SortKey = "FROM"
-	SortKeySize    SortKey = "SIZE"
-	SortKeySubject SortKey = "SUBJECT"
-	SortKeyTo      SortKey = "TO"
+	SortKeyArrival = imap.SortKeyArrival
+	SortKeyCc      = imap.SortKeyCc
+	SortKeyDate    = imap.SortKeyDate
+	SortKeyFrom    = imap.SortKeyFrom
+	SortKeySize    = imap.SortKeySize
+	SortKeySubject = imap.SortKeySubject
+	SortKeyTo      = imap.SortKeyTo
 )
 
-type SortCriterion struct {
-	Key     SortKey
-	Reverse bool
-}
-
 // SortOptions contains options for the SORT command.
 type SortOptions struct {
 	SearchCriteria *imap.SearchCriteria
 	SortCriteria   []SortCriterion
+
+	// Requires CONTEXT=SORT, updates are delivered via
+	// UnilateralDataHandler.SearchUpdate
+	ReturnUpdate bool
 }
 
 func (c *Client) sort(numKind imapwire.NumKind, options *SortOptions) *SortCommand {
 	cmd := &SortCommand{}
+	if err := c.checkUIDOnly(numKind); err != nil {
+		c.failCommand(cmd, err)
+		return cmd
+	}
 	enc := c.beginCommand(uidCmdName("SORT", numKind), cmd)
+	if options.ReturnUpdate {
+		enc.SP().Atom("RETURN").SP().Special('(').Atom("ALL").SP().Atom("UPDATE").Special(')')
+	}
 	enc.SP().List(len(options.SortCriteria), func(i int) {
 		criterion := options.SortCriteria[i]
 		if criterion.Reverse {
@@ -39,7 +48,7 @@ func (c *Client) sort(numKind imapwire.NumKind, options *SortOptions) *SortComma
 		enc.Atom(string(criterion.Key))
 	})
 	enc.SP().Atom("UTF-8").SP()
-	writeSearchKey(enc.Encoder, options.SearchCriteria)
+	writeSearchKey(enc.Encoder, c.searchCriteriaWithin(options.SearchCriteria))
 	enc.end()
 	return cmd
 }
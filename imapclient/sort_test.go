@@ -0,0 +1,48 @@
+package imapclient_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/imapclient"
+)
+
+func TestSort(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateAuthenticated)
+	defer client.Close()
+	defer server.Close()
+
+	for _, subject := range []string{"Re: Bravo", "alpha"} {
+		raw := "Subject: " + subject + "\r\n\r\nHello!"
+		appendCmd := client.Append("INBOX", int64(len(raw)), nil)
+		appendCmd.Write([]byte(raw))
+		appendCmd.Close()
+		if _, err := appendCmd.Wait(); err != nil {
+			t.Fatalf("AppendCommand.Wait() = %v", err)
+		}
+	}
+	if _, err := client.Select("INBOX", nil).Wait(); err != nil {
+		t.Fatalf("Select().Wait() = %v", err)
+	}
+
+	nums, err := client.Sort(&imapclient.SortOptions{
+		SearchCriteria: &imap.SearchCriteria{},
+		SortCriteria:   []imapclient.SortCriterion{{Key: imapclient.SortKeySubject}},
+	}).Wait()
+	if err != nil {
+		t.Fatalf("Sort().Wait() = %v", err)
+	} else if want := []uint32{1, 3, 2}; !reflect.DeepEqual(nums, want) {
+		t.Errorf("Sort().Wait() = %v, want %v", nums, want)
+	}
+
+	nums, err = client.UIDSort(&imapclient.SortOptions{
+		SearchCriteria: &imap.SearchCriteria{},
+		SortCriteria:   []imapclient.SortCriterion{{Key: imapclient.SortKeySubject, Reverse: true}},
+	}).Wait()
+	if err != nil {
+		t.Fatalf("UIDSort().Wait() = %v", err)
+	} else if want := []uint32{2, 3, 1}; !reflect.DeepEqual(nums, want) {
+		t.Errorf("UIDSort().Wait() = %v, want %v", nums, want)
+	}
+}
@@ -0,0 +1,52 @@
+package imapclient_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/emersion/go-imap/v2"
+)
+
+func TestSpecialUse(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateAuthenticated)
+	defer client.Close()
+	defer server.Close()
+
+	if !client.Caps().Has(imap.CapCreateSpecialUse) {
+		t.Skip("CREATE-SPECIAL-USE not supported")
+	}
+
+	options := imap.CreateOptions{SpecialUse: []imap.MailboxAttr{imap.MailboxAttrSent}}
+	if err := client.Create("Sent", &options).Wait(); err != nil {
+		t.Fatalf("Create().Wait() = %v", err)
+	}
+
+	err := client.Create("Sent2", &options).Wait()
+	var imapErr *imap.Error
+	if !errors.As(err, &imapErr) || imapErr.Code != imap.ResponseCodeUseAttr {
+		t.Errorf("Create().Wait() = %v, want USEATTR error", err)
+	}
+
+	mailboxes, err := client.List("", "*", &imap.ListOptions{
+		SelectSpecialUse: true,
+		ReturnSpecialUse: true,
+	}).Collect()
+	if err != nil {
+		t.Fatalf("List().Collect() = %v", err)
+	} else if len(mailboxes) != 1 {
+		t.Fatalf("len(mailboxes) = %v, want 1", len(mailboxes))
+	}
+	data := mailboxes[0]
+	if data.Mailbox != "Sent" {
+		t.Errorf("Mailbox = %q, want %q", data.Mailbox, "Sent")
+	}
+	hasAttr := false
+	for _, attr := range data.Attrs {
+		if attr == imap.MailboxAttrSent {
+			hasAttr = true
+		}
+	}
+	if !hasAttr {
+		t.Errorf("Attrs = %v, want %v", data.Attrs, imap.MailboxAttrSent)
+	}
+}
@@ -19,6 +19,7 @@ func statusItems(options *imap.StatusOptions) []string {
 		"APPENDLIMIT":     options.AppendLimit,
 		"DELETED-STORAGE": options.DeletedStorage,
 		"HIGHESTMODSEQ":   options.HighestModSeq,
+		"MAILBOXID":       options.MailboxID,
 	}
 
 	var l []string
@@ -72,6 +73,10 @@ func (c *Client) handleStatus() error {
 		cmd.pendingData.Status = data
 		cmd.mailboxes <- cmd.pendingData
 		cmd.pendingData = nil
+	default:
+		if handler := c.options.unilateralDataHandler().Status; handler != nil {
+			handler(data)
+		}
 	}
 
 	return nil
@@ -149,6 +154,8 @@ func readStatusAttVal(dec *imapwire.Decoder, data *imap.StatusData) error {
 		data.DeletedStorage = &storage
 	case "HIGHESTMODSEQ":
 		ok = dec.ExpectModSeq(&data.HighestModSeq)
+	case "MAILBOXID":
+		ok = expectObjectID(dec, &data.MailboxID)
 	default:
 		if !dec.DiscardValue() {
 			return dec.Err()
@@ -17,7 +17,12 @@ func (c *Client) Store(numSet imap.NumSet, store *imap.StoreFlags, options *imap
 		numSet: numSet,
 		msgs:   make(chan *FetchMessageData, 128),
 	}
-	enc := c.beginCommand(uidCmdName("STORE", imapwire.NumSetKind(numSet)), cmd)
+	numKind := imapwire.NumSetKind(numSet)
+	if err := c.checkUIDOnly(numKind); err != nil {
+		c.failCommand(cmd, err)
+		return cmd
+	}
+	enc := c.beginCommand(uidCmdName("STORE", numKind), cmd)
 	enc.SP().NumSet(numSet).SP()
 	if options != nil && options.UnchangedSince != 0 {
 		enc.Special('(').Atom("UNCHANGEDSINCE").SP().ModSeq(options.UnchangedSince).Special(')').SP()
@@ -38,3 +38,53 @@ func TestStore(t *testing.T) {
 		t.Errorf("msg.Flags is missing deleted flag: %v", msg.Flags)
 	}
 }
+
+func TestStore_unchangedSince(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateAuthenticated)
+	defer client.Close()
+	defer server.Close()
+
+	if !client.Caps().Has(imap.CapCondStore) {
+		t.Skip("server doesn't support CONDSTORE")
+	}
+
+	selectData, err := client.Select("INBOX", &imap.SelectOptions{CondStore: true}).Wait()
+	if err != nil {
+		t.Fatalf("Select().Wait() = %v", err)
+	} else if selectData.HighestModSeq == 0 {
+		t.Fatalf("selectData.HighestModSeq = 0")
+	}
+
+	seqSet := imap.SeqSetNum(1)
+	msgs, err := client.Fetch(seqSet, &imap.FetchOptions{ModSeq: true}).Collect()
+	if err != nil {
+		t.Fatalf("Fetch().Collect() = %v", err)
+	} else if len(msgs) != 1 {
+		t.Fatalf("len(msgs) = %v, want %v", len(msgs), 1)
+	}
+	modSeq := msgs[0].ModSeq
+	if modSeq == 0 {
+		t.Fatalf("msg.ModSeq = 0")
+	}
+
+	storeFlags := imap.StoreFlags{
+		Op:    imap.StoreFlagsAdd,
+		Flags: []imap.Flag{imap.FlagFlagged},
+	}
+	msgs, err = client.Store(seqSet, &storeFlags, &imap.StoreOptions{UnchangedSince: modSeq - 1}).Collect()
+	if err != nil {
+		t.Fatalf("Store().Collect() = %v", err)
+	} else if len(msgs) != 0 {
+		t.Errorf("len(msgs) = %v, want %v", len(msgs), 0)
+	}
+
+	msgs, err = client.Store(seqSet, &storeFlags, &imap.StoreOptions{UnchangedSince: modSeq}).Collect()
+	if err != nil {
+		t.Fatalf("Store().Collect() = %v", err)
+	} else if len(msgs) != 1 {
+		t.Fatalf("len(msgs) = %v, want %v", len(msgs), 1)
+	}
+	if msgs[0].ModSeq <= modSeq {
+		t.Errorf("msg.ModSeq = %v, want > %v", msgs[0].ModSeq, modSeq)
+	}
+}
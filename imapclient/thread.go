@@ -15,9 +15,13 @@ type ThreadOptions struct {
 
 func (c *Client) thread(numKind imapwire.NumKind, options *ThreadOptions) *ThreadCommand {
 	cmd := &ThreadCommand{}
+	if err := c.checkUIDOnly(numKind); err != nil {
+		c.failCommand(cmd, err)
+		return cmd
+	}
 	enc := c.beginCommand(uidCmdName("THREAD", numKind), cmd)
 	enc.SP().Atom(string(options.Algorithm)).SP().Atom("UTF-8").SP()
-	writeSearchKey(enc.Encoder, options.SearchCriteria)
+	writeSearchKey(enc.Encoder, c.searchCriteriaWithin(options.SearchCriteria))
 	enc.end()
 	return cmd
 }
@@ -38,7 +42,10 @@ func (c *Client) UIDThread(options *ThreadOptions) *ThreadCommand {
 
 func (c *Client) handleThread() error {
 	cmd := findPendingCmdByType[*ThreadCommand](c)
-	for c.dec.SP() {
+	if !c.dec.SP() {
+		return nil
+	}
+	for c.dec.Special('(') {
 		data, err := readThreadList(c.dec)
 		if err != nil {
 			return fmt.Errorf("in thread-list: %v", err)
@@ -61,25 +68,31 @@ func (cmd *ThreadCommand) Wait() ([]ThreadData, error) {
 	return cmd.data, err
 }
 
-type ThreadData struct {
-	Chain      []uint32
-	SubThreads []ThreadData
-}
+type ThreadData = imap.ThreadData
 
+// readThreadList reads a thread-list. The opening parenthesis must have
+// already been consumed.
+//
+// Nested thread lists aren't separated by spaces, so the generic list decoder
+// can't be used here.
 func readThreadList(dec *imapwire.Decoder) (*ThreadData, error) {
 	var data ThreadData
-	err := dec.ExpectList(func() error {
-		var num uint32
-		if len(data.SubThreads) == 0 && dec.Number(&num) {
-			data.Chain = append(data.Chain, num)
-		} else {
-			sub, err := readThreadList(dec)
-			if err != nil {
-				return err
-			}
-			data.SubThreads = append(data.SubThreads, *sub)
+	var num uint32
+	for dec.Number(&num) {
+		data.Chain = append(data.Chain, num)
+		if !dec.SP() {
+			break
 		}
-		return nil
-	})
-	return &data, err
+	}
+	for !dec.Special(')') {
+		if !dec.ExpectSpecial('(') {
+			return nil, dec.Err()
+		}
+		sub, err := readThreadList(dec)
+		if err != nil {
+			return nil, err
+		}
+		data.SubThreads = append(data.SubThreads, *sub)
+	}
+	return &data, nil
 }
@@ -0,0 +1,83 @@
+package imapclient_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/imapclient"
+)
+
+func TestThread(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateAuthenticated)
+	defer client.Close()
+	defer server.Close()
+
+	// The first message is simpleRawMessage
+	rawMessages := []string{
+		"Message-Id: <2@example.com>\r\nIn-Reply-To: <191101702316132@example.com>\r\nSubject: Re: Test\r\n\r\nHello!",
+		"Message-Id: <3@example.com>\r\nSubject: Other\r\n\r\nHello!",
+		"Message-Id: <4@example.com>\r\nReferences: <191101702316132@example.com> <2@example.com>\r\nSubject: Re: Test\r\n\r\nHello!",
+		"Message-Id: <5@example.com>\r\nIn-Reply-To: <191101702316132@example.com>\r\nSubject: Re: Test\r\n\r\nHello!",
+	}
+	for _, raw := range rawMessages {
+		appendCmd := client.Append("INBOX", int64(len(raw)), nil)
+		appendCmd.Write([]byte(raw))
+		appendCmd.Close()
+		if _, err := appendCmd.Wait(); err != nil {
+			t.Fatalf("AppendCommand.Wait() = %v", err)
+		}
+	}
+	if _, err := client.Select("INBOX", nil).Wait(); err != nil {
+		t.Fatalf("Select().Wait() = %v", err)
+	}
+
+	algorithms := client.Caps().ThreadAlgorithms()
+	wantAlgorithms := []imap.ThreadAlgorithm{imap.ThreadOrderedSubject, imap.ThreadReferences}
+	if len(algorithms) != len(wantAlgorithms) {
+		t.Errorf("Caps().ThreadAlgorithms() = %v, want %v", algorithms, wantAlgorithms)
+	}
+
+	threads, err := client.Thread(&imapclient.ThreadOptions{
+		Algorithm:      imap.ThreadReferences,
+		SearchCriteria: &imap.SearchCriteria{},
+	}).Wait()
+	if err != nil {
+		t.Fatalf("Thread(REFERENCES).Wait() = %v", err)
+	}
+	want := []imapclient.ThreadData{
+		{
+			Chain: []uint32{1},
+			SubThreads: []imapclient.ThreadData{
+				{Chain: []uint32{2, 4}},
+				{Chain: []uint32{5}},
+			},
+		},
+		{Chain: []uint32{3}},
+	}
+	if !reflect.DeepEqual(threads, want) {
+		t.Errorf("Thread(REFERENCES).Wait() = %v, want %v", threads, want)
+	}
+
+	threads, err = client.UIDThread(&imapclient.ThreadOptions{
+		Algorithm:      imap.ThreadOrderedSubject,
+		SearchCriteria: &imap.SearchCriteria{},
+	}).Wait()
+	if err != nil {
+		t.Fatalf("UIDThread(ORDEREDSUBJECT).Wait() = %v", err)
+	}
+	want = []imapclient.ThreadData{
+		{Chain: []uint32{1}},
+		{
+			Chain: []uint32{2},
+			SubThreads: []imapclient.ThreadData{
+				{Chain: []uint32{4}},
+				{Chain: []uint32{5}},
+			},
+		},
+		{Chain: []uint32{3}},
+	}
+	if !reflect.DeepEqual(threads, want) {
+		t.Errorf("UIDThread(ORDEREDSUBJECT).Wait() = %v, want %v", threads, want)
+	}
+}
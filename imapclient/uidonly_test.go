@@ -0,0 +1,76 @@
+package imapclient_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/imapclient"
+)
+
+func TestUIDOnly(t *testing.T) {
+	conn, server := newMemClientServerPair(t)
+	defer server.Close()
+
+	vanished := make(chan imap.UIDSet, 16)
+	client := imapclient.New(conn, &imapclient.Options{
+		UnilateralDataHandler: &imapclient.UnilateralDataHandler{
+			Vanished: func(uids imap.UIDSet, earlier bool) {
+				vanished <- uids
+			},
+		},
+	})
+	defer client.Close()
+
+	if err := client.Login(testUsername, testPassword).Wait(); err != nil {
+		t.Fatalf("Login().Wait() = %v", err)
+	}
+	appendCmd := client.Append("INBOX", int64(len(simpleRawMessage)), nil)
+	appendCmd.Write([]byte(simpleRawMessage))
+	appendCmd.Close()
+	if _, err := appendCmd.Wait(); err != nil {
+		t.Fatalf("AppendCommand.Wait() = %v", err)
+	}
+
+	if data, err := client.Enable(imap.CapUIDOnly).Wait(); err != nil {
+		t.Fatalf("Enable().Wait() = %v", err)
+	} else if !data.Caps.Has(imap.CapUIDOnly) {
+		t.Fatalf("Enable() didn't enable UIDONLY")
+	}
+	if _, err := client.Select("INBOX", nil).Wait(); err != nil {
+		t.Fatalf("Select().Wait() = %v", err)
+	}
+
+	if _, err := client.Fetch(imap.SeqSetNum(1), &imap.FetchOptions{Flags: true}).Collect(); err == nil {
+		t.Errorf("Fetch() with sequence numbers succeeded, want error")
+	}
+
+	msgs, err := client.Fetch(imap.UIDSetNum(1), &imap.FetchOptions{Flags: true}).Collect()
+	if err != nil {
+		t.Fatalf("UIDFetch().Collect() = %v", err)
+	} else if len(msgs) != 1 || msgs[0].UID != 1 || msgs[0].SeqNum != 0 {
+		t.Fatalf("UIDFetch().Collect() = %v, want a single message with UID 1", msgs)
+	}
+
+	_, err = client.UIDSearch(&imap.SearchCriteria{SeqNum: []imap.SeqSet{imap.SeqSetNum(1)}}, nil).Wait()
+	var imapErr *imap.Error
+	if !errors.As(err, &imapErr) || imapErr.Code != imap.ResponseCodeUIDRequired {
+		t.Errorf("UIDSearch() = %v, want UIDREQUIRED error", err)
+	}
+
+	storeFlags := imap.StoreFlags{Op: imap.StoreFlagsAdd, Flags: []imap.Flag{imap.FlagDeleted}, Silent: true}
+	if err := client.Store(imap.UIDSetNum(1), &storeFlags, nil).Close(); err != nil {
+		t.Fatalf("Store().Close() = %v", err)
+	}
+	if err := client.Expunge().Close(); err != nil {
+		t.Fatalf("Expunge().Close() = %v", err)
+	}
+	select {
+	case uids := <-vanished:
+		if !uids.Contains(1) {
+			t.Errorf("got VANISHED %v, want 1", uids)
+		}
+	default:
+		t.Errorf("no VANISHED response received")
+	}
+}
@@ -0,0 +1,126 @@
+package imapclient
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/emersion/go-imap/v2"
+)
+
+// GenURLAuth sends a GENURLAUTH command.
+//
+// Each URL rump must end with ";URLAUTH=<access>". The authorized URLs are
+// returned in the same order.
+//
+// This command requires support for the URLAUTH extension.
+func (c *Client) GenURLAuth(rumps []string, mechanism imap.URLAuthMechanism) *GenURLAuthCommand {
+	cmd := &GenURLAuthCommand{}
+	enc := c.beginCommand("GENURLAUTH", cmd)
+	for _, rump := range rumps {
+		enc.SP().String(rump).SP().Atom(string(mechanism))
+	}
+	enc.end()
+	return cmd
+}
+
+// ResetKey sends a RESETKEY command.
+//
+// If mailbox is empty, the access keys of all mailboxes are reset.
+//
+// This command requires support for the URLAUTH extension.
+func (c *Client) ResetKey(mailbox string, mechanisms ...imap.URLAuthMechanism) *Command {
+	cmd := &Command{}
+	enc := c.beginCommand("RESETKEY", cmd)
+	if mailbox != "" {
+		enc.SP().Mailbox(mailbox)
+		for _, mechanism := range mechanisms {
+			enc.SP().Atom(string(mechanism))
+		}
+	}
+	enc.end()
+	return cmd
+}
+
+// URLFetch sends a URLFETCH command.
+//
+// This command requires support for the URLAUTH extension.
+func (c *Client) URLFetch(urls ...string) *URLFetchCommand {
+	cmd := &URLFetchCommand{}
+	enc := c.beginCommand("URLFETCH", cmd)
+	for _, url := range urls {
+		enc.SP().String(url)
+	}
+	enc.end()
+	return cmd
+}
+
+func (c *Client) handleGenURLAuth() error {
+	var urls []string
+	for c.dec.SP() {
+		var url string
+		if !c.dec.ExpectAString(&url) {
+			return fmt.Errorf("in genurlauth-data: %v", c.dec.Err())
+		}
+		urls = append(urls, url)
+	}
+	if cmd := findPendingCmdByType[*GenURLAuthCommand](c); cmd != nil {
+		cmd.urls = append(cmd.urls, urls...)
+	}
+	return nil
+}
+
+func (c *Client) handleURLFetch() error {
+	data := make(map[string][]byte)
+	for c.dec.SP() {
+		var url string
+		if !c.dec.ExpectAString(&url) || !c.dec.ExpectSP() {
+			return fmt.Errorf("in urlfetch-data: %v", c.dec.Err())
+		}
+		lit, _, ok := c.dec.ExpectNStringReader()
+		if !ok {
+			return fmt.Errorf("in urlfetch-data: %v", c.dec.Err())
+		}
+		var b []byte
+		if lit != nil {
+			var err error
+			if b, err = io.ReadAll(lit); err != nil {
+				return err
+			}
+		}
+		data[url] = b
+	}
+
+	if cmd := findPendingCmdByType[*URLFetchCommand](c); cmd != nil {
+		if cmd.data == nil {
+			cmd.data = make(map[string][]byte)
+		}
+		for url, b := range data {
+			cmd.data[url] = b
+		}
+	}
+	return nil
+}
+
+// GenURLAuthCommand is a GENURLAUTH command.
+type GenURLAuthCommand struct {
+	cmd
+	urls []string
+}
+
+// Wait blocks until the command has completed, and returns the authorized
+// URLs.
+func (cmd *GenURLAuthCommand) Wait() ([]string, error) {
+	return cmd.urls, cmd.cmd.Wait()
+}
+
+// URLFetchCommand is a URLFETCH command.
+type URLFetchCommand struct {
+	cmd
+	data map[string][]byte
+}
+
+// Wait blocks until the command has completed, and returns the contents of
+// each URL. The contents are nil if the server couldn't fetch a URL.
+func (cmd *URLFetchCommand) Wait() (map[string][]byte, error) {
+	return cmd.data, cmd.cmd.Wait()
+}
@@ -0,0 +1,81 @@
+package imapclient_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/emersion/go-imap/v2"
+)
+
+func TestURLAuth(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateSelected)
+	defer client.Close()
+	defer server.Close()
+
+	if !client.Caps().Has(imap.CapURLAuth) {
+		t.Skip("URLAUTH not supported")
+	}
+
+	rump := "imap://" + testUsername + "@localhost/INBOX/;UID=1/;SECTION=TEXT;URLAUTH=user+" + testUsername
+	urls, err := client.GenURLAuth([]string{rump}, imap.URLAuthMechanismInternal).Wait()
+	if err != nil {
+		t.Fatalf("GenURLAuth().Wait() = %v", err)
+	} else if len(urls) != 1 || !strings.HasPrefix(urls[0], rump+":INTERNAL:") {
+		t.Fatalf("GenURLAuth().Wait() = %v, want URL with rump %q", urls, rump)
+	}
+	url := urls[0]
+
+	data, err := client.URLFetch(url, rump).Wait()
+	if err != nil {
+		t.Fatalf("URLFetch().Wait() = %v", err)
+	}
+	rawMessage := strings.ReplaceAll(simpleRawMessage, "\n", "\r\n")
+	want := rawMessage[strings.Index(rawMessage, "\r\n\r\n")+4:]
+	if string(data[url]) != want {
+		t.Errorf("URLFetch().Wait()[%q] = %q, want %q", url, data[url], want)
+	}
+	if b, ok := data[rump]; !ok || b != nil {
+		t.Errorf("URLFetch().Wait()[%q] = %q, want nil", rump, b)
+	}
+
+	// The token must not be accepted for another message or section
+	token := url[len(rump):]
+	tampered := []string{
+		url + ";UID=2",
+		rump + ";UID=2" + token,
+		strings.Replace(rump, ";SECTION=TEXT", ";SECTION=HEADER", 1) + token,
+		strings.Replace(rump, ";URLAUTH=", ";EXPIRE=2099-01-01T00:00:00Z;URLAUTH=", 1) + token,
+	}
+	data, err = client.URLFetch(tampered...).Wait()
+	if err != nil {
+		t.Fatalf("URLFetch().Wait() = %v", err)
+	}
+	for _, u := range tampered {
+		if b, ok := data[u]; !ok || b != nil {
+			t.Errorf("URLFetch().Wait()[%q] = %q, want nil", u, b)
+		}
+	}
+
+	// Only submit servers can fetch "submit+" URLs
+	submitRump := strings.Replace(rump, ";URLAUTH=user+", ";URLAUTH=submit+", 1)
+	urls, err = client.GenURLAuth([]string{submitRump}, imap.URLAuthMechanismInternal).Wait()
+	if err != nil {
+		t.Fatalf("GenURLAuth().Wait() = %v", err)
+	}
+	data, err = client.URLFetch(urls[0]).Wait()
+	if err != nil {
+		t.Fatalf("URLFetch().Wait() = %v", err)
+	} else if b := data[urls[0]]; b != nil {
+		t.Errorf("URLFetch().Wait()[%q] = %q, want nil", urls[0], b)
+	}
+
+	if err := client.ResetKey("INBOX").Wait(); err != nil {
+		t.Fatalf("ResetKey().Wait() = %v", err)
+	}
+	data, err = client.URLFetch(url).Wait()
+	if err != nil {
+		t.Fatalf("URLFetch().Wait() = %v", err)
+	} else if b := data[url]; b != nil {
+		t.Errorf("URLFetch().Wait()[%q] = %q after RESETKEY, want nil", url, b)
+	}
+}
@@ -0,0 +1,47 @@
+package imapclient_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/emersion/go-imap/v2"
+)
+
+func TestSearch_within(t *testing.T) {
+	client, server := newClientServerPair(t, imap.ConnStateSelected)
+	defer client.Close()
+	defer server.Close()
+
+	if !client.Caps().Has(imap.CapWithin) {
+		t.Skip("WITHIN not supported")
+	}
+
+	appendCmd := client.Append("INBOX", int64(len(simpleRawMessage)), &imap.AppendOptions{
+		Time: time.Now().Add(-10 * 24 * time.Hour),
+	})
+	appendCmd.Write([]byte(simpleRawMessage))
+	appendCmd.Close()
+	if _, err := appendCmd.Wait(); err != nil {
+		t.Fatalf("AppendCommand.Wait() = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		criteria imap.SearchCriteria
+		want     []imap.UID
+	}{
+		{"OLDER", imap.SearchCriteria{Older: 5 * 24 * time.Hour}, []imap.UID{2}},
+		{"YOUNGER", imap.SearchCriteria{Younger: 5 * 24 * time.Hour}, []imap.UID{1}},
+	}
+	for _, tc := range tests {
+		data, err := client.UIDSearch(&tc.criteria, nil).Wait()
+		if err != nil {
+			t.Errorf("UIDSearch(%v).Wait() = %v", tc.name, err)
+			continue
+		}
+		uids := data.AllUIDs()
+		if len(uids) != len(tc.want) || (len(uids) > 0 && uids[0] != tc.want[0]) {
+			t.Errorf("UIDSearch(%v): AllUIDs() = %v, want %v", tc.name, uids, tc.want)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package imapserver
+
+import (
+	"sort"
+
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/internal/imapwire"
+)
+
+func (c *Conn) handleMyRights(dec *imapwire.Decoder) error {
+	var mailbox string
+	if !dec.ExpectSP() || !dec.ExpectMailbox(&mailbox) || !dec.ExpectCRLF() {
+		return dec.Err()
+	}
+
+	session, err := c.aclSession()
+	if err != nil {
+		return err
+	}
+
+	data, err := session.MyRights(mailbox)
+	if err != nil {
+		return err
+	}
+
+	enc := newResponseEncoder(c)
+	defer enc.end()
+	enc.Atom("*").SP().Atom("MYRIGHTS").SP().Mailbox(data.Mailbox).SP().String(data.Rights.String())
+	return enc.CRLF()
+}
+
+func (c *Conn) handleSetACL(dec *imapwire.Decoder) error {
+	var mailbox, ri, rights string
+	if !dec.ExpectSP() || !dec.ExpectMailbox(&mailbox) || !dec.ExpectSP() || !dec.ExpectAString(&ri) || !dec.ExpectSP() || !dec.ExpectAString(&rights) || !dec.ExpectCRLF() {
+		return dec.Err()
+	}
+
+	rm := imap.RightModificationReplace
+	if len(rights) > 0 && (rights[0] == '+' || rights[0] == '-') {
+		rm = imap.RightModification(rights[0])
+		rights = rights[1:]
+	}
+	rs, err := parseRightSet(rights)
+	if err != nil {
+		return err
+	}
+
+	session, err := c.aclSession()
+	if err != nil {
+		return err
+	}
+
+	return session.SetACL(mailbox, imap.RightsIdentifier(ri), rm, rs)
+}
+
+func (c *Conn) handleDeleteACL(dec *imapwire.Decoder) error {
+	var mailbox, ri string
+	if !dec.ExpectSP() || !dec.ExpectMailbox(&mailbox) || !dec.ExpectSP() || !dec.ExpectAString(&ri) || !dec.ExpectCRLF() {
+		return dec.Err()
+	}
+
+	session, err := c.aclSession()
+	if err != nil {
+		return err
+	}
+
+	return session.DeleteACL(mailbox, imap.RightsIdentifier(ri))
+}
+
+func (c *Conn) handleGetACL(dec *imapwire.Decoder) error {
+	var mailbox string
+	if !dec.ExpectSP() || !dec.ExpectMailbox(&mailbox) || !dec.ExpectCRLF() {
+		return dec.Err()
+	}
+
+	session, err := c.aclSession()
+	if err != nil {
+		return err
+	}
+
+	data, err := session.GetACL(mailbox)
+	if err != nil {
+		return err
+	}
+
+	var identifiers []string
+	for ri := range data.Rights {
+		identifiers = append(identifiers, string(ri))
+	}
+	sort.Strings(identifiers)
+
+	enc := newResponseEncoder(c)
+	defer enc.end()
+	enc.Atom("*").SP().Atom("ACL").SP().Mailbox(data.Mailbox)
+	for _, ri := range identifiers {
+		rights := data.Rights[imap.RightsIdentifier(ri)]
+		enc.SP().String(ri).SP().String(rights.String())
+	}
+	return enc.CRLF()
+}
+
+func (c *Conn) handleListRights(dec *imapwire.Decoder) error {
+	var mailbox, ri string
+	if !dec.ExpectSP() || !dec.ExpectMailbox(&mailbox) || !dec.ExpectSP() || !dec.ExpectAString(&ri) || !dec.ExpectCRLF() {
+		return dec.Err()
+	}
+
+	session, err := c.aclSession()
+	if err != nil {
+		return err
+	}
+
+	data, err := session.ListRights(mailbox, imap.RightsIdentifier(ri))
+	if err != nil {
+		return err
+	}
+
+	enc := newResponseEncoder(c)
+	defer enc.end()
+	enc.Atom("*").SP().Atom("LISTRIGHTS").SP().Mailbox(data.Mailbox).SP().String(string(data.Identifier))
+	enc.SP().String(data.RequiredRights.String())
+	for _, rights := range data.OptionalRights {
+		enc.SP().String(rights.String())
+	}
+	return enc.CRLF()
+}
+
+func (c *Conn) aclSession() (SessionACL, error) {
+	if err := c.checkState(imap.ConnStateAuthenticated); err != nil {
+		return nil, err
+	}
+	session, ok := c.session.(SessionACL)
+	if !ok || !c.server.options.caps().Has(imap.CapACL) {
+		return nil, newClientBugError("ACL is not supported")
+	}
+	return session, nil
+}
+
+func parseRightSet(s string) (imap.RightSet, error) {
+	rs := make(imap.RightSet, len(s))
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if !(ch >= 'a' && ch <= 'z') && !(ch >= '0' && ch <= '9') {
+			return nil, newClientBugError("Invalid right")
+		}
+		rs[i] = imap.Right(ch)
+	}
+	return rs, nil
+}
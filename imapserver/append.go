@@ -1,6 +1,8 @@
 package imapserver
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
@@ -16,14 +18,171 @@ import (
 const appendLimit = 100 * 1024 * 1024 // 100MiB
 
 func (c *Conn) handleAppend(tag string, dec *imapwire.Decoder) error {
-	var (
-		mailbox string
-		options imap.AppendOptions
-	)
+	err := c.appendMessages(tag, dec)
+	var badURL *badURLError
+	if errors.As(err, &badURL) {
+		return c.writeBadURL(tag, badURL)
+	}
+	return err
+}
+
+func (c *Conn) appendMessages(tag string, dec *imapwire.Decoder) error {
+	var mailbox string
 	if !dec.ExpectSP() || !dec.ExpectMailbox(&mailbox) || !dec.ExpectSP() {
 		return dec.Err()
 	}
 
+	r := &MultiAppendReader{conn: c, dec: dec}
+	msg, err := r.readMessage()
+	if err != nil {
+		return err
+	}
+	r.cur = msg
+
+	c.setReadTimeout(literalReadTimeout)
+	defer c.setReadTimeout(cmdReadTimeout)
+
+	if err := c.checkState(imap.ConnStateAuthenticated); err != nil {
+		r.discard()
+		dec.CRLF()
+		return err
+	}
+
+	if session, ok := c.session.(SessionMultiAppend); ok && c.server.options.caps().Has(imap.CapMultiAppend) {
+		r.pending = true
+		data, appendErr := session.MultiAppend(mailbox, r)
+		if err := r.discard(); err != nil {
+			return err
+		}
+		if !dec.ExpectCRLF() {
+			return dec.Err()
+		}
+		if appendErr != nil {
+			return appendErr
+		}
+		if err := c.poll("APPEND"); err != nil {
+			return err
+		}
+		return c.writeMultiAppendOK(tag, data)
+	}
+
+	data, appendErr := c.session.Append(mailbox, msg.Literal, msg.Options)
+	if _, err := io.Copy(io.Discard, msg.Literal); err != nil {
+		return err
+	}
+	if r.dataExt && !dec.ExpectSpecial(')') {
+		return dec.Err()
+	}
+	if !dec.ExpectCRLF() {
+		return dec.Err()
+	}
+	if appendErr != nil {
+		return appendErr
+	}
+	if err := c.poll("APPEND"); err != nil {
+		return err
+	}
+	return c.writeAppendOK(tag, data)
+}
+
+func (c *Conn) writeAppendOK(tag string, data *imap.AppendData) error {
+	enc := newResponseEncoder(c)
+	defer enc.end()
+
+	enc.Atom(tag).SP().Atom("OK").SP()
+	if data != nil {
+		enc.Special('[')
+		enc.Atom("APPENDUID").SP().Number(data.UIDValidity).SP().UID(data.UID)
+		enc.Special(']').SP()
+	}
+	enc.Text("APPEND completed")
+	return enc.CRLF()
+}
+
+func (c *Conn) writeMultiAppendOK(tag string, data *imap.MultiAppendData) error {
+	enc := newResponseEncoder(c)
+	defer enc.end()
+
+	enc.Atom(tag).SP().Atom("OK").SP()
+	if data != nil && len(data.UIDs) > 0 {
+		enc.Special('[')
+		enc.Atom("APPENDUID").SP().Number(data.UIDValidity).SP().NumSet(data.UIDs)
+		enc.Special(']').SP()
+	}
+	enc.Text("APPEND completed")
+	return enc.CRLF()
+}
+
+// AppendMessage is a message sent by the client in an APPEND command.
+type AppendMessage struct {
+	Literal imap.LiteralReader
+	Options *imap.AppendOptions
+}
+
+// MultiAppendReader reads the messages of an APPEND command.
+//
+// See SessionMultiAppend.
+type MultiAppendReader struct {
+	conn *Conn
+	dec  *imapwire.Decoder
+
+	cur     *AppendMessage // nil once all messages have been read
+	dataExt bool
+	pending bool // cur hasn't been returned by Next yet
+	err     error
+}
+
+// Next advances to the next message.
+//
+// The previous message's literal is discarded if it hasn't been fully read.
+// Nil is returned once all messages have been read.
+func (r *MultiAppendReader) Next() (*AppendMessage, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	if r.pending {
+		r.pending = false
+		return r.cur, nil
+	}
+	if r.cur == nil {
+		return nil, nil
+	}
+
+	more, err := r.finishMessage()
+	if err != nil {
+		r.err = err
+		return nil, err
+	} else if !more {
+		r.cur = nil
+		return nil, nil
+	}
+
+	msg, err := r.readMessage()
+	if err != nil {
+		r.err = err
+		return nil, err
+	}
+	r.cur = msg
+	return msg, nil
+}
+
+// discard reads all remaining messages.
+func (r *MultiAppendReader) discard() error {
+	r.pending = false
+	for {
+		msg, err := r.Next()
+		if err != nil {
+			return err
+		} else if msg == nil {
+			return nil
+		}
+	}
+}
+
+func (r *MultiAppendReader) readMessage() (*AppendMessage, error) {
+	dec := r.dec
+	var options imap.AppendOptions
+
 	hasFlagList, err := dec.List(func() error {
 		flag, err := internal.ExpectFlag(dec)
 		if err != nil {
@@ -33,18 +192,18 @@ func (c *Conn) handleAppend(tag string, dec *imapwire.Decoder) error {
 		return nil
 	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if hasFlagList && !dec.ExpectSP() {
-		return dec.Err()
+		return nil, dec.Err()
 	}
 
 	t, err := internal.DecodeDateTime(dec)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if !t.IsZero() && !dec.ExpectSP() {
-		return dec.Err()
+		return nil, dec.Err()
 	}
 	options.Time = t
 
@@ -54,69 +213,162 @@ func (c *Conn) handleAppend(tag string, dec *imapwire.Decoder) error {
 		case "UTF8":
 			// '~' is the literal8 prefix
 			if !dec.ExpectSP() || !dec.ExpectSpecial('(') || !dec.ExpectSpecial('~') {
-				return dec.Err()
+				return nil, dec.Err()
 			}
+		case "CATENATE":
+			if !dec.ExpectSP() {
+				return nil, dec.Err()
+			}
+			lit, err := r.readCatenate()
+			if err != nil {
+				return nil, err
+			}
+			r.dataExt = false
+			return &AppendMessage{Literal: lit, Options: &options}, nil
 		default:
-			return newClientBugError("Unknown APPEND data extension")
+			return nil, newClientBugError("Unknown APPEND data extension")
 		}
 	} else {
 		dec.Special('~') // ignore literal8 prefix if any for BINARY
 	}
+	r.dataExt = dataExt != ""
 
 	lit, nonSync, err := dec.ExpectLiteralReader()
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	if err := checkAppendLimit(lit.Size()); err != nil {
+		return nil, err
+	}
+	if err := r.conn.acceptLiteral(lit.Size(), nonSync); err != nil {
+		return nil, err
+	}
+
+	return &AppendMessage{Literal: lit, Options: &options}, nil
+}
+
+// readCatenate reads the parts of a CATENATE message, and returns the
+// concatenated message.
+func (r *MultiAppendReader) readCatenate() (imap.LiteralReader, error) {
+	session, ok := r.conn.session.(SessionCatenate)
+	if !ok || !r.conn.server.options.caps().Has(imap.CapCatenate) {
+		return nil, newClientBugError("CATENATE is not supported")
+	}
+	if err := r.conn.checkState(imap.ConnStateAuthenticated); err != nil {
+		return nil, err
+	}
+
+	dec := r.dec
+	var (
+		buf    bytes.Buffer
+		nParts int
+	)
+	err := dec.ExpectList(func() error {
+		nParts++
+
+		var typ string
+		if !dec.ExpectAtom(&typ) || !dec.ExpectSP() {
+			return dec.Err()
+		}
+
+		switch strings.ToUpper(typ) {
+		case "TEXT":
+			lit, nonSync, err := dec.ExpectLiteralReader()
+			if err != nil {
+				return err
+			}
+			if err := checkAppendLimit(int64(buf.Len()) + lit.Size()); err != nil {
+				return err
+			}
+			if err := r.conn.acceptLiteral(lit.Size(), nonSync); err != nil {
+				return err
+			}
+			_, err = buf.ReadFrom(lit)
+			return err
+		case "URL":
+			var rawURL string
+			if !dec.ExpectAString(&rawURL) {
+				return dec.Err()
+			}
+			// The URL is echoed back in the BADURL response code
+			if strings.ContainsAny(rawURL, "]\r\n") {
+				return newClientBugError("Invalid CATENATE URL")
+			}
+			u, err := imap.ParseURL(rawURL)
+			if err != nil {
+				return &badURLError{url: rawURL, err: err}
+			}
+			b, err := session.CatenateURL(u)
+			if err != nil {
+				return &badURLError{url: rawURL, err: err}
+			}
+			if err := checkAppendLimit(int64(buf.Len() + len(b))); err != nil {
+				return err
+			}
+			buf.Write(b)
+			return nil
+		default:
+			return newClientBugError("Unknown CATENATE part type")
+		}
+	})
+	if err != nil {
+		return nil, err
+	} else if nParts == 0 {
+		return nil, newClientBugError("CATENATE requires at least one part")
 	}
 
-	if lit.Size() > appendLimit {
+	return bytes.NewReader(buf.Bytes()), nil
+}
+
+func checkAppendLimit(size int64) error {
+	if size > appendLimit {
 		return &imap.Error{
 			Type: imap.StatusResponseTypeNo,
 			Code: imap.ResponseCodeTooBig,
 			Text: fmt.Sprintf("Literals are limited to %v bytes for this command", appendLimit),
 		}
 	}
-	if err := c.acceptLiteral(lit.Size(), nonSync); err != nil {
-		return err
-	}
+	return nil
+}
 
-	c.setReadTimeout(literalReadTimeout)
-	defer c.setReadTimeout(cmdReadTimeout)
+// badURLError is returned when a CATENATE URL cannot be resolved.
+type badURLError struct {
+	url string
+	err error
+}
 
-	if err := c.checkState(imap.ConnStateAuthenticated); err != nil {
-		io.Copy(io.Discard, lit)
-		dec.CRLF()
-		return err
-	}
+func (err *badURLError) Error() string {
+	return fmt.Sprintf("bad URL %q: %v", err.url, err.err)
+}
 
-	data, appendErr := c.session.Append(mailbox, lit, &options)
-	if _, discardErr := io.Copy(io.Discard, lit); discardErr != nil {
-		return err
-	}
-	if dataExt != "" && !dec.ExpectSpecial(')') {
-		return dec.Err()
-	}
-	if !dec.ExpectCRLF() {
-		return err
-	}
-	if appendErr != nil {
-		return appendErr
-	}
-	if err := c.poll("APPEND"); err != nil {
-		return err
-	}
-	return c.writeAppendOK(tag, data)
+func (err *badURLError) Unwrap() error {
+	return err.err
 }
 
-func (c *Conn) writeAppendOK(tag string, data *imap.AppendData) error {
+func (c *Conn) writeBadURL(tag string, badURL *badURLError) error {
+	text := "Invalid URL"
+	var imapErr *imap.Error
+	if errors.As(badURL.err, &imapErr) && imapErr.Text != "" {
+		text = imapErr.Text
+	}
+
 	enc := newResponseEncoder(c)
 	defer enc.end()
 
-	enc.Atom(tag).SP().Atom("OK").SP()
-	if data != nil {
-		enc.Special('[')
-		enc.Atom("APPENDUID").SP().Number(data.UIDValidity).SP().UID(data.UID)
-		enc.Special(']').SP()
-	}
-	enc.Text("APPEND completed")
+	enc.Atom(tag).SP().Atom("NO").SP().Special('[').Atom("BADURL").SP()
+	enc.Atom(badURL.url).Special(']').SP().Text(text)
 	return enc.CRLF()
 }
+
+// finishMessage discards the rest of the current message, and checks whether
+// another message follows.
+func (r *MultiAppendReader) finishMessage() (more bool, err error) {
+	if _, err := io.Copy(io.Discard, r.cur.Literal); err != nil {
+		return false, err
+	}
+	if r.dataExt && !r.dec.ExpectSpecial(')') {
+		return false, r.dec.Err()
+	}
+	return r.dec.SP(), nil
+}
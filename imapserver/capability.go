@@ -43,6 +43,7 @@ func (c *Conn) availableCaps() []imap.Cap {
 			imap.CapLiteralMinus,
 		}...)
 	}
+	addAvailableCaps(&caps, available, []imap.Cap{imap.CapID})
 	if c.canStartTLS() {
 		caps = append(caps, imap.CapStartTLS)
 	}
@@ -78,10 +79,46 @@ func (c *Conn) availableCaps() []imap.Cap {
 			})
 		}
 		addAvailableCaps(&caps, available, []imap.Cap{
+			imap.CapCondStore,
+			imap.CapQResync,
+			imap.CapSort,
+			imap.CapESort,
+			imap.CapQuota,
+			imap.CapQuotaSet,
+			imap.CapMetadata,
+			imap.CapMetadataServer,
+			imap.CapACL,
+			imap.CapNotify,
+			imap.CapMultiAppend,
+			imap.CapCatenate,
+			imap.CapURLAuth,
+			imap.CapURLPartial,
+			imap.CapReplace,
+			imap.CapPreview,
+			imap.CapObjectID,
+			imap.CapSaveDate,
+			imap.CapWithin,
+			imap.CapSearchFuzzy,
+			imap.CapPartial,
+			imap.CapContextSearch,
+			imap.CapContextSort,
+			imap.CapFilters,
+			imap.CapMultiSearch,
+			imap.CapUIDOnly,
+			imap.CapCompressDeflate,
+			imap.CapSpecialUse,
 			imap.CapCreateSpecialUse,
 			imap.CapLiteralPlus,
 			imap.CapUnauthenticate,
 		})
+		if quotaSess, ok := c.session.(SessionQuota); ok && available.Has(imap.CapQuota) {
+			for _, typ := range quotaSess.QuotaResourceTypes() {
+				caps = append(caps, imap.Cap("QUOTA=RES-"+typ))
+			}
+		}
+		for _, alg := range c.threadAlgorithms() {
+			caps = append(caps, imap.Cap("THREAD="+alg))
+		}
 	}
 	return caps
 }
@@ -0,0 +1,74 @@
+package imapserver
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/internal"
+	"github.com/emersion/go-imap/v2/internal/imapwire"
+)
+
+func (c *Conn) handleCompress(tag string, dec *imapwire.Decoder) error {
+	var alg string
+	if !dec.ExpectSP() || !dec.ExpectAtom(&alg) || !dec.ExpectCRLF() {
+		return dec.Err()
+	}
+
+	if err := c.checkState(imap.ConnStateAuthenticated); err != nil {
+		return err
+	}
+	if !c.server.options.caps().Has(imap.CapCompressDeflate) {
+		return newClientBugError("COMPRESS is not supported")
+	}
+	if !strings.EqualFold(alg, "DEFLATE") {
+		return &imap.Error{
+			Type: imap.StatusResponseTypeBad,
+			Text: "Unsupported compression algorithm",
+		}
+	}
+
+	c.mutex.Lock()
+	_, compressed := c.conn.(*internal.DeflateConn)
+	c.mutex.Unlock()
+	if compressed {
+		return &imap.Error{
+			Type: imap.StatusResponseTypeNo,
+			Code: imap.ResponseCodeCompressionActive,
+			Text: "Compression is already active",
+		}
+	}
+
+	// Do not allow to write uncompressed data past this point: keep
+	// c.encMutex locked until the end
+	enc := newResponseEncoder(c)
+	defer enc.end()
+
+	err := writeStatusResp(enc.Encoder, tag, &imap.StatusResponse{
+		Type: imap.StatusResponseTypeOK,
+		Text: "Begin compression now",
+	})
+	if err != nil {
+		return err
+	}
+
+	// Drain buffered data from our bufio.Reader
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, c.br, int64(c.br.Buffered())); err != nil {
+		panic(err) // unreachable
+	}
+
+	c.mutex.Lock()
+	deflateConn := internal.NewDeflateConn(c.conn, buf.Bytes())
+	c.conn = deflateConn
+	c.mutex.Unlock()
+
+	// Wrap the compressed connection, so that the debug output stays
+	// uncompressed
+	rw := c.server.options.wrapReadWriter(deflateConn)
+	c.br.Reset(rw)
+	c.bw.Reset(rw)
+
+	return nil
+}
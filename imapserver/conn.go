@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/internal"
 	"github.com/emersion/go-imap/v2/internal/imapwire"
 )
 
@@ -44,6 +45,9 @@ type Conn struct {
 
 	state   imap.ConnState
 	session Session
+
+	searchContexts      []*searchContext
+	searchContextsStale bool // messages have changed since the last update
 }
 
 func newConn(c net.Conn, server *Server) *Conn {
@@ -141,9 +145,48 @@ func (c *Conn) serve() {
 	if _, ok := c.session.(SessionMove); !ok && caps.Has(imap.CapMove) {
 		panic("imapserver: server advertises MOVE but session doesn't support it")
 	}
+	if _, ok := c.session.(SessionCondStore); !ok && caps.Has(imap.CapCondStore) {
+		panic("imapserver: server advertises CONDSTORE but session doesn't support it")
+	}
+	if _, ok := c.session.(SessionSort); !ok && caps.Has(imap.CapSort) {
+		panic("imapserver: server advertises SORT but session doesn't support it")
+	}
+	if _, ok := c.session.(SessionQuota); !ok && caps.Has(imap.CapQuota) {
+		panic("imapserver: server advertises QUOTA but session doesn't support it")
+	}
+	if _, ok := c.session.(SessionQuotaSet); !ok && caps.Has(imap.CapQuotaSet) {
+		panic("imapserver: server advertises QUOTASET but session doesn't support it")
+	}
+	if _, ok := c.session.(SessionMetadata); !ok && (caps.Has(imap.CapMetadata) || caps.Has(imap.CapMetadataServer)) {
+		panic("imapserver: server advertises METADATA but session doesn't support it")
+	}
+	if _, ok := c.session.(SessionACL); !ok && caps.Has(imap.CapACL) {
+		panic("imapserver: server advertises ACL but session doesn't support it")
+	}
+	if _, ok := c.session.(SessionNotify); !ok && caps.Has(imap.CapNotify) {
+		panic("imapserver: server advertises NOTIFY but session doesn't support it")
+	}
+	if _, ok := c.session.(SessionMultiAppend); !ok && caps.Has(imap.CapMultiAppend) {
+		panic("imapserver: server advertises MULTIAPPEND but session doesn't support it")
+	}
+	if _, ok := c.session.(SessionCatenate); !ok && caps.Has(imap.CapCatenate) {
+		panic("imapserver: server advertises CATENATE but session doesn't support it")
+	}
+	if _, ok := c.session.(SessionURLAuth); !ok && caps.Has(imap.CapURLAuth) {
+		panic("imapserver: server advertises URLAUTH but session doesn't support it")
+	}
+	if _, ok := c.session.(SessionReplace); !ok && caps.Has(imap.CapReplace) {
+		panic("imapserver: server advertises REPLACE but session doesn't support it")
+	}
+	if _, ok := c.session.(SessionMultiSearch); !ok && caps.Has(imap.CapMultiSearch) {
+		panic("imapserver: server advertises MULTISEARCH but session doesn't support it")
+	}
 	if _, ok := c.session.(SessionUnauthenticate); !ok && caps.Has(imap.CapUnauthenticate) {
 		panic("imapserver: server advertises UNAUTHENTICATE but session doesn't support it")
 	}
+	if _, ok := c.session.(SessionUIDOnly); !ok && caps.Has(imap.CapUIDOnly) {
+		panic("imapserver: server advertises UIDONLY but session doesn't support it")
+	}
 
 	c.state = imap.ConnStateNotAuthenticated
 	statusType := imap.StatusResponseTypeOK
@@ -213,6 +256,9 @@ func (c *Conn) readCommand(dec *imapwire.Decoder) error {
 	case "STARTTLS":
 		err = c.handleStartTLS(tag, dec)
 		sendOK = false
+	case "COMPRESS":
+		err = c.handleCompress(tag, dec)
+		sendOK = false
 	case "AUTHENTICATE":
 		err = c.handleAuthenticate(tag, dec)
 		sendOK = false
@@ -221,10 +267,16 @@ func (c *Conn) readCommand(dec *imapwire.Decoder) error {
 	case "LOGIN":
 		err = c.handleLogin(tag, dec)
 		sendOK = false
+	case "ID":
+		err = c.handleID(dec)
+	case "NOTIFY":
+		err = c.handleNotify(tag, dec)
+		sendOK = false
 	case "ENABLE":
 		err = c.handleEnable(dec)
 	case "CREATE":
-		err = c.handleCreate(dec)
+		err = c.handleCreate(tag, dec)
+		sendOK = false
 	case "DELETE":
 		err = c.handleDelete(dec)
 	case "RENAME":
@@ -241,6 +293,33 @@ func (c *Conn) readCommand(dec *imapwire.Decoder) error {
 		err = c.handleLSub(dec)
 	case "NAMESPACE":
 		err = c.handleNamespace(dec)
+	case "GETQUOTA":
+		err = c.handleGetQuota(dec)
+	case "GETQUOTAROOT":
+		err = c.handleGetQuotaRoot(dec)
+	case "SETQUOTA":
+		err = c.handleSetQuota(dec)
+	case "GETMETADATA":
+		err = c.handleGetMetadata(tag, dec)
+		sendOK = false
+	case "SETMETADATA":
+		err = c.handleSetMetadata(dec)
+	case "MYRIGHTS":
+		err = c.handleMyRights(dec)
+	case "SETACL":
+		err = c.handleSetACL(dec)
+	case "DELETEACL":
+		err = c.handleDeleteACL(dec)
+	case "GETACL":
+		err = c.handleGetACL(dec)
+	case "LISTRIGHTS":
+		err = c.handleListRights(dec)
+	case "GENURLAUTH":
+		err = c.handleGenURLAuth(dec)
+	case "RESETKEY":
+		err = c.handleResetKey(dec)
+	case "URLFETCH":
+		err = c.handleURLFetch(dec)
 	case "IDLE":
 		err = c.handleIdle(dec)
 	case "SELECT", "EXAMINE":
@@ -258,14 +337,26 @@ func (c *Conn) readCommand(dec *imapwire.Decoder) error {
 	case "UID EXPUNGE":
 		err = c.handleUIDExpunge(dec)
 	case "STORE", "UID STORE":
-		err = c.handleStore(dec, numKind)
+		err = c.handleStore(tag, dec, numKind)
+		sendOK = false
 	case "COPY", "UID COPY":
 		err = c.handleCopy(tag, dec, numKind)
 		sendOK = false
 	case "MOVE", "UID MOVE":
 		err = c.handleMove(dec, numKind)
+	case "REPLACE", "UID REPLACE":
+		err = c.handleReplace(tag, dec, numKind)
+		sendOK = false
 	case "SEARCH", "UID SEARCH":
 		err = c.handleSearch(tag, dec, numKind)
+	case "SORT", "UID SORT":
+		err = c.handleSort(tag, dec, numKind)
+	case "THREAD", "UID THREAD":
+		err = c.handleThread(dec, numKind)
+	case "CANCELUPDATE":
+		err = c.handleCancelUpdate(dec)
+	case "ESEARCH":
+		err = c.handleMultiSearch(tag, dec)
 	default:
 		if c.state == imap.ConnStateNotAuthenticated {
 			// Don't allow a single unknown command before authentication to
@@ -408,7 +499,11 @@ func (c *Conn) canAuth() bool {
 	if c.state != imap.ConnStateNotAuthenticated {
 		return false
 	}
-	_, isTLS := c.conn.(*tls.Conn)
+	conn := c.conn
+	if deflateConn, ok := conn.(*internal.DeflateConn); ok {
+		conn = deflateConn.NetConn()
+	}
+	_, isTLS := conn.(*tls.Conn)
 	return isTLS || c.server.options.InsecureAuth
 }
 
@@ -440,6 +535,38 @@ func (c *Conn) checkState(state imap.ConnState) error {
 	return nil
 }
 
+// enableCondStore marks CONDSTORE as enabled, as a side-effect of a
+// CONDSTORE-enabling command (RFC 7162 section 3.1).
+func (c *Conn) enableCondStore() {
+	c.mutex.Lock()
+	c.enabled[imap.CapCondStore] = struct{}{}
+	c.mutex.Unlock()
+}
+
+func (c *Conn) condStoreEnabled() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.enabled.Has(imap.CapCondStore)
+}
+
+func (c *Conn) qresyncEnabled() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.enabled.Has(imap.CapQResync)
+}
+
+func (c *Conn) uidOnlyEnabled() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.enabled.Has(imap.CapUIDOnly)
+}
+
+func (c *Conn) metadataEnabled() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.enabled.Has(imap.CapMetadata)
+}
+
 func (c *Conn) setReadTimeout(dur time.Duration) {
 	if dur > 0 {
 		c.conn.SetReadDeadline(time.Now().Add(dur))
@@ -471,7 +598,10 @@ func (c *Conn) poll(cmd string) error {
 	}
 
 	w := &UpdateWriter{conn: c, allowExpunge: allowExpunge}
-	return c.session.Poll(w, allowExpunge)
+	if err := c.session.Poll(w, allowExpunge); err != nil {
+		return err
+	}
+	return c.updateSearchContexts()
 }
 
 type responseEncoder struct {
@@ -577,6 +707,15 @@ func (w *UpdateWriter) WriteExpunge(seqNum uint32) error {
 	return w.conn.writeExpunge(seqNum)
 }
 
+// WriteExpungeUID writes an EXPUNGE response, or a VANISHED response if the
+// client has enabled QRESYNC or UIDONLY.
+func (w *UpdateWriter) WriteExpungeUID(seqNum uint32, uid imap.UID) error {
+	if !w.allowExpunge {
+		return fmt.Errorf("imapserver: EXPUNGE updates are not allowed in this context")
+	}
+	return w.conn.writeExpungeUID(seqNum, uid)
+}
+
 // WriteNumMessages writes an EXISTS response.
 func (w *UpdateWriter) WriteNumMessages(n uint32) error {
 	return w.conn.writeExists(n)
@@ -587,13 +726,66 @@ func (w *UpdateWriter) WriteMailboxFlags(flags []imap.Flag) error {
 	return w.conn.writeFlags(flags)
 }
 
+// WriteMetadata writes an unsolicited METADATA response, indicating that the
+// value of some mailbox entries have changed.
+//
+// This is a no-op unless the client has enabled METADATA.
+func (w *UpdateWriter) WriteMetadata(mailbox string, entries []string) error {
+	if !w.conn.metadataEnabled() {
+		return nil
+	}
+	return w.conn.writeMetadataEntryList(mailbox, entries)
+}
+
+// WriteStatus writes an unsolicited STATUS response, typically for a NOTIFY
+// event.
+//
+// Only the populated fields of data are written. HIGHESTMODSEQ is omitted if
+// the client hasn't enabled CONDSTORE.
+func (w *UpdateWriter) WriteStatus(data *imap.StatusData) error {
+	options := imap.StatusOptions{
+		NumMessages:    data.NumMessages != nil,
+		UIDNext:        data.UIDNext != 0,
+		UIDValidity:    data.UIDValidity != 0,
+		NumUnseen:      data.NumUnseen != nil,
+		NumDeleted:     data.NumDeleted != nil,
+		Size:           data.Size != nil,
+		AppendLimit:    data.AppendLimit != nil,
+		DeletedStorage: data.DeletedStorage != nil,
+		HighestModSeq:  data.HighestModSeq != 0 && w.conn.condStoreEnabled(),
+	}
+	return w.conn.writeStatus(data, &options, false)
+}
+
+// WriteList writes an unsolicited LIST response, typically for a NOTIFY
+// event.
+func (w *UpdateWriter) WriteList(data *imap.ListData) error {
+	return w.conn.writeList(data)
+}
+
 // WriteMessageFlags writes a FETCH response with FLAGS.
 func (w *UpdateWriter) WriteMessageFlags(seqNum uint32, uid imap.UID, flags []imap.Flag) error {
+	return w.WriteMessageFlagsModSeq(seqNum, uid, flags, 0)
+}
+
+// WriteMessageFlagsModSeq writes a FETCH response with FLAGS and MODSEQ.
+//
+// MODSEQ is omitted if zero or if the client hasn't enabled CONDSTORE.
+func (w *UpdateWriter) WriteMessageFlagsModSeq(seqNum uint32, uid imap.UID, flags []imap.Flag, modSeq uint64) error {
 	fetchWriter := &FetchWriter{conn: w.conn}
-	respWriter := fetchWriter.CreateMessage(seqNum)
+	var respWriter *FetchResponseWriter
 	if uid != 0 {
+		respWriter = fetchWriter.CreateMessageUID(seqNum, uid)
 		respWriter.WriteUID(uid)
+	} else if w.conn.uidOnlyEnabled() {
+		return fmt.Errorf("imapserver: cannot write FETCH response without UID when UIDONLY is enabled")
+	} else {
+		respWriter = fetchWriter.CreateMessage(seqNum)
 	}
 	respWriter.WriteFlags(flags)
+	if modSeq != 0 {
+		respWriter.WriteModSeq(modSeq)
+	}
+	w.conn.invalidateSearchContexts()
 	return respWriter.Close()
 }
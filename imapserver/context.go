@@ -0,0 +1,359 @@
+package imapserver
+
+import (
+	"fmt"
+
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/internal/imapwire"
+)
+
+// searchContext is the result of a SEARCH or SORT command which used the
+// UPDATE return option.
+type searchContext struct {
+	tag          string
+	numKind      NumKind
+	criteria     *imap.SearchCriteria
+	sortCriteria []imap.SortCriterion // nil for SEARCH
+	uids         []imap.UID           // in sort order for SORT
+}
+
+func (c *Conn) handleCancelUpdate(dec *imapwire.Decoder) error {
+	var tags []string
+	for dec.SP() {
+		var tag string
+		if !dec.ExpectString(&tag) {
+			return dec.Err()
+		}
+		tags = append(tags, tag)
+	}
+	if !dec.ExpectCRLF() {
+		return dec.Err()
+	}
+	if len(tags) == 0 {
+		return newClientBugError("CANCELUPDATE requires at least one tag")
+	}
+
+	if err := c.checkState(imap.ConnStateSelected); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	var l []*searchContext
+	for _, ctx := range c.searchContexts {
+		canceled := false
+		for _, tag := range tags {
+			if ctx.tag == tag {
+				canceled = true
+				break
+			}
+		}
+		if !canceled {
+			l = append(l, ctx)
+		}
+	}
+	c.searchContexts = l
+	return nil
+}
+
+// addSearchContext registers a search context. nums contains the result of
+// the original search, in the order of the results.
+func (c *Conn) addSearchContext(ctx *searchContext, nums []uint32) error {
+	if ctx.numKind == NumKindUID {
+		for _, num := range nums {
+			ctx.uids = append(ctx.uids, imap.UID(num))
+		}
+	} else if len(nums) > 0 {
+		uids, err := c.searchUIDs(nums)
+		if err != nil {
+			return err
+		}
+		for _, seqNum := range nums {
+			if uid, ok := uids[seqNum]; ok {
+				ctx.uids = append(ctx.uids, uid)
+			}
+		}
+	}
+
+	c.mutex.Lock()
+	c.searchContexts = append(c.searchContexts, ctx)
+	c.mutex.Unlock()
+	return nil
+}
+
+func (c *Conn) clearSearchContexts() {
+	c.mutex.Lock()
+	c.searchContexts = nil
+	c.mutex.Unlock()
+}
+
+// invalidateSearchContexts marks the search contexts for re-evaluation, after
+// messages have been added, removed or modified.
+func (c *Conn) invalidateSearchContexts() {
+	c.mutex.Lock()
+	c.searchContextsStale = true
+	c.mutex.Unlock()
+}
+
+func (c *Conn) searchContextUIDs(ctx *searchContext) ([]imap.UID, error) {
+	if ctx.sortCriteria != nil {
+		nums, err := c.session.(SessionSort).Sort(NumKindUID, ctx.sortCriteria, ctx.criteria)
+		if err != nil {
+			return nil, err
+		}
+		uids := make([]imap.UID, len(nums))
+		for i, num := range nums {
+			uids[i] = imap.UID(num)
+		}
+		return uids, nil
+	}
+
+	data, err := c.session.Search(NumKindUID, ctx.criteria, &imap.SearchOptions{ReturnAll: true})
+	if err != nil {
+		return nil, err
+	}
+	uidSet, _ := data.All.(imap.UIDSet)
+	uids, _ := uidSet.Nums()
+	return uids, nil
+}
+
+// updateSearchContexts evaluates again the searches registered with the
+// UPDATE return option, and notifies the client about changes.
+//
+// This is a no-op if messages haven't changed since the last call.
+func (c *Conn) updateSearchContexts() error {
+	c.mutex.Lock()
+	stale := c.searchContextsStale
+	c.searchContextsStale = false
+	contexts := append([]*searchContext(nil), c.searchContexts...)
+	c.mutex.Unlock()
+
+	if !stale {
+		return nil
+	}
+
+	for _, ctx := range contexts {
+		uids, err := c.searchContextUIDs(ctx)
+		if err != nil {
+			return err
+		}
+		update, err := c.diffSearchContext(ctx, uids)
+		if err != nil {
+			return err
+		} else if update == nil {
+			// try again later
+			c.invalidateSearchContexts()
+			continue
+		}
+		ctx.uids = uids
+		if len(update.AddTo) == 0 && len(update.RemoveFrom) == 0 {
+			continue
+		}
+		if err := c.writeSearchUpdate(update); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// diffSearchContext computes the changes between the current result of a
+// search context and a new result. nil is returned if the changes can't be
+// computed yet.
+func (c *Conn) diffSearchContext(ctx *searchContext, uids []imap.UID) (*imap.SearchUpdate, error) {
+	oldSet := make(map[imap.UID]struct{}, len(ctx.uids))
+	for _, uid := range ctx.uids {
+		oldSet[uid] = struct{}{}
+	}
+	newSet := make(map[imap.UID]struct{}, len(uids))
+	for _, uid := range uids {
+		newSet[uid] = struct{}{}
+	}
+
+	var removed, added []int
+	for i, uid := range ctx.uids {
+		if _, ok := newSet[uid]; !ok {
+			removed = append(removed, i)
+		}
+	}
+	for i, uid := range uids {
+		if _, ok := oldSet[uid]; !ok {
+			added = append(added, i)
+		}
+	}
+
+	update := &imap.SearchUpdate{Tag: ctx.tag, UID: ctx.numKind == NumKindUID}
+	if len(removed) == 0 && len(added) == 0 {
+		return update, nil
+	}
+
+	// Sequence numbers are only known for messages which haven't been
+	// expunged. The client learns about expunged messages via EXPUNGE.
+	var seqNums map[imap.UID]uint32
+	if ctx.numKind == NumKindSeq {
+		var l []imap.UID
+		for _, i := range removed {
+			l = append(l, ctx.uids[i])
+		}
+		for _, i := range added {
+			l = append(l, uids[i])
+		}
+		var (
+			ok  bool
+			err error
+		)
+		seqNums, ok, err = c.searchSeqNums(l)
+		if err != nil || !ok {
+			return nil, err
+		}
+	}
+	num := func(uid imap.UID) (uint32, bool) {
+		if ctx.numKind == NumKindUID {
+			return uint32(uid), true
+		}
+		seqNum, ok := seqNums[uid]
+		return seqNum, ok
+	}
+
+	// For SEARCH, the position is always zero and all messages can be
+	// grouped together
+	if ctx.sortCriteria == nil {
+		var r imap.SearchUpdateRange
+		for _, i := range removed {
+			if n, ok := num(ctx.uids[i]); ok {
+				r.Nums = append(r.Nums, n)
+			}
+		}
+		if len(r.Nums) > 0 {
+			update.RemoveFrom = append(update.RemoveFrom, r)
+		}
+		r = imap.SearchUpdateRange{}
+		for _, i := range added {
+			if n, ok := num(uids[i]); ok {
+				r.Nums = append(r.Nums, n)
+			}
+		}
+		if len(r.Nums) > 0 {
+			update.AddTo = append(update.AddTo, r)
+		}
+		return update, nil
+	}
+
+	// For SORT, messages are removed starting from the end of the old result
+	// so that positions stay valid, then added in the order of the new result
+	for _, run := range contiguousRuns(removed) {
+		r := imap.SearchUpdateRange{Position: uint32(run[0]) + 1}
+		for _, i := range run {
+			if n, ok := num(ctx.uids[i]); ok {
+				r.Nums = append(r.Nums, n)
+			}
+		}
+		if len(r.Nums) > 0 {
+			update.RemoveFrom = append([]imap.SearchUpdateRange{r}, update.RemoveFrom...)
+		}
+	}
+	for _, run := range contiguousRuns(added) {
+		r := imap.SearchUpdateRange{Position: uint32(run[0]) + 1}
+		for _, i := range run {
+			if n, ok := num(uids[i]); ok {
+				r.Nums = append(r.Nums, n)
+			}
+		}
+		if len(r.Nums) > 0 {
+			update.AddTo = append(update.AddTo, r)
+		}
+	}
+	return update, nil
+}
+
+// searchSeqNums returns the sequence numbers of the messages with the
+// specified UIDs. Expunged messages are omitted.
+//
+// false is returned if the sequence numbers can't be determined yet, because
+// an expunge is pending.
+func (c *Conn) searchSeqNums(uids []imap.UID) (map[imap.UID]uint32, bool, error) {
+	criteria := imap.SearchCriteria{UID: []imap.UIDSet{imap.UIDSetNum(uids...)}}
+	options := imap.SearchOptions{ReturnAll: true}
+	uidData, err := c.session.Search(NumKindUID, &criteria, &options)
+	if err != nil {
+		return nil, false, err
+	}
+	seqData, err := c.session.Search(NumKindSeq, &criteria, &options)
+	if err != nil {
+		return nil, false, err
+	}
+	uidSet, _ := uidData.All.(imap.UIDSet)
+	existing, _ := uidSet.Nums()
+	seqSet, _ := seqData.All.(imap.SeqSet)
+	seqNums, _ := seqSet.Nums()
+	if len(existing) != len(seqNums) {
+		return nil, false, nil
+	}
+
+	// Sequence numbers and UIDs are in the same order
+	m := make(map[imap.UID]uint32, len(seqNums))
+	for i, seqNum := range seqNums {
+		m[existing[i]] = seqNum
+	}
+	return m, true, nil
+}
+
+// searchUIDs returns the UIDs of the messages with the specified sequence
+// numbers. Expunged messages are omitted.
+func (c *Conn) searchUIDs(seqNums []uint32) (map[uint32]imap.UID, error) {
+	criteria := imap.SearchCriteria{SeqNum: []imap.SeqSet{imap.SeqSetNum(seqNums...)}}
+	options := imap.SearchOptions{ReturnAll: true}
+	seqData, err := c.session.Search(NumKindSeq, &criteria, &options)
+	if err != nil {
+		return nil, err
+	}
+	uidData, err := c.session.Search(NumKindUID, &criteria, &options)
+	if err != nil {
+		return nil, err
+	}
+	seqSet, _ := seqData.All.(imap.SeqSet)
+	existing, _ := seqSet.Nums()
+	uidSet, _ := uidData.All.(imap.UIDSet)
+	uids, _ := uidSet.Nums()
+	if len(existing) != len(uids) {
+		return nil, fmt.Errorf("imapserver: mismatched sequence numbers and UIDs")
+	}
+
+	// Sequence numbers and UIDs are in the same order
+	m := make(map[uint32]imap.UID, len(uids))
+	for i, uid := range uids {
+		m[existing[i]] = uid
+	}
+	return m, nil
+}
+
+// contiguousRuns splits a sorted list of indices into runs of consecutive
+// indices.
+func contiguousRuns(indices []int) [][]int {
+	var runs [][]int
+	for i, index := range indices {
+		if i > 0 && index == indices[i-1]+1 {
+			runs[len(runs)-1] = append(runs[len(runs)-1], index)
+		} else {
+			runs = append(runs, []int{index})
+		}
+	}
+	return runs
+}
+
+func (c *Conn) writeSearchUpdate(update *imap.SearchUpdate) error {
+	enc := newResponseEncoder(c)
+	defer enc.end()
+
+	enc.Atom("*").SP().Atom("ESEARCH")
+	enc.SP().Special('(').Atom("TAG").SP().Atom(update.Tag).Special(')')
+	if update.UID {
+		enc.SP().Atom("UID")
+	}
+	for _, r := range update.RemoveFrom {
+		enc.SP().Atom("REMOVEFROM").SP().Special('(').Number(r.Position).SP().Atom(sortedSeqSetString(r.Nums)).Special(')')
+	}
+	for _, r := range update.AddTo {
+		enc.SP().Atom("ADDTO").SP().Special('(').Number(r.Position).SP().Atom(sortedSeqSetString(r.Nums)).Special(')')
+	}
+	return enc.CRLF()
+}
@@ -8,7 +8,7 @@ import (
 	"github.com/emersion/go-imap/v2/internal/imapwire"
 )
 
-func (c *Conn) handleCreate(dec *imapwire.Decoder) error {
+func (c *Conn) handleCreate(tag string, dec *imapwire.Decoder) error {
 	var (
 		name    string
 		options imap.CreateOptions
@@ -41,5 +41,32 @@ func (c *Conn) handleCreate(dec *imapwire.Decoder) error {
 	if err := c.checkState(imap.ConnStateAuthenticated); err != nil {
 		return err
 	}
-	return c.session.Create(name, &options)
+	if err := c.session.Create(name, &options); err != nil {
+		return err
+	}
+
+	var mailboxID string
+	if c.server.options.caps().Has(imap.CapObjectID) {
+		data, err := c.session.Status(name, &imap.StatusOptions{MailboxID: true})
+		if err == nil {
+			mailboxID = data.MailboxID
+		}
+	}
+
+	if err := c.poll("CREATE"); err != nil {
+		return err
+	}
+	return c.writeCreateOK(tag, mailboxID)
+}
+
+func (c *Conn) writeCreateOK(tag string, mailboxID string) error {
+	enc := newResponseEncoder(c)
+	defer enc.end()
+
+	enc.Atom(tag).SP().Atom("OK").SP()
+	if mailboxID != "" {
+		enc.Special('[').Atom("MAILBOXID").SP().Special('(').Atom(mailboxID).Special(')').Special(']').SP()
+	}
+	enc.Text("CREATE completed")
+	return enc.CRLF()
 }
@@ -23,10 +23,32 @@ func (c *Conn) handleEnable(dec *imapwire.Decoder) error {
 	}
 
 	var enabled []imap.Cap
+	enable := func(cap imap.Cap) {
+		for _, e := range enabled {
+			if e == cap {
+				return
+			}
+		}
+		enabled = append(enabled, cap)
+	}
 	for _, req := range requested {
 		switch req {
 		case imap.CapIMAP4rev2, imap.CapUTF8Accept:
-			enabled = append(enabled, req)
+			enable(req)
+		case imap.CapCondStore, imap.CapMetadata:
+			if c.server.options.caps().Has(req) {
+				enable(req)
+			}
+		case imap.CapUIDOnly:
+			if _, ok := c.session.(SessionUIDOnly); ok && c.server.options.caps().Has(req) {
+				enable(req)
+			}
+		case imap.CapQResync:
+			// QRESYNC implies CONDSTORE (RFC 7162 section 3.2.3)
+			if c.server.options.caps().Has(req) {
+				enable(req)
+				enable(imap.CapCondStore)
+			}
 		}
 	}
 
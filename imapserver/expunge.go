@@ -1,6 +1,8 @@
 package imapserver
 
 import (
+	"fmt"
+
 	"github.com/emersion/go-imap/v2"
 	"github.com/emersion/go-imap/v2/internal/imapwire"
 )
@@ -29,12 +31,35 @@ func (c *Conn) expunge(uids *imap.UIDSet) error {
 }
 
 func (c *Conn) writeExpunge(seqNum uint32) error {
+	if c.uidOnlyEnabled() {
+		return fmt.Errorf("imapserver: cannot write EXPUNGE response without UID when UIDONLY is enabled")
+	}
+	c.invalidateSearchContexts()
 	enc := newResponseEncoder(c)
 	defer enc.end()
 	enc.Atom("*").SP().Number(seqNum).SP().Atom("EXPUNGE")
 	return enc.CRLF()
 }
 
+func (c *Conn) writeExpungeUID(seqNum uint32, uid imap.UID) error {
+	if !c.qresyncEnabled() && !c.uidOnlyEnabled() {
+		return c.writeExpunge(seqNum)
+	}
+	c.invalidateSearchContexts()
+	return c.writeVanished(imap.UIDSetNum(uid), false)
+}
+
+func (c *Conn) writeVanished(uids imap.UIDSet, earlier bool) error {
+	enc := newResponseEncoder(c)
+	defer enc.end()
+	enc.Atom("*").SP().Atom("VANISHED").SP()
+	if earlier {
+		enc.Special('(').Atom("EARLIER").Special(')').SP()
+	}
+	enc.NumSet(uids)
+	return enc.CRLF()
+}
+
 // ExpungeWriter writes EXPUNGE updates.
 type ExpungeWriter struct {
 	conn *Conn
@@ -48,3 +73,14 @@ func (w *ExpungeWriter) WriteExpunge(seqNum uint32) error {
 	}
 	return w.conn.writeExpunge(seqNum)
 }
+
+// WriteExpungeUID is like WriteExpunge, but also provides the message's UID.
+//
+// A VANISHED response is written instead of EXPUNGE if the client has enabled
+// QRESYNC or UIDONLY.
+func (w *ExpungeWriter) WriteExpungeUID(seqNum uint32, uid imap.UID) error {
+	if w.conn == nil {
+		return nil
+	}
+	return w.conn.writeExpungeUID(seqNum, uid)
+}
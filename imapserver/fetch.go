@@ -1,6 +1,7 @@
 package imapserver
 
 import (
+	"bufio"
 	"fmt"
 	"io"
 	"mime"
@@ -75,6 +76,15 @@ func (c *Conn) handleFetch(dec *imapwire.Decoder, numKind NumKind) error {
 		}
 	}
 
+	if dec.SP() {
+		err := dec.ExpectList(func() error {
+			return c.readFetchModifier(dec, numKind, &options)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
 	if !dec.ExpectCRLF() {
 		return dec.Err()
 	}
@@ -82,10 +92,22 @@ func (c *Conn) handleFetch(dec *imapwire.Decoder, numKind NumKind) error {
 	if err := c.checkState(imap.ConnStateSelected); err != nil {
 		return err
 	}
+	if err := c.checkUIDOnly(numKind); err != nil {
+		return err
+	}
 
 	if numKind == NumKindUID {
 		options.UID = true
 	}
+	if options.Vanished && (numKind != NumKindUID || options.ChangedSince == 0) {
+		return newClientBugError("VANISHED requires UID FETCH and CHANGEDSINCE")
+	}
+	if options.ChangedSince > 0 {
+		options.ModSeq = true
+	}
+	if options.ModSeq {
+		c.enableCondStore()
+	}
 
 	w := &FetchWriter{conn: c, options: writerOptions}
 	if err := c.session.Fetch(w, numSet, &options); err != nil {
@@ -108,6 +130,36 @@ func handleFetchAtt(dec *imapwire.Decoder, attName string, options *imap.FetchOp
 		options.RFC822Size = true
 	case "UID":
 		options.UID = true
+	case "MODSEQ":
+		options.ModSeq = true
+	case "EMAILID":
+		options.EmailID = true
+	case "THREADID":
+		options.ThreadID = true
+	case "SAVEDATE":
+		options.SaveDate = true
+	case "PREVIEW":
+		options.Preview = &imap.FetchItemPreview{}
+		// Peek to avoid confusing a preview modifier list with FETCH modifiers
+		if dec.PeekFold(" (LAZY") {
+			if !dec.ExpectSP() {
+				return dec.Err()
+			}
+			err := dec.ExpectList(func() error {
+				var mod string
+				if !dec.ExpectAtom(&mod) {
+					return dec.Err()
+				}
+				if strings.ToUpper(mod) != "LAZY" {
+					return newClientBugError("Unknown PREVIEW modifier")
+				}
+				options.Preview.Lazy = true
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
 	case "RFC822": // equivalent to BODY[]
 		bs := &imap.FetchItemBodySection{}
 		writerOptions.obsolete[bs] = attName
@@ -182,6 +234,42 @@ func handleFetchAtt(dec *imapwire.Decoder, attName string, options *imap.FetchOp
 	return nil
 }
 
+func (c *Conn) readFetchModifier(dec *imapwire.Decoder, numKind NumKind, options *imap.FetchOptions) error {
+	var name string
+	if !dec.ExpectAtom(&name) {
+		return dec.Err()
+	}
+	switch strings.ToUpper(name) {
+	case "CHANGEDSINCE":
+		if !c.server.options.caps().Has(imap.CapCondStore) {
+			return newClientBugError("CONDSTORE is not supported")
+		}
+		if !dec.ExpectSP() || !dec.ExpectModSeq(&options.ChangedSince) {
+			return dec.Err()
+		}
+	case "VANISHED":
+		if !c.qresyncEnabled() {
+			return newClientBugError("QRESYNC must be enabled before using VANISHED")
+		}
+		options.Vanished = true
+	case "PARTIAL":
+		if !c.server.options.caps().Has(imap.CapPartial) {
+			return newClientBugError("PARTIAL is not supported")
+		}
+		if numKind != NumKindUID {
+			return newClientBugError("PARTIAL is only allowed with UID FETCH")
+		}
+		var r imap.PartialRange
+		if !dec.ExpectSP() || !dec.ExpectPartialRange(&r) {
+			return dec.Err()
+		}
+		options.Partial = &r
+	default:
+		return newClientBugError("Unknown FETCH modifier")
+	}
+	return nil
+}
+
 func handleFetchBodyStructure(options *imap.FetchOptions, writerOptions *fetchWriterOptions, extended bool) {
 	if options.BodyStructure == nil || extended {
 		options.BodyStructure = &imap.FetchItemBodyStructure{Extended: extended}
@@ -327,16 +415,61 @@ type FetchWriter struct {
 // CreateMessage writes a FETCH response for a message.
 //
 // FetchResponseWriter.Close must be called.
+//
+// Sessions implementing SessionUIDOnly must use CreateMessageUID instead: if
+// the client has enabled UIDONLY, the response is discarded and
+// FetchResponseWriter.Close returns an error.
 func (cmd *FetchWriter) CreateMessage(seqNum uint32) *FetchResponseWriter {
+	if cmd.conn.uidOnlyEnabled() {
+		enc := &responseEncoder{
+			Encoder: imapwire.NewEncoder(bufio.NewWriter(io.Discard), imapwire.ConnSideServer),
+			conn:    cmd.conn,
+		}
+		return &FetchResponseWriter{
+			enc:     enc,
+			options: cmd.options,
+			err:     fmt.Errorf("imapserver: FetchWriter.CreateMessage called with UIDONLY enabled, use CreateMessageUID"),
+		}
+	}
+	condStore := cmd.conn.condStoreEnabled()
 	enc := newResponseEncoder(cmd.conn)
 	enc.Atom("*").SP().Number(seqNum).SP().Atom("FETCH").SP().Special('(')
-	return &FetchResponseWriter{enc: enc, options: cmd.options}
+	return &FetchResponseWriter{enc: enc, options: cmd.options, condStore: condStore}
+}
+
+// CreateMessageUID is like CreateMessage, but also provides the message's
+// UID.
+//
+// If the client has enabled UIDONLY, a UIDFETCH response is written instead
+// and the message sequence number is ignored.
+func (cmd *FetchWriter) CreateMessageUID(seqNum uint32, uid imap.UID) *FetchResponseWriter {
+	if !cmd.conn.uidOnlyEnabled() {
+		return cmd.CreateMessage(seqNum)
+	}
+	condStore := cmd.conn.condStoreEnabled()
+	enc := newResponseEncoder(cmd.conn)
+	enc.Atom("*").SP().UID(uid).SP().Atom("UIDFETCH").SP().Special('(')
+	return &FetchResponseWriter{enc: enc, options: cmd.options, condStore: condStore, uidOnly: true}
+}
+
+// WriteVanished writes a VANISHED (EARLIER) response for messages which have
+// been expunged since the mod-sequence specified in imap.FetchOptions.
+//
+// This should only be called when imap.FetchOptions.Vanished is set.
+func (cmd *FetchWriter) WriteVanished(uids imap.UIDSet) error {
+	if len(uids) == 0 {
+		return nil
+	}
+	return cmd.conn.writeVanished(uids, true)
 }
 
 // FetchResponseWriter writes a single FETCH response for a message.
 type FetchResponseWriter struct {
-	enc     *responseEncoder
-	options fetchWriterOptions
+	enc       *responseEncoder
+	options   fetchWriterOptions
+	condStore bool
+	uidOnly   bool  // the UID is part of the UIDFETCH response
+	err       error // the response is discarded
 
 	hasItem bool
 }
@@ -349,7 +482,12 @@ func (w *FetchResponseWriter) writeItemSep() {
 }
 
 // WriteUID writes the message's UID.
+//
+// This is a no-op for UIDFETCH responses.
 func (w *FetchResponseWriter) WriteUID(uid imap.UID) {
+	if w.uidOnly {
+		return
+	}
 	w.writeItemSep()
 	w.enc.Atom("UID").SP().UID(uid)
 }
@@ -362,6 +500,51 @@ func (w *FetchResponseWriter) WriteFlags(flags []imap.Flag) {
 	})
 }
 
+// WriteModSeq writes the message's mod-sequence.
+//
+// This is a no-op if the client hasn't enabled CONDSTORE.
+func (w *FetchResponseWriter) WriteModSeq(modSeq uint64) {
+	if !w.condStore {
+		return
+	}
+	w.writeItemSep()
+	w.enc.Atom("MODSEQ").SP().Special('(').ModSeq(modSeq).Special(')')
+}
+
+// WritePreview writes the message's preview.
+//
+// A nil preview indicates that the preview isn't available, see
+// imap.FetchItemPreview.Lazy.
+func (w *FetchResponseWriter) WritePreview(preview *string) {
+	w.writeItemSep()
+	w.enc.Atom("PREVIEW").SP()
+	if preview != nil {
+		w.enc.String(*preview)
+	} else {
+		w.enc.NIL()
+	}
+}
+
+// WriteEmailID writes the message's email ID.
+func (w *FetchResponseWriter) WriteEmailID(id string) {
+	w.writeItemSep()
+	w.enc.Atom("EMAILID").SP().Special('(').Atom(id).Special(')')
+}
+
+// WriteThreadID writes the message's thread ID.
+//
+// An empty ID indicates that the server doesn't support thread IDs for this
+// message.
+func (w *FetchResponseWriter) WriteThreadID(id string) {
+	w.writeItemSep()
+	w.enc.Atom("THREADID").SP()
+	if id != "" {
+		w.enc.Special('(').Atom(id).Special(')')
+	} else {
+		w.enc.NIL()
+	}
+}
+
 // WriteRFC822Size writes the message's full size.
 func (w *FetchResponseWriter) WriteRFC822Size(size int64) {
 	w.writeItemSep()
@@ -374,6 +557,19 @@ func (w *FetchResponseWriter) WriteInternalDate(t time.Time) {
 	w.enc.Atom("INTERNALDATE").SP().String(t.Format(internal.DateTimeLayout))
 }
 
+// WriteSaveDate writes the date the message was saved to the mailbox.
+//
+// A zero time indicates that the save date isn't available.
+func (w *FetchResponseWriter) WriteSaveDate(t time.Time) {
+	w.writeItemSep()
+	w.enc.Atom("SAVEDATE").SP()
+	if !t.IsZero() {
+		w.enc.String(t.Format(internal.DateTimeLayout))
+	} else {
+		w.enc.NIL()
+	}
+}
+
 // WriteBodySection writes a body section.
 //
 // The returned io.WriteCloser must be closed before writing any more message
@@ -496,6 +692,10 @@ func (w *FetchResponseWriter) Close() error {
 	if w.enc == nil {
 		return fmt.Errorf("imapserver: FetchResponseWriter already closed")
 	}
+	if w.err != nil {
+		w.enc = nil
+		return w.err
+	}
 	err := w.enc.Special(')').CRLF()
 	w.enc.end()
 	w.enc = nil
@@ -0,0 +1,115 @@
+package imapserver
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/internal/imapwire"
+)
+
+// maxFilterDepth is the maximum number of nested FILTER search keys.
+const maxFilterDepth = 8
+
+// expandSearchFilters replaces FILTER search keys with the search programs
+// stored in METADATA.
+func (c *Conn) expandSearchFilters(criteria *imap.SearchCriteria) error {
+	return c.expandSearchFiltersDepth(criteria, 0)
+}
+
+func (c *Conn) expandSearchFiltersDepth(criteria *imap.SearchCriteria, depth int) error {
+	for i := range criteria.Not {
+		if err := c.expandSearchFiltersDepth(&criteria.Not[i], depth); err != nil {
+			return err
+		}
+	}
+	for i := range criteria.Or {
+		for j := range criteria.Or[i] {
+			if err := c.expandSearchFiltersDepth(&criteria.Or[i][j], depth); err != nil {
+				return err
+			}
+		}
+	}
+	for i := range criteria.Fuzzy {
+		if err := c.expandSearchFiltersDepth(&criteria.Fuzzy[i], depth); err != nil {
+			return err
+		}
+	}
+
+	if len(criteria.Filter) == 0 {
+		return nil
+	} else if !c.server.options.caps().Has(imap.CapFilters) {
+		return newClientBugError("FILTERS is not supported")
+	} else if depth >= maxFilterDepth {
+		return &imap.Error{
+			Type: imap.StatusResponseTypeNo,
+			Code: imap.ResponseCodeLimit,
+			Text: "Too many nested filters",
+		}
+	}
+
+	names := criteria.Filter
+	criteria.Filter = nil
+	for _, name := range names {
+		program, err := c.lookupFilter(name)
+		if err != nil {
+			return err
+		}
+
+		var filterCriteria imap.SearchCriteria
+		if err := parseSearchProgram(&filterCriteria, program); err != nil {
+			return &imap.Error{
+				Type: imap.StatusResponseTypeNo,
+				Code: imap.ResponseCodeUndefinedFilter,
+				Text: fmt.Sprintf("Invalid search program in filter %q: %v", name, err),
+			}
+		}
+		if err := c.expandSearchFiltersDepth(&filterCriteria, depth+1); err != nil {
+			return err
+		}
+		criteria.And(&filterCriteria)
+	}
+	return nil
+}
+
+// lookupFilter returns the search program of a filter. Private filters take
+// precedence over shared ones.
+func (c *Conn) lookupFilter(name string) (string, error) {
+	session, ok := c.session.(SessionMetadata)
+	if !ok {
+		return "", newClientBugError("FILTER requires METADATA")
+	}
+
+	entries := []string{
+		"/private/filters/values/" + name,
+		"/shared/filters/values/" + name,
+	}
+	data, err := session.GetMetadata("", entries, &imap.GetMetadataOptions{})
+	if err != nil {
+		return "", err
+	}
+	for _, entry := range entries {
+		if v := data.Entries[entry]; v != nil {
+			return string(*v), nil
+		}
+	}
+
+	return "", &imap.Error{
+		Type: imap.StatusResponseTypeNo,
+		Code: imap.ResponseCodeUndefinedFilter,
+		Text: fmt.Sprintf("Undefined filter %q", name),
+	}
+}
+
+func parseSearchProgram(criteria *imap.SearchCriteria, program string) error {
+	br := bufio.NewReader(strings.NewReader(program + "\r\n"))
+	dec := imapwire.NewDecoder(br, imapwire.ConnSideServer)
+	if err := readSearchCriteria(criteria, dec, ""); err != nil {
+		return err
+	}
+	if !dec.ExpectCRLF() {
+		return dec.Err()
+	}
+	return nil
+}
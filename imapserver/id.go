@@ -0,0 +1,74 @@
+package imapserver
+
+import (
+	"sort"
+
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/internal/imapwire"
+)
+
+func (c *Conn) handleID(dec *imapwire.Decoder) error {
+	if !dec.ExpectSP() {
+		return dec.Err()
+	}
+
+	var clientID map[string]string
+	isList, err := dec.List(func() error {
+		var key, value string
+		if !dec.ExpectString(&key) || !dec.ExpectSP() || !dec.ExpectNString(&value) {
+			return dec.Err()
+		}
+		if clientID == nil {
+			clientID = make(map[string]string)
+		}
+		clientID[key] = value
+		return nil
+	})
+	if err != nil {
+		return err
+	} else if !isList && !dec.ExpectNIL() {
+		return dec.Err()
+	}
+
+	if !dec.ExpectCRLF() {
+		return dec.Err()
+	}
+
+	if !c.server.options.caps().Has(imap.CapID) {
+		return newClientBugError("ID is not supported")
+	}
+
+	var serverID map[string]string
+	if session, ok := c.session.(SessionID); ok {
+		serverID, err = session.ID(clientID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return c.writeID(serverID)
+}
+
+func (c *Conn) writeID(data map[string]string) error {
+	enc := newResponseEncoder(c)
+	defer enc.end()
+
+	enc.Atom("*").SP().Atom("ID").SP()
+	if len(data) == 0 {
+		enc.NIL()
+		return enc.CRLF()
+	}
+
+	var keys []string
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	listEnc := enc.BeginList()
+	for _, k := range keys {
+		listEnc.Item().String(k).SP().String(data[k])
+	}
+	listEnc.End()
+	return enc.CRLF()
+}
@@ -0,0 +1,201 @@
+package imapmemserver
+
+import (
+	"strings"
+
+	"github.com/emersion/go-imap/v2"
+)
+
+var errNoPerm = &imap.Error{
+	Type: imap.StatusResponseTypeNo,
+	Code: imap.ResponseCodeNoPerm,
+	Text: "Permission denied",
+}
+
+// rightsLocked returns the rights of a user on the mailbox.
+//
+// Mailboxes without an ACL grant all rights to everyone.
+func (mbox *Mailbox) rightsLocked(username string) imap.RightSet {
+	if mbox.acl == nil {
+		return imap.RightSetAll
+	}
+	rights := mbox.acl[imap.RightsIdentifier(username)]
+	return rights.Add(mbox.acl[imap.RightsIdentifierAnyone])
+}
+
+func (mbox *Mailbox) rights(username string) imap.RightSet {
+	mbox.mutex.Lock()
+	defer mbox.mutex.Unlock()
+	return mbox.rightsLocked(username)
+}
+
+// checkRights returns an error if the user is missing one of the rights.
+func (mbox *Mailbox) checkRights(username string, rights ...imap.Right) error {
+	have := mbox.rights(username)
+	for _, right := range rights {
+		if !have.Has(right) {
+			return errNoPerm
+		}
+	}
+	return nil
+}
+
+// adminMailbox returns a mailbox on which the user has the administer right.
+func (u *User) adminMailbox(name string) (*Mailbox, error) {
+	mbox, err := u.mailbox(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := mbox.checkRights(u.username, imap.RightAdminister); err != nil {
+		return nil, err
+	}
+	return mbox, nil
+}
+
+func (u *User) MyRights(mailbox string) (*imap.MyRightsData, error) {
+	mbox, err := u.mailbox(mailbox)
+	if err != nil {
+		return nil, err
+	}
+	return &imap.MyRightsData{
+		Mailbox: mailbox,
+		Rights:  mbox.rights(u.username),
+	}, nil
+}
+
+func (u *User) GetACL(mailbox string) (*imap.GetACLData, error) {
+	mbox, err := u.adminMailbox(mailbox)
+	if err != nil {
+		return nil, err
+	}
+
+	mbox.mutex.Lock()
+	defer mbox.mutex.Unlock()
+
+	data := imap.GetACLData{
+		Mailbox: mailbox,
+		Rights:  make(map[imap.RightsIdentifier]imap.RightSet),
+	}
+	if mbox.acl == nil {
+		data.Rights[imap.RightsIdentifierAnyone] = imap.RightSetAll
+	}
+	for ri, rights := range mbox.acl {
+		data.Rights[ri] = rights
+	}
+	return &data, nil
+}
+
+func (u *User) SetACL(mailbox string, ri imap.RightsIdentifier, rm imap.RightModification, rs imap.RightSet) error {
+	if err := checkRightsIdentifier(ri); err != nil {
+		return err
+	}
+	for _, right := range rs {
+		if !imap.RightSetAll.Has(right) {
+			return &imap.Error{
+				Type: imap.StatusResponseTypeBad,
+				Text: "Unsupported right",
+			}
+		}
+	}
+
+	mbox, err := u.adminMailbox(mailbox)
+	if err != nil {
+		return err
+	}
+
+	mbox.mutex.Lock()
+	defer mbox.mutex.Unlock()
+
+	mbox.initACLLocked(u.username)
+	switch rm {
+	case imap.RightModificationAdd:
+		rs = mbox.acl[ri].Add(rs)
+	case imap.RightModificationRemove:
+		rs = mbox.acl[ri].Remove(rs)
+	}
+	if len(rs) == 0 {
+		delete(mbox.acl, ri)
+	} else {
+		mbox.acl[ri] = rs
+	}
+	return nil
+}
+
+func (u *User) DeleteACL(mailbox string, ri imap.RightsIdentifier) error {
+	if err := checkRightsIdentifier(ri); err != nil {
+		return err
+	}
+
+	mbox, err := u.adminMailbox(mailbox)
+	if err != nil {
+		return err
+	}
+
+	mbox.mutex.Lock()
+	defer mbox.mutex.Unlock()
+
+	mbox.initACLLocked(u.username)
+	delete(mbox.acl, ri)
+	return nil
+}
+
+func (u *User) ListRights(mailbox string, ri imap.RightsIdentifier) (*imap.ListRightsData, error) {
+	if err := checkRightsIdentifier(ri); err != nil {
+		return nil, err
+	}
+	if _, err := u.adminMailbox(mailbox); err != nil {
+		return nil, err
+	}
+
+	// All rights can be granted independently
+	data := imap.ListRightsData{
+		Mailbox:        mailbox,
+		Identifier:     ri,
+		RequiredRights: imap.RightSet{},
+	}
+	for _, right := range imap.RightSetAll {
+		data.OptionalRights = append(data.OptionalRights, imap.RightSet{right})
+	}
+	return &data, nil
+}
+
+// initACLLocked replaces the implicit ACL of a mailbox which doesn't have
+// one, so that the user doesn't lose access when the ACL is first modified.
+func (mbox *Mailbox) initACLLocked(username string) {
+	if mbox.acl == nil {
+		mbox.acl = map[imap.RightsIdentifier]imap.RightSet{
+			imap.RightsIdentifier(username): imap.RightSetAll,
+		}
+	}
+}
+
+func checkRightsIdentifier(ri imap.RightsIdentifier) error {
+	if strings.HasPrefix(string(ri), "-") {
+		return &imap.Error{
+			Type: imap.StatusResponseTypeNo,
+			Text: "Negative rights are not supported",
+		}
+	}
+	return nil
+}
+
+// storeRights returns the rights needed to alter flags with STORE.
+func storeRights(flags *imap.StoreFlags) []imap.Right {
+	if flags.Op == imap.StoreFlagsSet {
+		// Replacing flags may alter any of them
+		return []imap.Right{imap.RightSeen, imap.RightDeleteMessages, imap.RightWrite}
+	}
+
+	var rights []imap.Right
+	for _, flag := range flags.Flags {
+		switch canonicalFlag(flag) {
+		case canonicalFlag(imap.FlagSeen):
+			rights = append(rights, imap.RightSeen)
+		case canonicalFlag(imap.FlagDeleted):
+			rights = append(rights, imap.RightDeleteMessages)
+		default:
+			rights = append(rights, imap.RightWrite)
+		}
+	}
+	return rights
+}
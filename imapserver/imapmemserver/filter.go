@@ -0,0 +1,43 @@
+package imapmemserver
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SetFilter defines a named search filter for the FILTERS extension.
+//
+// The search program is stored in the /private/filters/values/<name> server
+// metadata entry, or in /shared/filters/values/<name> if shared is true. Shared
+// filters are visible to all users of the server. An empty program deletes the
+// filter.
+func (u *User) SetFilter(name, program string, shared bool) error {
+	if name == "" || strings.ContainsAny(name, "/%* ") {
+		return fmt.Errorf("imapmemserver: invalid filter name %q", name)
+	}
+
+	var value *[]byte
+	if program != "" {
+		b := []byte(program)
+		value = &b
+	}
+
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	if shared {
+		u.serverMetadata.set("/shared/filters/values/"+name, value)
+		return nil
+	}
+
+	if u.metadata == nil {
+		u.metadata = make(map[string]map[string][]byte)
+	}
+	m := u.metadata[""]
+	if m == nil {
+		m = make(map[string][]byte)
+		u.metadata[""] = m
+	}
+	setMetadataEntry(m, "/private/filters/values/"+name, value)
+	return nil
+}
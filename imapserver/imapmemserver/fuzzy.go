@@ -0,0 +1,282 @@
+package imapmemserver
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/emersion/go-imap/v2"
+	gomessage "github.com/emersion/go-message"
+	"github.com/emersion/go-message/mail"
+	"github.com/emersion/go-message/textproto"
+)
+
+// fuzzyThreshold is the minimum similarity score for a fuzzy text query to
+// match.
+const fuzzyThreshold = 0.75
+
+// fuzzySearch checks whether a message approximately matches a criteria.
+//
+// Text queries (TEXT, BODY and HEADER values) are matched approximately, the
+// rest of the criteria is matched exactly. The returned score is between 0
+// and 1.
+func (msg *message) fuzzySearch(seqNum uint32, criteria *imap.SearchCriteria) (score float64, ok bool) {
+	exact := *criteria
+	exact.Text = nil
+	exact.Body = nil
+	exact.Header = nil
+	var queries []fuzzyQuery
+	for _, s := range criteria.Text {
+		queries = append(queries, fuzzyQuery{kind: fuzzyQueryText, value: s})
+	}
+	for _, s := range criteria.Body {
+		queries = append(queries, fuzzyQuery{kind: fuzzyQueryBody, value: s})
+	}
+	for _, fieldCriteria := range criteria.Header {
+		if fieldCriteria.Value == "" {
+			exact.Header = append(exact.Header, fieldCriteria)
+			continue
+		}
+		queries = append(queries, fuzzyQuery{
+			kind:  fuzzyQueryHeader,
+			key:   fieldCriteria.Key,
+			value: fieldCriteria.Value,
+		})
+	}
+
+	if !msg.search(seqNum, &exact) {
+		return 0, false
+	}
+	if len(queries) == 0 {
+		return 1, true
+	}
+
+	br := bufio.NewReader(bytes.NewReader(msg.buf))
+	rawHeader, _ := textproto.ReadHeader(br)
+	header := mail.Header{gomessage.Header{rawHeader}}
+
+	// Message tokens are only computed once per kind of query
+	var textTokens, bodyTokens map[string]struct{}
+	var total float64
+	for _, q := range queries {
+		var tokens map[string]struct{}
+		switch q.kind {
+		case fuzzyQueryText, fuzzyQueryBody:
+			if bodyTokens == nil {
+				bodyTokens = fuzzyTokenSet(msg.fuzzyBody())
+			}
+			tokens = bodyTokens
+			if q.kind == fuzzyQueryText {
+				if textTokens == nil {
+					textTokens = make(map[string]struct{}, len(bodyTokens))
+					for tok := range bodyTokens {
+						textTokens[tok] = struct{}{}
+					}
+					for fields := rawHeader.Fields(); fields.Next(); {
+						addFuzzyTokens(textTokens, fields.Key()+" "+fields.Value())
+					}
+				}
+				tokens = textTokens
+			}
+		case fuzzyQueryHeader:
+			tokens = fuzzyTokenSet(strings.Join(header.Values(q.key), " "))
+		}
+		s := fuzzyMatch(tokens, fuzzyTokens(q.value))
+		if s < fuzzyThreshold {
+			return 0, false
+		}
+		total += s
+	}
+	return total / float64(len(queries)), true
+}
+
+// fuzzyBody returns the decoded contents of the message's text parts.
+// Attachments and other non-text parts are skipped, so that their encoded
+// contents don't produce tokens.
+func (msg *message) fuzzyBody() string {
+	entity, err := gomessage.Read(bytes.NewReader(msg.buf))
+	if entity == nil {
+		return ""
+	} else if err != nil && !gomessage.IsUnknownCharset(err) && !gomessage.IsUnknownEncoding(err) {
+		return ""
+	}
+
+	var sb strings.Builder
+	entity.Walk(func(path []int, part *gomessage.Entity, err error) error {
+		if err != nil {
+			return nil // skip parts we can't decode
+		}
+		mediaType, _, _ := part.Header.ContentType()
+		if !part.Header.Has("Content-Type") {
+			mediaType = "text/plain"
+		}
+		if mediaType != "text/plain" && mediaType != "text/html" {
+			return nil
+		}
+		if disp, _, _ := part.Header.ContentDisposition(); strings.EqualFold(disp, "attachment") {
+			return nil
+		}
+
+		b, err := io.ReadAll(part.Body)
+		if err != nil {
+			return nil
+		}
+		text := string(b)
+		if mediaType == "text/html" {
+			text = stripHTML(text)
+		}
+		sb.WriteString(text)
+		sb.WriteByte(' ')
+		return nil
+	})
+	return sb.String()
+}
+
+// searchRelevancy is like search, but also returns a relevancy score between 1
+// and 100 computed from the fuzzy criteria.
+func (msg *message) searchRelevancy(seqNum uint32, criteria *imap.SearchCriteria) (relevancy uint8, ok bool) {
+	exact := *criteria
+	exact.Fuzzy = nil
+	if !msg.search(seqNum, &exact) {
+		return 0, false
+	}
+	if len(criteria.Fuzzy) == 0 {
+		return 100, true
+	}
+
+	var total float64
+	for i := range criteria.Fuzzy {
+		score, ok := msg.fuzzySearch(seqNum, &criteria.Fuzzy[i])
+		if !ok {
+			return 0, false
+		}
+		total += score
+	}
+	relevancy = uint8(100 * total / float64(len(criteria.Fuzzy)))
+	if relevancy == 0 {
+		relevancy = 1
+	}
+	return relevancy, true
+}
+
+type fuzzyQueryKind int
+
+const (
+	fuzzyQueryText fuzzyQueryKind = iota
+	fuzzyQueryBody
+	fuzzyQueryHeader
+)
+
+type fuzzyQuery struct {
+	kind  fuzzyQueryKind
+	key   string
+	value string
+}
+
+// fuzzyMaxTokenLen is the maximum length of a token, in bytes. Longer tokens
+// are ignored: they are unlikely to be words, and comparing them is costly.
+const fuzzyMaxTokenLen = 64
+
+func fuzzyTokens(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// fuzzyTokenSet returns the set of distinct tokens of a text.
+func fuzzyTokenSet(s string) map[string]struct{} {
+	set := make(map[string]struct{})
+	addFuzzyTokens(set, s)
+	return set
+}
+
+func addFuzzyTokens(set map[string]struct{}, s string) {
+	for _, tok := range fuzzyTokens(s) {
+		if len(tok) <= fuzzyMaxTokenLen {
+			set[tok] = struct{}{}
+		}
+	}
+}
+
+// fuzzyMatch returns the average similarity of each query token with its
+// closest text token.
+func fuzzyMatch(text map[string]struct{}, query []string) float64 {
+	if len(query) == 0 {
+		return 1
+	}
+	var total float64
+	for _, q := range query {
+		if _, ok := text[q]; ok {
+			total++
+			continue
+		}
+		var best float64
+		for t := range text {
+			// The length difference bounds the similarity, skip tokens which
+			// can't improve the best match
+			if similarityBound(q, t) <= best {
+				continue
+			}
+			if s := similarity(q, t); s > best {
+				best = s
+			}
+		}
+		total += best
+	}
+	return total / float64(len(query))
+}
+
+// similarityBound returns an upper bound of similarity(a, b).
+func similarityBound(a, b string) float64 {
+	la, lb := utf8.RuneCountInString(a), utf8.RuneCountInString(b)
+	if la < lb {
+		la, lb = lb, la
+	}
+	if la == 0 {
+		return 1
+	}
+	return float64(lb) / float64(la)
+}
+
+// similarity returns a score between 0 and 1 based on the Levenshtein
+// distance between two strings.
+func similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	n := len(ra)
+	if len(rb) > n {
+		n = len(rb)
+	}
+	if n == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(n)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = prev[j-1] + cost
+			if prev[j]+1 < cur[j] {
+				cur[j] = prev[j] + 1
+			}
+			if cur[j-1]+1 < cur[j] {
+				cur[j] = cur[j-1] + 1
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -1,7 +1,6 @@
 package imapmemserver
 
 import (
-	"bytes"
 	"sort"
 	"sync"
 	"time"
@@ -17,31 +16,44 @@ import (
 type Mailbox struct {
 	tracker     *imapserver.MailboxTracker
 	uidValidity uint32
-
-	mutex      sync.Mutex
-	name       string
-	subscribed bool
-	l          []*message
-	uidNext    imap.UID
+	id          string // OBJECTID, preserved across renames
+
+	mutex         sync.Mutex
+	name          string
+	subscribed    bool
+	specialUse    []imap.MailboxAttr
+	l             []*message
+	uidNext       imap.UID
+	highestModSeq uint64
+	metadata      map[string][]byte // shared entries
+	acl           map[imap.RightsIdentifier]imap.RightSet
+	userTrackers  map[*userTracker]struct{}
+	accessKey     []byte // URLAUTH
+	owner         *User  // quota root, may be nil
 }
 
 // NewMailbox creates a new mailbox.
 func NewMailbox(name string, uidValidity uint32) *Mailbox {
 	return &Mailbox{
-		tracker:     imapserver.NewMailboxTracker(0),
-		uidValidity: uidValidity,
-		name:        name,
-		uidNext:     1,
+		tracker:       imapserver.NewMailboxTracker(0),
+		uidValidity:   uidValidity,
+		id:            newObjectID("F"),
+		name:          name,
+		uidNext:       1,
+		highestModSeq: 1,
 	}
 }
 
-func (mbox *Mailbox) list(options *imap.ListOptions) *imap.ListData {
+func (mbox *Mailbox) list(username string, options *imap.ListOptions) *imap.ListData {
 	mbox.mutex.Lock()
 	defer mbox.mutex.Unlock()
 
 	if options.SelectSubscribed && !mbox.subscribed {
 		return nil
 	}
+	if options.SelectSpecialUse && len(mbox.specialUse) == 0 {
+		return nil
+	}
 
 	data := imap.ListData{
 		Mailbox: mbox.name,
@@ -50,7 +62,11 @@ func (mbox *Mailbox) list(options *imap.ListOptions) *imap.ListData {
 	if mbox.subscribed {
 		data.Attrs = append(data.Attrs, imap.MailboxAttrSubscribed)
 	}
-	if options.ReturnStatus != nil {
+	// Special-use attributes are cheap to compute, so they're always
+	// returned regardless of ReturnSpecialUse, as allowed by RFC 6154
+	data.Attrs = append(data.Attrs, mbox.specialUse...)
+	// STATUS requires the read right
+	if options.ReturnStatus != nil && mbox.rightsLocked(username).Has(imap.RightRead) {
 		data.Status = mbox.statusDataLocked(options.ReturnStatus)
 	}
 	return &data
@@ -87,6 +103,16 @@ func (mbox *Mailbox) statusDataLocked(options *imap.StatusOptions) *imap.StatusD
 		size := mbox.sizeLocked()
 		data.Size = &size
 	}
+	if options.DeletedStorage {
+		storage := quotaStorage(mbox.deletedSizeLocked())
+		data.DeletedStorage = &storage
+	}
+	if options.HighestModSeq {
+		data.HighestModSeq = mbox.highestModSeq
+	}
+	if options.MailboxID {
+		data.MailboxID = mbox.id
+	}
 	return &data
 }
 
@@ -100,33 +126,51 @@ func (mbox *Mailbox) countByFlagLocked(flag imap.Flag) uint32 {
 	return n
 }
 
-func (mbox *Mailbox) sizeLocked() int64 {
+func (mbox *Mailbox) deletedSizeLocked() int64 {
 	var size int64
 	for _, msg := range mbox.l {
-		size += int64(len(msg.buf))
+		if _, ok := msg.flags[canonicalFlag(imap.FlagDeleted)]; ok {
+			size += int64(len(msg.buf))
+		}
 	}
 	return size
 }
 
-func (mbox *Mailbox) appendLiteral(r imap.LiteralReader, options *imap.AppendOptions) (*imap.AppendData, error) {
-	var buf bytes.Buffer
-	if _, err := buf.ReadFrom(r); err != nil {
-		return nil, err
+func (mbox *Mailbox) sizeLocked() int64 {
+	var size int64
+	for _, msg := range mbox.l {
+		size += int64(len(msg.buf))
 	}
-	return mbox.appendBytes(buf.Bytes(), options), nil
+	return size
 }
 
 func (mbox *Mailbox) copyMsg(msg *message) *imap.AppendData {
-	return mbox.appendBytes(msg.buf, &imap.AppendOptions{
+	mbox.mutex.Lock()
+	defer mbox.mutex.Unlock()
+
+	// Copies keep the same email ID
+	return mbox.appendLocked(msg.buf, &imap.AppendOptions{
 		Time:  msg.t,
 		Flags: msg.flagList(),
-	})
+	}, msg.emailID)
 }
 
 func (mbox *Mailbox) appendBytes(buf []byte, options *imap.AppendOptions) *imap.AppendData {
+	mbox.mutex.Lock()
+	defer mbox.mutex.Unlock()
+	return mbox.appendBytesLocked(buf, options)
+}
+
+func (mbox *Mailbox) appendBytesLocked(buf []byte, options *imap.AppendOptions) *imap.AppendData {
+	return mbox.appendLocked(buf, options, newObjectID("M"))
+}
+
+func (mbox *Mailbox) appendLocked(buf []byte, options *imap.AppendOptions, emailID string) *imap.AppendData {
 	msg := &message{
-		flags: make(map[imap.Flag]struct{}),
-		buf:   buf,
+		flags:    make(map[imap.Flag]struct{}),
+		buf:      buf,
+		emailID:  emailID,
+		saveDate: time.Now(),
 	}
 
 	if options.Time.IsZero() {
@@ -139,14 +183,13 @@ func (mbox *Mailbox) appendBytes(buf []byte, options *imap.AppendOptions) *imap.
 		msg.flags[canonicalFlag(flag)] = struct{}{}
 	}
 
-	mbox.mutex.Lock()
-	defer mbox.mutex.Unlock()
-
 	msg.uid = mbox.uidNext
 	mbox.uidNext++
+	msg.modSeq = mbox.nextModSeqLocked()
 
 	mbox.l = append(mbox.l, msg)
 	mbox.tracker.QueueNumMessages(uint32(len(mbox.l)))
+	mbox.notifyLocked(imap.NotifyEventMessageNew)
 
 	return &imap.AppendData{
 		UIDValidity: mbox.uidValidity,
@@ -154,12 +197,37 @@ func (mbox *Mailbox) appendBytes(buf []byte, options *imap.AppendOptions) *imap.
 	}
 }
 
+// nextModSeqLocked increments and returns the mailbox's highest
+// mod-sequence.
+func (mbox *Mailbox) nextModSeqLocked() uint64 {
+	mbox.highestModSeq++
+	return mbox.highestModSeq
+}
+
 func (mbox *Mailbox) rename(newName string) {
 	mbox.mutex.Lock()
 	mbox.name = newName
 	mbox.mutex.Unlock()
 }
 
+// SetSpecialUse changes the special-use attributes of this mailbox.
+func (mbox *Mailbox) SetSpecialUse(attrs []imap.MailboxAttr) {
+	mbox.mutex.Lock()
+	mbox.specialUse = attrs
+	mbox.mutex.Unlock()
+}
+
+func (mbox *Mailbox) hasSpecialUse(attr imap.MailboxAttr) bool {
+	mbox.mutex.Lock()
+	defer mbox.mutex.Unlock()
+	for _, a := range mbox.specialUse {
+		if a == attr {
+			return true
+		}
+	}
+	return false
+}
+
 // SetSubscribed changes the subscription state of this mailbox.
 func (mbox *Mailbox) SetSubscribed(subscribed bool) {
 	mbox.mutex.Lock()
@@ -180,6 +248,8 @@ func (mbox *Mailbox) selectDataLocked() *imap.SelectData {
 		NumMessages:    uint32(len(mbox.l)),
 		UIDNext:        mbox.uidNext,
 		UIDValidity:    mbox.uidValidity,
+		HighestModSeq:  mbox.highestModSeq,
+		MailboxID:      mbox.id,
 	}
 }
 
@@ -227,9 +297,14 @@ func (mbox *Mailbox) Expunge(w *imapserver.ExpungeWriter, uids *imap.UIDSet) err
 	return nil
 }
 
-func (mbox *Mailbox) expungeLocked(expunged map[*message]struct{}) (seqNums []uint32) {
+func (mbox *Mailbox) expungeLocked(expunged map[*message]struct{}) (seqNums []uint32, uids []imap.UID) {
 	// TODO: optimize
 
+	if len(expunged) == 0 {
+		return nil, nil
+	}
+	modSeq := mbox.nextModSeqLocked()
+
 	// Iterate in reverse order, to keep sequence numbers consistent
 	var filtered []*message
 	for i := len(mbox.l) - 1; i >= 0; i-- {
@@ -237,7 +312,8 @@ func (mbox *Mailbox) expungeLocked(expunged map[*message]struct{}) (seqNums []ui
 		if _, ok := expunged[msg]; ok {
 			seqNum := uint32(i) + 1
 			seqNums = append(seqNums, seqNum)
-			mbox.tracker.QueueExpunge(seqNum)
+			uids = append(uids, msg.uid)
+			mbox.tracker.QueueExpungeUID(seqNum, msg.uid, modSeq)
 		} else {
 			filtered = append(filtered, msg)
 		}
@@ -250,8 +326,9 @@ func (mbox *Mailbox) expungeLocked(expunged map[*message]struct{}) (seqNums []ui
 	}
 
 	mbox.l = filtered
+	mbox.notifyLocked(imap.NotifyEventMessageExpunge)
 
-	return seqNums
+	return seqNums, uids
 }
 
 // NewView creates a new view into this mailbox.
@@ -292,23 +369,85 @@ func (mbox *MailboxView) Fetch(w *imapserver.FetchWriter, numSet imap.NumSet, op
 		}
 	}
 
-	var err error
-	mbox.forEach(numSet, func(seqNum uint32, msg *message) {
-		if err != nil {
+	if options.Vanished {
+		if err := mbox.fetchVanished(w, numSet, options.ChangedSince); err != nil {
+			return err
+		}
+	}
+
+	type fetchMsg struct {
+		seqNum uint32
+		msg    *message
+	}
+
+	var (
+		err          error
+		flagsChanged bool
+		msgs         []fetchMsg
+	)
+	mbox.mutex.Lock()
+	mbox.forEachLocked(numSet, func(seqNum uint32, msg *message) {
+		if options.ChangedSince > 0 && msg.modSeq <= options.ChangedSince {
 			return
 		}
+		msgs = append(msgs, fetchMsg{seqNum: seqNum, msg: msg})
+	})
+	if options.Partial != nil {
+		start, end := partialBounds(*options.Partial, len(msgs))
+		msgs = msgs[start:end]
+	}
+	for _, m := range msgs {
+		seqNum, msg := m.seqNum, m.msg
 
-		if markSeen {
+		if _, ok := msg.flags[canonicalFlag(imap.FlagSeen)]; markSeen && !ok {
 			msg.flags[canonicalFlag(imap.FlagSeen)] = struct{}{}
-			mbox.Mailbox.tracker.QueueMessageFlags(seqNum, msg.uid, msg.flagList(), nil)
+			msg.modSeq = mbox.nextModSeqLocked()
+			mbox.Mailbox.tracker.QueueMessageFlagsModSeq(seqNum, msg.uid, msg.flagList(), msg.modSeq, nil)
+			flagsChanged = true
 		}
 
-		respWriter := w.CreateMessage(mbox.tracker.EncodeSeqNum(seqNum))
-		err = msg.fetch(respWriter, options)
-	})
+		respWriter := w.CreateMessageUID(mbox.tracker.EncodeSeqNum(seqNum), msg.uid)
+		if err = msg.fetch(respWriter, options); err != nil {
+			break
+		}
+	}
+	mbox.mutex.Unlock()
+	if flagsChanged {
+		mbox.notify(imap.NotifyEventFlagChange)
+	}
 	return err
 }
 
+func (mbox *MailboxView) fetchVanished(w *imapserver.FetchWriter, numSet imap.NumSet, modSeq uint64) error {
+	mbox.mutex.Lock()
+	defer mbox.mutex.Unlock()
+
+	uidSet, _ := mbox.staticNumSet(numSet).(imap.UIDSet)
+
+	var vanished imap.UIDSet
+	if expunged, ok := mbox.Mailbox.tracker.ExpungedSince(modSeq); ok {
+		uids, _ := expunged.Nums()
+		for _, uid := range uids {
+			if uidSet.Contains(uid) {
+				vanished.AddNum(uid)
+			}
+		}
+	} else {
+		// The expunge history is incomplete: report all UIDs which don't
+		// exist anymore
+		existing := make(map[imap.UID]struct{}, len(mbox.l))
+		for _, msg := range mbox.l {
+			existing[msg.uid] = struct{}{}
+		}
+		for uid := imap.UID(1); uid < mbox.uidNext; uid++ {
+			if _, ok := existing[uid]; !ok && uidSet.Contains(uid) {
+				vanished.AddNum(uid)
+			}
+		}
+	}
+	return w.WriteVanished(vanished)
+}
+
 func (mbox *MailboxView) Search(numKind imapserver.NumKind, criteria *imap.SearchCriteria, options *imap.SearchOptions) (*imap.SearchData, error) {
 	mbox.mutex.Lock()
 	defer mbox.mutex.Unlock()
@@ -317,14 +456,33 @@ func (mbox *MailboxView) Search(numKind imapserver.NumKind, criteria *imap.Searc
 
 	data := imap.SearchData{UID: numKind == imapserver.NumKindUID}
 
+	// When PARTIAL is the only result requested, stop as soon as the range is
+	// filled. Negative ranges are filled by walking the mailbox backwards.
+	partialOnly := options.ReturnPartial != nil && !options.ReturnMin && !options.ReturnMax &&
+		!options.ReturnAll && !options.ReturnCount && !options.ReturnSave && !options.ReturnRelevancy &&
+		criteria.ModSeq == nil
+	backwards := partialOnly && options.ReturnPartial.Offset < 0
+
 	var (
-		seqSet imap.SeqSet
-		uidSet imap.UIDSet
+		seqSet      imap.SeqSet
+		uidSet      imap.UIDSet
+		partialNums []uint32
 	)
-	for i, msg := range mbox.l {
+	for j := range mbox.l {
+		i := j
+		if backwards {
+			i = len(mbox.l) - 1 - j
+		}
+		msg := mbox.l[i]
 		seqNum := mbox.tracker.EncodeSeqNum(uint32(i) + 1)
 
-		if !msg.search(seqNum, criteria) {
+		var relevancy uint8
+		if options.ReturnRelevancy {
+			var ok bool
+			if relevancy, ok = msg.searchRelevancy(seqNum, criteria); !ok {
+				continue
+			}
+		} else if !msg.search(seqNum, criteria) {
 			continue
 		}
 
@@ -349,6 +507,21 @@ func (mbox *MailboxView) Search(numKind imapserver.NumKind, criteria *imap.Searc
 			data.Max = num
 		}
 		data.Count++
+
+		if options.ReturnRelevancy {
+			data.Relevancy = append(data.Relevancy, relevancy)
+		}
+
+		if criteria.ModSeq != nil && msg.modSeq > data.ModSeq {
+			data.ModSeq = msg.modSeq
+		}
+
+		if options.ReturnPartial != nil {
+			partialNums = append(partialNums, num)
+			if partialOnly && int64(len(partialNums)) >= partialLen(*options.ReturnPartial) {
+				break
+			}
+		}
 	}
 
 	switch numKind {
@@ -362,6 +535,28 @@ func (mbox *MailboxView) Search(numKind imapserver.NumKind, criteria *imap.Searc
 		mbox.searchRes = uidSet
 	}
 
+	if options.ReturnPartial != nil {
+		if backwards {
+			for i, j := 0, len(partialNums)-1; i < j; i, j = i+1, j-1 {
+				partialNums[i], partialNums[j] = partialNums[j], partialNums[i]
+			}
+		}
+		start, end := partialBounds(*options.ReturnPartial, len(partialNums))
+		partialNums = partialNums[start:end]
+
+		data.Partial = &imap.SearchPartialData{Range: *options.ReturnPartial}
+		switch numKind {
+		case imapserver.NumKindSeq:
+			data.Partial.All = imap.SeqSetNum(partialNums...)
+		case imapserver.NumKindUID:
+			uids := make([]imap.UID, len(partialNums))
+			for i, num := range partialNums {
+				uids[i] = imap.UID(num)
+			}
+			data.Partial.All = imap.UIDSetNum(uids...)
+		}
+	}
+
 	return &data, nil
 }
 
@@ -390,17 +585,63 @@ func (mbox *MailboxView) staticSearchCriteria(criteria *imap.SearchCriteria) {
 			mbox.staticSearchCriteria(&criteria.Or[i][j])
 		}
 	}
+	for i := range criteria.Fuzzy {
+		mbox.staticSearchCriteria(&criteria.Fuzzy[i])
+	}
 }
 
 func (mbox *MailboxView) Store(w *imapserver.FetchWriter, numSet imap.NumSet, flags *imap.StoreFlags, options *imap.StoreOptions) error {
+	_, err := mbox.store(w, numSet, flags, options, false)
+	return err
+}
+
+// StoreUnchangedSince is like Store, but leaves messages modified since
+// options.UnchangedSince untouched and returns them.
+func (mbox *MailboxView) StoreUnchangedSince(w *imapserver.FetchWriter, numSet imap.NumSet, flags *imap.StoreFlags, options *imap.StoreOptions) (imap.NumSet, error) {
+	return mbox.store(w, numSet, flags, options, true)
+}
+
+func (mbox *MailboxView) store(w *imapserver.FetchWriter, numSet imap.NumSet, flags *imap.StoreFlags, options *imap.StoreOptions, unchangedSince bool) (imap.NumSet, error) {
+	var (
+		modified, stored imap.SeqSet
+		modifiedUIDs     imap.UIDSet
+		storedUIDs       imap.UIDSet
+		flagsChanged     bool
+	)
 	mbox.forEach(numSet, func(seqNum uint32, msg *message) {
-		msg.store(flags)
-		mbox.Mailbox.tracker.QueueMessageFlags(seqNum, msg.uid, msg.flagList(), mbox.tracker)
+		if unchangedSince && msg.modSeq > options.UnchangedSince {
+			modified.AddNum(mbox.tracker.EncodeSeqNum(seqNum))
+			modifiedUIDs.AddNum(msg.uid)
+			return
+		}
+		stored.AddNum(mbox.tracker.EncodeSeqNum(seqNum))
+		storedUIDs.AddNum(msg.uid)
+
+		if msg.store(flags) {
+			msg.modSeq = mbox.nextModSeqLocked()
+			mbox.Mailbox.tracker.QueueMessageFlagsModSeq(seqNum, msg.uid, msg.flagList(), msg.modSeq, mbox.tracker)
+			flagsChanged = true
+		}
 	})
-	if !flags.Silent {
-		return mbox.Fetch(w, numSet, &imap.FetchOptions{Flags: true})
+	if flagsChanged {
+		mbox.notify(imap.NotifyEventFlagChange)
 	}
-	return nil
+
+	var modifiedSet, storedSet imap.NumSet = modified, stored
+	if _, ok := numSet.(imap.UIDSet); ok {
+		modifiedSet, storedSet = modifiedUIDs, storedUIDs
+	}
+
+	fetchOptions := imap.FetchOptions{
+		Flags:  !flags.Silent,
+		ModSeq: true,
+	}
+	if (fetchOptions.Flags || unchangedSince) && len(storedUIDs) > 0 {
+		if err := mbox.Fetch(w, storedSet, &fetchOptions); err != nil {
+			return nil, err
+		}
+	}
+	return modifiedSet, nil
 }
 
 func (mbox *MailboxView) Poll(w *imapserver.UpdateWriter, allowExpunge bool) error {
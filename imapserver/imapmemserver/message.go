@@ -18,12 +18,15 @@ import (
 
 type message struct {
 	// immutable
-	uid imap.UID
-	buf []byte
-	t   time.Time
+	uid      imap.UID
+	buf      []byte
+	t        time.Time
+	emailID  string
+	saveDate time.Time
 
 	// mutable, protected by Mailbox.mutex
-	flags map[imap.Flag]struct{}
+	flags  map[imap.Flag]struct{}
+	modSeq uint64
 }
 
 func (msg *message) fetch(w *imapserver.FetchResponseWriter, options *imap.FetchOptions) error {
@@ -32,6 +35,9 @@ func (msg *message) fetch(w *imapserver.FetchResponseWriter, options *imap.Fetch
 	if options.Flags {
 		w.WriteFlags(msg.flagList())
 	}
+	if options.ModSeq {
+		w.WriteModSeq(msg.modSeq)
+	}
 	if options.InternalDate {
 		w.WriteInternalDate(msg.t)
 	}
@@ -44,6 +50,20 @@ func (msg *message) fetch(w *imapserver.FetchResponseWriter, options *imap.Fetch
 	if bs := options.BodyStructure; bs != nil {
 		w.WriteBodyStructure(msg.bodyStructure(bs.Extended))
 	}
+	if options.SaveDate {
+		w.WriteSaveDate(msg.saveDate)
+	}
+	if options.EmailID {
+		w.WriteEmailID(msg.emailID)
+	}
+	if options.ThreadID {
+		w.WriteThreadID(msg.threadID())
+	}
+	if options.Preview != nil {
+		// Previews are cheap to generate here, so LAZY is ignored
+		preview := msg.preview()
+		w.WritePreview(&preview)
+	}
 
 	for _, bs := range options.BodySection {
 		buf := msg.bodySection(bs)
@@ -219,7 +239,14 @@ func (msg *message) flagList() []imap.Flag {
 	return flags
 }
 
-func (msg *message) store(store *imap.StoreFlags) {
+// store alters the message flags, and returns true if they have changed.
+func (msg *message) store(store *imap.StoreFlags) bool {
+	prev := msg.flags
+	msg.flags = make(map[imap.Flag]struct{}, len(prev))
+	for flag := range prev {
+		msg.flags[flag] = struct{}{}
+	}
+
 	switch store.Op {
 	case imap.StoreFlagsSet:
 		msg.flags = make(map[imap.Flag]struct{})
@@ -235,6 +262,16 @@ func (msg *message) store(store *imap.StoreFlags) {
 	default:
 		panic(fmt.Errorf("unknown STORE flag operation: %v", store.Op))
 	}
+
+	if len(prev) != len(msg.flags) {
+		return true
+	}
+	for flag := range msg.flags {
+		if _, ok := prev[flag]; !ok {
+			return true
+		}
+	}
+	return false
 }
 
 func (msg *message) search(seqNum uint32, criteria *imap.SearchCriteria) bool {
@@ -251,6 +288,17 @@ func (msg *message) search(seqNum uint32, criteria *imap.SearchCriteria) bool {
 	if !matchDate(msg.t, criteria.Since, criteria.Before) {
 		return false
 	}
+	if criteria.Older != 0 && time.Since(msg.t) < criteria.Older {
+		return false
+	}
+	if criteria.Younger != 0 && time.Since(msg.t) > criteria.Younger {
+		return false
+	}
+	// Save dates are always available, so SaveDateSupported matches all
+	// messages
+	if !matchDate(msg.saveDate, criteria.SavedSince, criteria.SavedBefore) {
+		return false
+	}
 
 	for _, flag := range criteria.Flag {
 		if _, ok := msg.flags[canonicalFlag(flag)]; !ok {
@@ -263,6 +311,24 @@ func (msg *message) search(seqNum uint32, criteria *imap.SearchCriteria) bool {
 		}
 	}
 
+	if criteria.ModSeq != nil && msg.modSeq < criteria.ModSeq.ModSeq {
+		return false
+	}
+
+	for _, id := range criteria.EmailID {
+		if id != msg.emailID {
+			return false
+		}
+	}
+	if len(criteria.ThreadID) > 0 {
+		threadID := msg.threadID()
+		for _, id := range criteria.ThreadID {
+			if id != threadID {
+				return false
+			}
+		}
+	}
+
 	if criteria.Larger != 0 && int64(len(msg.buf)) <= criteria.Larger {
 		return false
 	}
@@ -323,6 +389,11 @@ func (msg *message) search(seqNum uint32, criteria *imap.SearchCriteria) bool {
 			return false
 		}
 	}
+	for _, fuzzy := range criteria.Fuzzy {
+		if _, ok := msg.fuzzySearch(seqNum, &fuzzy); !ok {
+			return false
+		}
+	}
 
 	return true
 }
@@ -0,0 +1,186 @@
+package imapmemserver
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/imapserver"
+)
+
+// serverMetadata holds shared server entries.
+type serverMetadata struct {
+	mutex   sync.Mutex
+	entries map[string][]byte
+}
+
+func (md *serverMetadata) set(name string, value *[]byte) {
+	md.mutex.Lock()
+	defer md.mutex.Unlock()
+
+	if md.entries == nil {
+		md.entries = make(map[string][]byte)
+	}
+	setMetadataEntry(md.entries, name, value)
+}
+
+// GetMetadata returns metadata entries.
+//
+// Shared mailbox entries are stored in the mailbox, shared server entries are
+// stored in the server, other entries are stored in the user.
+func (u *User) GetMetadata(mailbox string, entries []string, options *imap.GetMetadataOptions) (*imap.GetMetadataData, error) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	all := make(map[string][]byte)
+	for name, value := range u.metadata[mailbox] {
+		all[name] = value
+	}
+	if mailbox == "" {
+		u.serverMetadata.mutex.Lock()
+		for name, value := range u.serverMetadata.entries {
+			all[name] = value
+		}
+		u.serverMetadata.mutex.Unlock()
+	} else {
+		mbox, err := u.mailboxLocked(mailbox)
+		if err != nil {
+			return nil, err
+		}
+		if err := mbox.checkRights(u.username, imap.RightLookup, imap.RightRead); err != nil {
+			return nil, err
+		}
+		mbox.mutex.Lock()
+		for name, value := range mbox.metadata {
+			all[name] = value
+		}
+		mbox.mutex.Unlock()
+	}
+
+	data := imap.GetMetadataData{
+		Mailbox: mailbox,
+		Entries: make(map[string]*[]byte),
+	}
+	for _, entry := range entries {
+		for name, value := range all {
+			if matchMetadataEntry(name, entry, options.Depth) {
+				b := append([]byte(nil), value...)
+				data.Entries[name] = &b
+			}
+		}
+		if _, ok := data.Entries[entry]; !ok {
+			data.Entries[entry] = nil
+		}
+	}
+	return &data, nil
+}
+
+func (sess *UserSession) SetMetadata(mailbox string, entries map[string]*[]byte) error {
+	u := sess.user
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	var mbox *Mailbox
+	if mailbox != "" {
+		var err error
+		mbox, err = u.mailboxLocked(mailbox)
+		if err != nil {
+			return err
+		}
+
+		// Shared mailbox entries additionally require the write right
+		rights := []imap.Right{imap.RightLookup, imap.RightRead}
+		for name := range entries {
+			if isSharedMetadataEntry(name) {
+				rights = append(rights, imap.RightWrite)
+				break
+			}
+		}
+		if err := mbox.checkRights(u.username, rights...); err != nil {
+			return err
+		}
+	} else {
+		// Shared server entries can only be changed via Server.SetMetadata
+		for name := range entries {
+			if isSharedMetadataEntry(name) {
+				return errNoPerm
+			}
+		}
+	}
+
+	shared := make(map[string]*[]byte)
+	for name, value := range entries {
+		if mbox != nil && isSharedMetadataEntry(name) {
+			shared[name] = value
+			continue
+		}
+
+		if u.metadata == nil {
+			u.metadata = make(map[string]map[string][]byte)
+		}
+		m := u.metadata[mailbox]
+		if m == nil {
+			m = make(map[string][]byte)
+			u.metadata[mailbox] = m
+		}
+		setMetadataEntry(m, name, value)
+	}
+
+	if mbox != nil && len(shared) > 0 {
+		// Don't notify the session which has performed the change
+		var source *imapserver.SessionTracker
+		if sess.mailbox != nil && sess.mailbox.Mailbox == mbox {
+			source = sess.mailbox.tracker
+		}
+		mbox.setSharedMetadata(shared, source)
+	}
+
+	return nil
+}
+
+func (mbox *Mailbox) setSharedMetadata(entries map[string]*[]byte, source *imapserver.SessionTracker) {
+	mbox.mutex.Lock()
+	defer mbox.mutex.Unlock()
+
+	if mbox.metadata == nil {
+		mbox.metadata = make(map[string][]byte)
+	}
+	var names []string
+	for name, value := range entries {
+		setMetadataEntry(mbox.metadata, name, value)
+		names = append(names, name)
+	}
+
+	mbox.tracker.QueueMetadata(mbox.name, names, source)
+}
+
+func setMetadataEntry(m map[string][]byte, name string, value *[]byte) {
+	if value == nil {
+		delete(m, name)
+	} else {
+		m[name] = append([]byte(nil), *value...)
+	}
+}
+
+func isSharedMetadataEntry(name string) bool {
+	return name == "/shared" || strings.HasPrefix(name, "/shared/")
+}
+
+// matchMetadataEntry checks whether name is entry or one of its descendants
+// up to the specified depth.
+func matchMetadataEntry(name, entry string, depth imap.GetMetadataDepth) bool {
+	if name == entry {
+		return true
+	}
+	if !strings.HasPrefix(name, entry+"/") {
+		return false
+	}
+	switch depth {
+	case imap.GetMetadataDepthOne:
+		return !strings.Contains(name[len(entry)+1:], "/")
+	case imap.GetMetadataDepthInfinity:
+		return true
+	default:
+		return false
+	}
+}
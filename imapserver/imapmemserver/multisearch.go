@@ -0,0 +1,163 @@
+package imapmemserver
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/imapserver"
+)
+
+var _ imapserver.SessionMultiSearch = (*UserSession)(nil)
+
+func (sess *UserSession) MultiSearch(w *imapserver.MultiSearchWriter, sources []imap.MultiSearchSource, criteria *imap.SearchCriteria, options *imap.SearchOptions) error {
+	selected := sess.selected()
+	if len(sources) == 0 {
+		sources = []imap.MultiSearchSource{{Filter: imap.MultiSearchFilterSelected}}
+	}
+
+	for _, mbox := range sess.user.multiSearchMailboxes(sources, selected) {
+		// The criteria is altered by MailboxView.Search
+		mboxCriteria := copySearchCriteria(criteria)
+
+		var (
+			data *imap.SearchData
+			err  error
+		)
+		if mbox == selected {
+			data, err = sess.mailbox.Search(imapserver.NumKindUID, mboxCriteria, options)
+		} else {
+			view := mbox.NewView()
+			data, err = view.Search(imapserver.NumKindUID, mboxCriteria, options)
+			view.Close()
+		}
+		if err != nil {
+			return err
+		} else if data.Count == 0 {
+			continue
+		}
+
+		mbox.mutex.Lock()
+		name := mbox.name
+		mbox.mutex.Unlock()
+
+		err = w.WriteSearch(&imap.MultiSearchData{
+			Mailbox:     name,
+			UIDValidity: mbox.uidValidity,
+			Data:        *data,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// multiSearchMailboxes returns the readable mailboxes matching at least one of
+// the sources, sorted by name.
+func (u *User) multiSearchMailboxes(sources []imap.MultiSearchSource, selected *Mailbox) []*Mailbox {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	var names []string
+	for name, mbox := range u.mailboxes {
+		if !mbox.rights(u.username).Has(imap.RightRead) {
+			continue
+		}
+		mbox.mutex.Lock()
+		subscribed := mbox.subscribed
+		personal := mbox.owner == nil || mbox.owner == u
+		mbox.mutex.Unlock()
+		for _, source := range sources {
+			if matchMultiSearchSource(&source, name, subscribed, personal, mbox == selected) {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+
+	sort.Strings(names)
+	l := make([]*Mailbox, len(names))
+	for i, name := range names {
+		l[i] = u.mailboxes[name]
+	}
+	return l
+}
+
+func matchMultiSearchSource(source *imap.MultiSearchSource, name string, subscribed, personal, selected bool) bool {
+	switch source.Filter {
+	case imap.MultiSearchFilterSelected:
+		return selected
+	case imap.MultiSearchFilterInboxes:
+		return strings.EqualFold(name, "INBOX")
+	case imap.MultiSearchFilterPersonal:
+		// Mailboxes shared by other users aren't in the personal namespace
+		return personal
+	case imap.MultiSearchFilterSubscribed:
+		return subscribed
+	case imap.MultiSearchFilterSubtree:
+		for _, mailbox := range source.Mailboxes {
+			if name == mailbox || strings.HasPrefix(name, mailbox+string(mailboxDelim)) {
+				return true
+			}
+		}
+		return false
+	case imap.MultiSearchFilterSubtreeOne:
+		for _, mailbox := range source.Mailboxes {
+			if name == mailbox {
+				return true
+			}
+			child := strings.TrimPrefix(name, mailbox+string(mailboxDelim))
+			if child != name && !strings.ContainsRune(child, mailboxDelim) {
+				return true
+			}
+		}
+		return false
+	case imap.MultiSearchFilterMailboxes:
+		for _, mailbox := range source.Mailboxes {
+			if name == mailbox {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+// copySearchCriteria returns a copy of the criteria, including its message
+// number sets. The SEARCHRES marker is preserved.
+func copySearchCriteria(criteria *imap.SearchCriteria) *imap.SearchCriteria {
+	c := *criteria
+	c.SeqNum = make([]imap.SeqSet, len(criteria.SeqNum))
+	for i, seqSet := range criteria.SeqNum {
+		if imap.IsSearchRes(seqSet) {
+			c.SeqNum[i] = seqSet
+		} else {
+			c.SeqNum[i] = append(imap.SeqSet(nil), seqSet...)
+		}
+	}
+	c.UID = make([]imap.UIDSet, len(criteria.UID))
+	for i, uidSet := range criteria.UID {
+		if imap.IsSearchRes(uidSet) {
+			c.UID[i] = uidSet
+		} else {
+			c.UID[i] = append(imap.UIDSet(nil), uidSet...)
+		}
+	}
+	c.Not = make([]imap.SearchCriteria, len(criteria.Not))
+	for i := range criteria.Not {
+		c.Not[i] = *copySearchCriteria(&criteria.Not[i])
+	}
+	c.Or = make([][2]imap.SearchCriteria, len(criteria.Or))
+	for i := range criteria.Or {
+		for j := range criteria.Or[i] {
+			c.Or[i][j] = *copySearchCriteria(&criteria.Or[i][j])
+		}
+	}
+	c.Fuzzy = make([]imap.SearchCriteria, len(criteria.Fuzzy))
+	for i := range criteria.Fuzzy {
+		c.Fuzzy[i] = *copySearchCriteria(&criteria.Fuzzy[i])
+	}
+	return &c
+}
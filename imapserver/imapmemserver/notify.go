@@ -0,0 +1,345 @@
+package imapmemserver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/imapserver"
+)
+
+var notifyEvents = []imap.NotifyEvent{
+	imap.NotifyEventMessageNew,
+	imap.NotifyEventMessageExpunge,
+	imap.NotifyEventFlagChange,
+	imap.NotifyEventMailboxName,
+	imap.NotifyEventSubscriptionChange,
+}
+
+var notifyStatusOptions = imap.StatusOptions{
+	NumMessages:   true,
+	UIDNext:       true,
+	UIDValidity:   true,
+	NumUnseen:     true,
+	HighestModSeq: true,
+}
+
+type notifyEvent struct {
+	kind    imap.NotifyEvent
+	mailbox *Mailbox       // for message events
+	list    *imap.ListData // for mailbox events
+}
+
+// userTracker dispatches events to the sessions of a user which have enabled
+// NOTIFY.
+type userTracker struct {
+	mutex    sync.Mutex
+	sessions map[*notifyTracker]struct{}
+}
+
+func (t *userTracker) newSession(user *User, options *imap.NotifyOptions) *notifyTracker {
+	nt := &notifyTracker{user: user, options: options}
+	t.mutex.Lock()
+	if t.sessions == nil {
+		t.sessions = make(map[*notifyTracker]struct{})
+	}
+	t.sessions[nt] = struct{}{}
+	t.mutex.Unlock()
+	return nt
+}
+
+func (t *userTracker) closeSession(nt *notifyTracker) {
+	t.mutex.Lock()
+	delete(t.sessions, nt)
+	t.mutex.Unlock()
+}
+
+func (t *userTracker) queue(ev *notifyEvent) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for nt := range t.sessions {
+		nt.queue(ev)
+	}
+}
+
+// notifyTracker holds the pending NOTIFY events for a session.
+type notifyTracker struct {
+	user    *User               // immutable
+	options *imap.NotifyOptions // immutable
+
+	mutex   sync.Mutex
+	events  []notifyEvent
+	updates chan<- struct{}
+}
+
+func (t *notifyTracker) queue(ev *notifyEvent) {
+	var updates chan<- struct{}
+	t.mutex.Lock()
+	t.events = append(t.events, *ev)
+	updates = t.updates
+	t.mutex.Unlock()
+
+	if updates != nil {
+		select {
+		case updates <- struct{}{}:
+			// we notified notifyTracker.Idle about the update
+		default:
+			// skip the update
+		}
+	}
+}
+
+// Poll writes pending events. Message events for the selected mailbox are
+// skipped, since these are handled by the mailbox tracker.
+func (t *notifyTracker) Poll(w *imapserver.UpdateWriter, selected *Mailbox) error {
+	t.mutex.Lock()
+	events := t.events
+	t.events = nil
+	t.mutex.Unlock()
+
+	written := make(map[*Mailbox]struct{})
+	for _, ev := range events {
+		if ev.list != nil {
+			if !t.wantsList(&ev) {
+				continue
+			}
+			if err := w.WriteList(ev.list); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if _, ok := written[ev.mailbox]; ok || ev.mailbox == selected {
+			continue
+		}
+		data := t.status(ev.mailbox, ev.kind)
+		if data == nil {
+			continue
+		}
+		if err := w.WriteStatus(data); err != nil {
+			return err
+		}
+		written[ev.mailbox] = struct{}{}
+	}
+	return nil
+}
+
+// Idle continuously writes pending events, until the stop channel is closed.
+func (t *notifyTracker) Idle(w *imapserver.UpdateWriter, selected *Mailbox, stop <-chan struct{}) error {
+	updates := make(chan struct{}, 64)
+	t.mutex.Lock()
+	ok := t.updates == nil
+	if ok {
+		t.updates = updates
+	}
+	t.mutex.Unlock()
+	if !ok {
+		return fmt.Errorf("imapmemserver: only a single notifyTracker.Idle call is allowed at a time")
+	}
+
+	defer func() {
+		t.mutex.Lock()
+		t.updates = nil
+		t.mutex.Unlock()
+	}()
+
+	for {
+		select {
+		case <-updates:
+			if err := t.Poll(w, selected); err != nil {
+				return err
+			}
+		case <-stop:
+			return nil
+		}
+	}
+}
+
+// writeStatusAll writes a STATUS response for each mailbox with message
+// events, except the selected one.
+func (t *notifyTracker) writeStatusAll(w *imapserver.UpdateWriter, selected *Mailbox) error {
+	t.user.mutex.Lock()
+	var l []*Mailbox
+	for _, mbox := range t.user.mailboxes {
+		if mbox != selected {
+			l = append(l, mbox)
+		}
+	}
+	t.user.mutex.Unlock()
+
+	var statusList []*imap.StatusData
+	for _, mbox := range l {
+		if data := t.status(mbox, imap.NotifyEventMessageNew); data != nil {
+			statusList = append(statusList, data)
+		}
+	}
+
+	sort.Slice(statusList, func(i, j int) bool {
+		return statusList[i].Mailbox < statusList[j].Mailbox
+	})
+
+	for _, data := range statusList {
+		if err := w.WriteStatus(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// status returns the STATUS data to be sent for a message event, or nil if
+// the client isn't interested in the event.
+func (t *notifyTracker) status(mbox *Mailbox, kind imap.NotifyEvent) *imap.StatusData {
+	mbox.mutex.Lock()
+	name := mbox.name
+	subscribed := mbox.subscribed
+	mbox.mutex.Unlock()
+
+	// The mailbox may have been deleted in the meantime
+	if m, err := t.user.mailbox(name); err != nil || m != mbox {
+		return nil
+	}
+
+	group := t.findGroup(name, subscribed)
+	if group == nil || !group.Has(kind) {
+		return nil
+	}
+	if !mbox.rights(t.user.username).Has(imap.RightRead) {
+		return nil
+	}
+
+	return mbox.StatusData(&notifyStatusOptions)
+}
+
+func (t *notifyTracker) wantsList(ev *notifyEvent) bool {
+	subscribed := false
+	for _, attr := range ev.list.Attrs {
+		if attr == imap.MailboxAttrSubscribed {
+			subscribed = true
+		}
+	}
+
+	names := []string{ev.list.Mailbox}
+	if ev.list.OldName != "" {
+		names = append(names, ev.list.OldName)
+	}
+	for _, name := range names {
+		if group := t.findGroup(name, subscribed); group != nil && group.Has(ev.kind) {
+			return true
+		}
+	}
+	return false
+}
+
+// findGroup returns the first event group matching a mailbox which isn't
+// selected.
+func (t *notifyTracker) findGroup(name string, subscribed bool) *imap.NotifyGroup {
+	for i := range t.options.Groups {
+		group := &t.options.Groups[i]
+		if matchNotifyGroup(group, name, subscribed) {
+			return group
+		}
+	}
+	return nil
+}
+
+func matchNotifyGroup(group *imap.NotifyGroup, name string, subscribed bool) bool {
+	switch group.Filter {
+	case imap.NotifyFilterInboxes:
+		return strings.EqualFold(name, "INBOX")
+	case imap.NotifyFilterPersonal:
+		return true
+	case imap.NotifyFilterSubscribed:
+		return subscribed
+	case imap.NotifyFilterSubtree:
+		for _, mailbox := range group.Mailboxes {
+			if name == mailbox || strings.HasPrefix(name, mailbox+string(mailboxDelim)) {
+				return true
+			}
+		}
+		return false
+	case imap.NotifyFilterMailboxes:
+		for _, mailbox := range group.Mailboxes {
+			if name == mailbox {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+func (sess *UserSession) NotifyEvents() []imap.NotifyEvent {
+	return notifyEvents
+}
+
+func (sess *UserSession) Notify(w *imapserver.UpdateWriter, options *imap.NotifyOptions) error {
+	if options != nil {
+		for _, group := range options.Groups {
+			switch group.Filter {
+			case imap.NotifyFilterSelected, imap.NotifyFilterSelectedDelayed:
+				// Events for the selected mailbox are always delivered as
+				// usual
+				return &imap.Error{
+					Type: imap.StatusResponseTypeBad,
+					Text: "SELECTED and SELECTED-DELAYED filters are not supported",
+				}
+			}
+		}
+	}
+
+	if sess.notify != nil {
+		sess.user.tracker.closeSession(sess.notify)
+		sess.notify = nil
+	}
+	if options == nil {
+		return nil
+	}
+
+	sess.notify = sess.user.tracker.newSession(sess.user, options)
+	if options.Status {
+		return sess.notify.writeStatusAll(w, sess.selected())
+	}
+	return nil
+}
+
+func (sess *UserSession) selected() *Mailbox {
+	if sess.mailbox == nil {
+		return nil
+	}
+	return sess.mailbox.Mailbox
+}
+
+func (mbox *Mailbox) addUserTracker(t *userTracker) {
+	mbox.mutex.Lock()
+	defer mbox.mutex.Unlock()
+	if mbox.userTrackers == nil {
+		mbox.userTrackers = make(map[*userTracker]struct{})
+	}
+	mbox.userTrackers[t] = struct{}{}
+}
+
+func (mbox *Mailbox) removeUserTracker(t *userTracker) {
+	mbox.mutex.Lock()
+	delete(mbox.userTrackers, t)
+	mbox.mutex.Unlock()
+}
+
+func (mbox *Mailbox) notify(kind imap.NotifyEvent) {
+	mbox.mutex.Lock()
+	defer mbox.mutex.Unlock()
+	mbox.notifyLocked(kind)
+}
+
+func (mbox *Mailbox) notifyLocked(kind imap.NotifyEvent) {
+	for t := range mbox.userTrackers {
+		t.queue(&notifyEvent{kind: kind, mailbox: mbox})
+	}
+}
+
+func (u *User) notifyMailboxLocked(kind imap.NotifyEvent, data *imap.ListData) {
+	data.Delim = mailboxDelim
+	u.tracker.queue(&notifyEvent{kind: kind, list: data})
+}
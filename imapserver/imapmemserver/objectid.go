@@ -0,0 +1,37 @@
+package imapmemserver
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// newObjectID generates a random object ID, as defined in RFC 8474.
+//
+// The prefix indicates the kind of object: "F" for mailboxes and "M" for
+// messages.
+func newObjectID(prefix string) string {
+	var b [12]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	return prefix + hex.EncodeToString(b[:])
+}
+
+// threadID returns the message's thread ID.
+//
+// Messages belonging to the same thread share the same root message ID: the
+// first one listed in References or In-Reply-To. The thread ID is derived
+// from it, so that it's stable and doesn't need to be stored.
+func (msg *message) threadID() string {
+	tm := msg.threadMessage(0)
+	root := tm.messageID
+	if len(tm.refs) > 0 {
+		root = tm.refs[0]
+	}
+	if root == "" {
+		root = msg.emailID
+	}
+	sum := sha256.Sum256([]byte(root))
+	return "T" + hex.EncodeToString(sum[:12])
+}
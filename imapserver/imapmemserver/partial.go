@@ -0,0 +1,37 @@
+package imapmemserver
+
+import (
+	"github.com/emersion/go-imap/v2"
+)
+
+// partialBounds returns the bounds of the items selected by a partial range
+// in a list of n items.
+func partialBounds(r imap.PartialRange, n int) (start, end int) {
+	var s, e int64
+	if r.Offset >= 0 {
+		s = r.Offset
+		e = s + int64(r.Count)
+	} else {
+		e = int64(n) + r.Offset + 1
+		s = e - int64(r.Count)
+	}
+	if s < 0 {
+		s = 0
+	}
+	if e > int64(n) {
+		e = int64(n)
+	}
+	if s >= e {
+		return 0, 0
+	}
+	return int(s), int(e)
+}
+
+// partialLen returns the number of items needed from the start (or the end,
+// if the offset is negative) of a list to fill a partial range.
+func partialLen(r imap.PartialRange) int64 {
+	if r.Offset >= 0 {
+		return r.Offset + int64(r.Count)
+	}
+	return -r.Offset - 1 + int64(r.Count)
+}
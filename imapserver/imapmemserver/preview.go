@@ -0,0 +1,115 @@
+package imapmemserver
+
+import (
+	"bytes"
+	"errors"
+	"html"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	gomessage "github.com/emersion/go-message"
+	_ "github.com/emersion/go-message/charset"
+)
+
+// previewMaxLen is the maximum length of a preview, in characters.
+//
+// See RFC 8970 section 3.1.
+const previewMaxLen = 256
+
+var errPreviewFound = errors.New("preview found")
+
+// preview generates a plain text preview of the message: the first text part
+// is decoded, stripped of any markup and truncated.
+func (msg *message) preview() string {
+	entity, err := gomessage.Read(bytes.NewReader(msg.buf))
+	if entity == nil {
+		return ""
+	} else if err != nil && !gomessage.IsUnknownCharset(err) && !gomessage.IsUnknownEncoding(err) {
+		return ""
+	}
+
+	var text string
+	err = entity.Walk(func(path []int, part *gomessage.Entity, err error) error {
+		if err != nil {
+			return nil // skip parts we can't decode
+		}
+		mediaType, _, _ := part.Header.ContentType()
+		if mediaType != "text/plain" && mediaType != "text/html" {
+			return nil
+		}
+		if disp, _, _ := part.Header.ContentDisposition(); strings.EqualFold(disp, "attachment") {
+			return nil
+		}
+
+		b, err := io.ReadAll(part.Body)
+		if err != nil {
+			return err
+		}
+		text = string(b)
+		if mediaType == "text/html" {
+			text = stripHTML(text)
+		}
+		return errPreviewFound
+	})
+	if err != nil && err != errPreviewFound {
+		return ""
+	}
+
+	return truncatePreview(strings.Join(strings.Fields(text), " "))
+}
+
+// stripHTML converts an HTML document to plain text.
+func stripHTML(s string) string {
+	var sb strings.Builder
+	for {
+		i := strings.IndexByte(s, '<')
+		if i < 0 {
+			sb.WriteString(html.UnescapeString(s))
+			break
+		}
+		sb.WriteString(html.UnescapeString(s[:i]))
+		s = s[i+1:]
+
+		j := strings.IndexByte(s, '>')
+		if j < 0 {
+			break
+		}
+		tag := strings.ToLower(strings.TrimPrefix(s[:j], "/"))
+		if k := strings.IndexAny(tag, " \t\r\n/"); k >= 0 {
+			tag = tag[:k]
+		}
+		s = s[j+1:]
+
+		switch tag {
+		case "head", "style", "script", "title":
+			// Skip the element's contents
+			end := "</" + tag
+			if k := strings.Index(strings.ToLower(s), end); k >= 0 {
+				s = s[k+len(end):]
+				if k := strings.IndexByte(s, '>'); k >= 0 {
+					s = s[k+1:]
+				}
+			}
+		case "a", "b", "i", "u", "em", "strong", "span", "font", "code", "small", "big", "sub", "sup":
+			// Inline elements don't break words
+		default:
+			sb.WriteByte(' ')
+		}
+	}
+	return sb.String()
+}
+
+func truncatePreview(s string) string {
+	if utf8.RuneCountInString(s) <= previewMaxLen {
+		return s
+	}
+	n := 0
+	for i := range s {
+		if n == previewMaxLen {
+			return s[:i]
+		}
+		n++
+	}
+	return s
+}
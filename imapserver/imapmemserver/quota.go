@@ -0,0 +1,165 @@
+package imapmemserver
+
+import (
+	"github.com/emersion/go-imap/v2"
+)
+
+// quotaRoot is the name of the single quota root of a user, which covers all
+// of their mailboxes.
+const quotaRoot = ""
+
+var errNoSuchQuotaRoot = &imap.Error{
+	Type: imap.StatusResponseTypeNo,
+	Code: imap.ResponseCodeNonExistent,
+	Text: "No such quota root",
+}
+
+// quotaStorage converts a size in bytes into STORAGE units (1024 octets).
+func quotaStorage(size int64) int64 {
+	return (size + 1023) / 1024
+}
+
+func (u *User) QuotaResourceTypes() []imap.QuotaResourceType {
+	return []imap.QuotaResourceType{imap.QuotaResourceStorage, imap.QuotaResourceMessage}
+}
+
+func (u *User) GetQuota(root string) (*imap.QuotaData, error) {
+	if root != quotaRoot {
+		return nil, errNoSuchQuotaRoot
+	}
+
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	return u.quotaDataLocked(), nil
+}
+
+func (u *User) GetQuotaRoot(mailbox string) ([]imap.QuotaData, error) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	if _, err := u.mailboxLocked(mailbox); err != nil {
+		return nil, err
+	}
+	return []imap.QuotaData{*u.quotaDataLocked()}, nil
+}
+
+// SetQuota sets the resource limits of the user. Resource types missing from
+// limits are unlimited.
+//
+// Users can't change their own limits with the SETQUOTA command, see
+// UserSession.SetQuota.
+func (u *User) SetQuota(root string, limits map[imap.QuotaResourceType]int64) (*imap.QuotaData, error) {
+	if root != quotaRoot {
+		return nil, errNoSuchQuotaRoot
+	}
+
+	l := make(map[imap.QuotaResourceType]int64, len(limits))
+	for typ, limit := range limits {
+		switch typ {
+		case imap.QuotaResourceStorage, imap.QuotaResourceMessage:
+			l[typ] = limit
+		default:
+			return nil, &imap.Error{
+				Type: imap.StatusResponseTypeNo,
+				Text: "Unsupported quota resource type",
+			}
+		}
+	}
+
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.quotaLimits = l
+	return u.quotaDataLocked(), nil
+}
+
+func (u *User) quotaDataLocked() *imap.QuotaData {
+	numMessages, size := u.quotaUsageLocked()
+	data := imap.QuotaData{
+		Root:      quotaRoot,
+		Resources: make(map[imap.QuotaResourceType]imap.QuotaResourceData),
+	}
+	for typ, limit := range u.quotaLimits {
+		var usage int64
+		switch typ {
+		case imap.QuotaResourceStorage:
+			usage = quotaStorage(size)
+		case imap.QuotaResourceMessage:
+			usage = numMessages
+		}
+		data.Resources[typ] = imap.QuotaResourceData{Usage: usage, Limit: limit}
+	}
+	return &data
+}
+
+// quotaUsageLocked returns the usage of the mailboxes owned by the user.
+// Mailboxes shared with the user by others count towards their owner's quota.
+func (u *User) quotaUsageLocked() (numMessages, size int64) {
+	for _, mbox := range u.mailboxes {
+		mbox.mutex.Lock()
+		if mbox.owner == u {
+			numMessages += int64(len(mbox.l))
+			size += mbox.sizeLocked()
+		}
+		mbox.mutex.Unlock()
+	}
+	return numMessages, size
+}
+
+// SetQuota implements SETQUOTA. Users aren't allowed to change their own
+// limits, this can only be done via User.SetQuota, so servers shouldn't
+// advertise QUOTASET.
+func (sess *UserSession) SetQuota(root string, limits map[imap.QuotaResourceType]int64) (*imap.QuotaData, error) {
+	return nil, errNoPerm
+}
+
+// lockQuota locks the owner of the mailbox and checks that adding numMessages
+// messages totalling size bytes doesn't exceed their limits.
+//
+// On success, the returned function must be called once the messages are
+// added.
+func (mbox *Mailbox) lockQuota(numMessages, size int64) (unlock func(), err error) {
+	owner := mbox.ownerUser()
+	if owner == nil {
+		return func() {}, nil
+	}
+
+	owner.mutex.Lock()
+	if err := owner.checkQuotaLocked(numMessages, size); err != nil {
+		owner.mutex.Unlock()
+		return nil, err
+	}
+	return owner.mutex.Unlock, nil
+}
+
+func (mbox *Mailbox) ownerUser() *User {
+	mbox.mutex.Lock()
+	defer mbox.mutex.Unlock()
+	return mbox.owner
+}
+
+// checkQuotaLocked returns an error if adding numMessages messages totalling
+// size bytes would exceed the user's limits.
+//
+// The user's mutex must be held until the messages are added.
+func (u *User) checkQuotaLocked(numMessages, size int64) error {
+	if len(u.quotaLimits) == 0 {
+		return nil
+	}
+
+	usedMessages, usedSize := u.quotaUsageLocked()
+	over := false
+	if limit, ok := u.quotaLimits[imap.QuotaResourceStorage]; ok && quotaStorage(usedSize+size) > limit {
+		over = true
+	}
+	if limit, ok := u.quotaLimits[imap.QuotaResourceMessage]; ok && usedMessages+numMessages > limit {
+		over = true
+	}
+	if over {
+		return &imap.Error{
+			Type: imap.StatusResponseTypeNo,
+			Code: imap.ResponseCodeOverQuota,
+			Text: "Quota exceeded",
+		}
+	}
+	return nil
+}
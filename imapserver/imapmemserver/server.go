@@ -2,8 +2,10 @@
 package imapmemserver
 
 import (
+	"fmt"
 	"sync"
 
+	"github.com/emersion/go-imap/v2"
 	"github.com/emersion/go-imap/v2/imapserver"
 )
 
@@ -11,8 +13,10 @@ import (
 //
 // A server contains a list of users.
 type Server struct {
-	mutex sync.Mutex
-	users map[string]*User
+	mutex      sync.Mutex
+	users      map[string]*User
+	submitUser string
+	metadata   serverMetadata
 }
 
 // New creates a new server.
@@ -34,19 +38,64 @@ func (s *Server) user(username string) *User {
 }
 
 // AddUser adds a user to the server.
+//
+// The user's shared server metadata entries are replaced with the server's.
 func (s *Server) AddUser(user *User) {
+	user.mutex.Lock()
+	user.serverMetadata = &s.metadata
+	user.mutex.Unlock()
+
 	s.mutex.Lock()
 	s.users[user.username] = user
 	s.mutex.Unlock()
 }
 
+// SetSubmitUser sets the name of the user acting as a message submission
+// server. Only this user can fetch URLs with a "submit+" URLAUTH access
+// identifier.
+func (s *Server) SetSubmitUser(username string) {
+	s.mutex.Lock()
+	s.submitUser = username
+	s.mutex.Unlock()
+}
+
+// SetMetadata sets shared server metadata entries, which are visible to all
+// users. Users can't change these entries with the SETMETADATA command.
+//
+// To remove an entry, set it to nil.
+func (s *Server) SetMetadata(entries map[string]*[]byte) error {
+	for name := range entries {
+		if !isSharedMetadataEntry(name) {
+			return fmt.Errorf("imapmemserver: invalid shared metadata entry %q", name)
+		}
+	}
+	for name, value := range entries {
+		s.metadata.set(name, value)
+	}
+	return nil
+}
+
+func (s *Server) isSubmitUser(username string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.submitUser != "" && s.submitUser == username
+}
+
 type serverSession struct {
 	*UserSession // may be nil
 
 	server *Server // immutable
 }
 
-var _ imapserver.Session = (*serverSession)(nil)
+var (
+	_ imapserver.Session        = (*serverSession)(nil)
+	_ imapserver.SessionID      = (*serverSession)(nil)
+	_ imapserver.SessionURLAuth = (*serverSession)(nil)
+)
+
+func (sess *serverSession) ID(clientID map[string]string) (map[string]string, error) {
+	return map[string]string{"name": "imapmemserver"}, nil
+}
 
 func (sess *serverSession) Login(username, password string) error {
 	u := sess.server.user(username)
@@ -59,3 +108,25 @@ func (sess *serverSession) Login(username, password string) error {
 	sess.UserSession = NewUserSession(u)
 	return nil
 }
+
+// URLAuthKey looks up keys of other users, so that URLs can be fetched on
+// their behalf.
+func (sess *serverSession) URLAuthKey(url *imap.URL, create bool) ([]byte, error) {
+	if create {
+		return sess.UserSession.URLAuthKey(url, create)
+	}
+	owner := sess.server.user(url.User)
+	if owner == nil {
+		return nil, errBadURL
+	}
+	return owner.urlAuthKey(url.Mailbox, false)
+}
+
+func (sess *serverSession) URLFetch(url *imap.URL) ([]byte, error) {
+	owner := sess.server.user(url.User)
+	if owner == nil {
+		return nil, errBadURL
+	}
+	username := sess.user.username
+	return owner.urlFetch(url, username, sess.server.isSubmitUser(username))
+}
@@ -1,6 +1,8 @@
 package imapmemserver
 
 import (
+	"bytes"
+
 	"github.com/emersion/go-imap/v2"
 	"github.com/emersion/go-imap/v2/imapserver"
 )
@@ -17,19 +19,40 @@ type (
 type UserSession struct {
 	*user    // immutable
 	*mailbox // may be nil
+
+	notify *notifyTracker // may be nil
 }
 
-var _ imapserver.SessionIMAP4rev2 = (*UserSession)(nil)
+var (
+	_ imapserver.SessionIMAP4rev2   = (*UserSession)(nil)
+	_ imapserver.SessionCondStore   = (*UserSession)(nil)
+	_ imapserver.SessionSort        = (*UserSession)(nil)
+	_ imapserver.SessionThread      = (*UserSession)(nil)
+	_ imapserver.SessionQuota       = (*UserSession)(nil)
+	_ imapserver.SessionMetadata    = (*UserSession)(nil)
+	_ imapserver.SessionACL         = (*UserSession)(nil)
+	_ imapserver.SessionNotify      = (*UserSession)(nil)
+	_ imapserver.SessionMultiAppend = (*UserSession)(nil)
+	_ imapserver.SessionCatenate    = (*UserSession)(nil)
+	_ imapserver.SessionURLAuth     = (*UserSession)(nil)
+	_ imapserver.SessionReplace     = (*UserSession)(nil)
+	_ imapserver.SessionUIDOnly     = (*UserSession)(nil)
+)
 
 // NewUserSession creates a new user session.
 func NewUserSession(user *User) *UserSession {
 	return &UserSession{user: user}
 }
 
+func (sess *UserSession) SupportsUIDOnly() {}
+
 func (sess *UserSession) Close() error {
 	if sess != nil && sess.mailbox != nil {
 		sess.mailbox.Close()
 	}
+	if sess != nil && sess.notify != nil {
+		sess.user.tracker.closeSession(sess.notify)
+	}
 	return nil
 }
 
@@ -38,6 +61,9 @@ func (sess *UserSession) Select(name string, options *imap.SelectOptions) (*imap
 	if err != nil {
 		return nil, err
 	}
+	if err := mbox.checkRights(sess.user.username, imap.RightRead); err != nil {
+		return nil, err
+	}
 	mbox.mutex.Lock()
 	defer mbox.mutex.Unlock()
 	sess.mailbox = mbox.NewView()
@@ -50,6 +76,27 @@ func (sess *UserSession) Unselect() error {
 	return nil
 }
 
+func (sess *UserSession) Store(w *imapserver.FetchWriter, numSet imap.NumSet, flags *imap.StoreFlags, options *imap.StoreOptions) error {
+	if err := sess.mailbox.checkRights(sess.user.username, storeRights(flags)...); err != nil {
+		return err
+	}
+	return sess.mailbox.Store(w, numSet, flags, options)
+}
+
+func (sess *UserSession) StoreUnchangedSince(w *imapserver.FetchWriter, numSet imap.NumSet, flags *imap.StoreFlags, options *imap.StoreOptions) (imap.NumSet, error) {
+	if err := sess.mailbox.checkRights(sess.user.username, storeRights(flags)...); err != nil {
+		return nil, err
+	}
+	return sess.mailbox.StoreUnchangedSince(w, numSet, flags, options)
+}
+
+func (sess *UserSession) Expunge(w *imapserver.ExpungeWriter, uids *imap.UIDSet) error {
+	if err := sess.mailbox.checkRights(sess.user.username, imap.RightExpunge); err != nil {
+		return err
+	}
+	return sess.mailbox.Expunge(w, uids)
+}
+
 func (sess *UserSession) Copy(numSet imap.NumSet, destName string) (*imap.CopyData, error) {
 	dest, err := sess.user.mailbox(destName)
 	if err != nil {
@@ -64,6 +111,15 @@ func (sess *UserSession) Copy(numSet imap.NumSet, destName string) (*imap.CopyDa
 			Text: "Source and destination mailboxes are identical",
 		}
 	}
+	if err := dest.checkRights(sess.user.username, imap.RightInsert); err != nil {
+		return nil, err
+	}
+
+	unlock, err := dest.lockQuota(sess.copyUsage(numSet))
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
 
 	var sourceUIDs, destUIDs imap.UIDSet
 	sess.mailbox.forEach(numSet, func(seqNum uint32, msg *message) {
@@ -93,6 +149,21 @@ func (sess *UserSession) Move(w *imapserver.MoveWriter, numSet imap.NumSet, dest
 			Text: "Source and destination mailboxes are identical",
 		}
 	}
+	if err := dest.checkRights(sess.user.username, imap.RightInsert); err != nil {
+		return err
+	}
+	if err := sess.mailbox.checkRights(sess.user.username, imap.RightDeleteMessages, imap.RightExpunge); err != nil {
+		return err
+	}
+
+	// The quota usage only changes if the destination has another owner
+	if dest.ownerUser() != sess.mailbox.ownerUser() {
+		unlock, err := dest.lockQuota(sess.copyUsage(numSet))
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
 
 	sess.mailbox.mutex.Lock()
 	defer sess.mailbox.mutex.Unlock()
@@ -105,7 +176,7 @@ func (sess *UserSession) Move(w *imapserver.MoveWriter, numSet imap.NumSet, dest
 		destUIDs.AddNum(appendData.UID)
 		expunged[msg] = struct{}{}
 	})
-	seqNums := sess.mailbox.expungeLocked(expunged)
+	seqNums, uids := sess.mailbox.expungeLocked(expunged)
 
 	err = w.WriteCopyData(&imap.CopyData{
 		UIDValidity: dest.uidValidity,
@@ -116,8 +187,8 @@ func (sess *UserSession) Move(w *imapserver.MoveWriter, numSet imap.NumSet, dest
 		return err
 	}
 
-	for _, seqNum := range seqNums {
-		if err := w.WriteExpunge(sess.mailbox.tracker.EncodeSeqNum(seqNum)); err != nil {
+	for i, seqNum := range seqNums {
+		if err := w.WriteExpungeUID(sess.mailbox.tracker.EncodeSeqNum(seqNum), uids[i]); err != nil {
 			return err
 		}
 	}
@@ -125,16 +196,111 @@ func (sess *UserSession) Move(w *imapserver.MoveWriter, numSet imap.NumSet, dest
 	return nil
 }
 
+func (sess *UserSession) Replace(numSet imap.NumSet, destName string, r imap.LiteralReader, options *imap.AppendOptions) (*imap.AppendData, error) {
+	dest, err := sess.user.mailbox(destName)
+	if err != nil {
+		return nil, &imap.Error{
+			Type: imap.StatusResponseTypeNo,
+			Code: imap.ResponseCodeTryCreate,
+			Text: "No such mailbox",
+		}
+	}
+	if err := dest.checkRights(sess.user.username, imap.RightInsert); err != nil {
+		return nil, err
+	}
+	if err := sess.mailbox.checkRights(sess.user.username, imap.RightDeleteMessages, imap.RightExpunge); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(r); err != nil {
+		return nil, err
+	}
+
+	errNoSuchMessage := &imap.Error{
+		Type: imap.StatusResponseTypeNo,
+		Text: "No such message",
+	}
+	var replaced *message
+	sess.mailbox.forEach(numSet, func(seqNum uint32, msg *message) {
+		replaced = msg
+	})
+	if replaced == nil {
+		return nil, errNoSuchMessage
+	}
+
+	// The replaced message is expunged, so only the size difference counts
+	// if both mailboxes have the same owner
+	numMessages, size := int64(1), int64(buf.Len())
+	if dest.ownerUser() == sess.mailbox.ownerUser() {
+		numMessages, size = 0, size-int64(len(replaced.buf))
+	}
+	unlock, err := dest.lockQuota(numMessages, size)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	sess.mailbox.mutex.Lock()
+	defer sess.mailbox.mutex.Unlock()
+
+	// The message may have been expunged in the meantime
+	var stillExists bool
+	sess.mailbox.forEachLocked(numSet, func(seqNum uint32, msg *message) {
+		stillExists = msg == replaced
+	})
+	if !stillExists {
+		return nil, errNoSuchMessage
+	}
+
+	var data *imap.AppendData
+	if dest == sess.mailbox.Mailbox {
+		data = dest.appendBytesLocked(buf.Bytes(), options)
+	} else {
+		data = dest.appendBytes(buf.Bytes(), options)
+	}
+	sess.mailbox.expungeLocked(map[*message]struct{}{replaced: {}})
+	return data, nil
+}
+
+// copyUsage returns the number and total size of the messages in numSet.
+func (sess *UserSession) copyUsage(numSet imap.NumSet) (numMessages, size int64) {
+	sess.mailbox.forEach(numSet, func(seqNum uint32, msg *message) {
+		numMessages++
+		size += int64(len(msg.buf))
+	})
+	return numMessages, size
+}
+
 func (sess *UserSession) Poll(w *imapserver.UpdateWriter, allowExpunge bool) error {
-	if sess.mailbox == nil {
-		return nil
+	if sess.mailbox != nil {
+		if err := sess.mailbox.Poll(w, allowExpunge); err != nil {
+			return err
+		}
 	}
-	return sess.mailbox.Poll(w, allowExpunge)
+	if sess.notify != nil {
+		return sess.notify.Poll(w, sess.selected())
+	}
+	return nil
 }
 
 func (sess *UserSession) Idle(w *imapserver.UpdateWriter, stop <-chan struct{}) error {
-	if sess.mailbox == nil {
+	switch {
+	case sess.notify == nil && sess.mailbox == nil:
 		return nil // TODO
+	case sess.notify == nil:
+		return sess.mailbox.Idle(w, stop)
+	case sess.mailbox == nil:
+		return sess.notify.Idle(w, nil, stop)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- sess.mailbox.Idle(w, stop)
+	}()
+	err := sess.notify.Idle(w, sess.selected(), stop)
+	if mboxErr := <-done; err == nil {
+		err = mboxErr
 	}
-	return sess.mailbox.Idle(w, stop)
+	return err
 }
@@ -0,0 +1,254 @@
+package imapmemserver
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/imapserver"
+	gomessage "github.com/emersion/go-message"
+	"github.com/emersion/go-message/mail"
+	"github.com/emersion/go-message/textproto"
+)
+
+func (mbox *MailboxView) Sort(numKind imapserver.NumKind, criteria []imap.SortCriterion, searchCriteria *imap.SearchCriteria) ([]uint32, error) {
+	mbox.mutex.Lock()
+	defer mbox.mutex.Unlock()
+
+	mbox.staticSearchCriteria(searchCriteria)
+
+	type sortItem struct {
+		num    uint32
+		values *sortValues
+	}
+
+	var items []sortItem
+	for i, msg := range mbox.l {
+		seqNum := mbox.tracker.EncodeSeqNum(uint32(i) + 1)
+
+		if !msg.search(seqNum, searchCriteria) {
+			continue
+		}
+
+		var num uint32
+		switch numKind {
+		case imapserver.NumKindSeq:
+			if seqNum == 0 {
+				continue
+			}
+			num = seqNum
+		case imapserver.NumKindUID:
+			num = uint32(msg.uid)
+		}
+		items = append(items, sortItem{num: num, values: msg.sortValues()})
+	}
+
+	// Messages are already in sequence number order, which is the tie-breaker
+	// mandated by RFC 5256
+	sort.SliceStable(items, func(i, j int) bool {
+		return compareSortValues(criteria, items[i].values, items[j].values) < 0
+	})
+
+	nums := make([]uint32, len(items))
+	for i, item := range items {
+		nums[i] = item.num
+	}
+	return nums, nil
+}
+
+type sortValues struct {
+	arrival, date time.Time
+	size          int64
+	// upper-cased for the i;ascii-casemap collation
+	from, to, cc, subject string
+}
+
+func (msg *message) sortValues() *sortValues {
+	br := bufio.NewReader(bytes.NewReader(msg.buf))
+	rawHeader, _ := textproto.ReadHeader(br)
+	envelope := getEnvelope(rawHeader)
+
+	mh := mail.Header{Header: gomessage.Header{Header: rawHeader}}
+	subject, err := mh.Subject()
+	if err != nil {
+		subject = envelope.Subject
+	}
+
+	base, _ := baseSubject(subject)
+
+	return &sortValues{
+		arrival: msg.t,
+		date:    msg.sentDate(envelope),
+		size:    int64(len(msg.buf)),
+		from:    strings.ToUpper(firstAddressMailbox(envelope.From)),
+		to:      strings.ToUpper(firstAddressMailbox(envelope.To)),
+		cc:      strings.ToUpper(firstAddressMailbox(envelope.Cc)),
+		subject: strings.ToUpper(base),
+	}
+}
+
+// sentDate returns the Date header field of the message. If it cannot be
+// determined, the internal date is used instead.
+func (msg *message) sentDate(envelope *imap.Envelope) time.Time {
+	if envelope.Date.IsZero() {
+		return msg.t
+	}
+	return envelope.Date
+}
+
+func firstAddressMailbox(addrs []imap.Address) string {
+	if len(addrs) == 0 {
+		return ""
+	}
+	return addrs[0].Mailbox
+}
+
+func compareSortValues(criteria []imap.SortCriterion, a, b *sortValues) int {
+	for _, criterion := range criteria {
+		var cmp int
+		switch criterion.Key {
+		case imap.SortKeyArrival:
+			cmp = compareTime(a.arrival, b.arrival)
+		case imap.SortKeyDate:
+			cmp = compareTime(a.date, b.date)
+		case imap.SortKeySize:
+			cmp = compareInt64(a.size, b.size)
+		case imap.SortKeyFrom:
+			cmp = strings.Compare(a.from, b.from)
+		case imap.SortKeyTo:
+			cmp = strings.Compare(a.to, b.to)
+		case imap.SortKeyCc:
+			cmp = strings.Compare(a.cc, b.cc)
+		case imap.SortKeySubject:
+			cmp = strings.Compare(a.subject, b.subject)
+		default:
+			panic(fmt.Errorf("unknown sort key: %v", criterion.Key))
+		}
+		if criterion.Reverse {
+			cmp = -cmp
+		}
+		if cmp != 0 {
+			return cmp
+		}
+	}
+	return 0
+}
+
+func compareTime(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	default:
+		return 0
+	}
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// baseSubject extracts the base subject of a decoded Subject header field, as
+// defined in RFC 5256 section 2.1. isReplyOrForward indicates whether a reply
+// or forward marker has been removed.
+func baseSubject(s string) (base string, isReplyOrForward bool) {
+	// (1) Collapse whitespace
+	s = strings.Join(strings.Fields(s), " ")
+
+	for {
+		// (2) Remove subj-trailer
+		for len(s) >= len("(fwd)") && strings.EqualFold(s[len(s)-len("(fwd)"):], "(fwd)") {
+			s = strings.TrimRight(s[:len(s)-len("(fwd)")], " ")
+			isReplyOrForward = true
+		}
+
+		// (3), (4) and (5) Remove subj-leader and subj-blob
+		for {
+			prev := s
+			var refwd bool
+			s, refwd = trimSubjectLeader(s)
+			if refwd {
+				isReplyOrForward = true
+			}
+			if rest, ok := trimSubjectBlob(s); ok && rest != "" {
+				s = rest
+			}
+			if s == prev {
+				break
+			}
+		}
+
+		// (6) Remove subj-fwd-hdr and subj-fwd-trl
+		if hasPrefixFold(s, "[fwd:") && strings.HasSuffix(s, "]") {
+			s = strings.TrimSpace(s[len("[fwd:") : len(s)-1])
+			isReplyOrForward = true
+			continue
+		}
+
+		return s, isReplyOrForward
+	}
+}
+
+// trimSubjectLeader removes a subj-leader prefix, if any. refwd indicates
+// whether the prefix contained a subj-refwd.
+func trimSubjectLeader(s string) (rest string, refwd bool) {
+	if strings.HasPrefix(s, " ") {
+		return strings.TrimLeft(s, " "), false
+	}
+
+	t := s
+	for {
+		rest, ok := trimSubjectBlob(t)
+		if !ok {
+			break
+		}
+		t = rest
+	}
+
+	switch {
+	case hasPrefixFold(t, "re"):
+		t = t[len("re"):]
+	case hasPrefixFold(t, "fwd"):
+		t = t[len("fwd"):]
+	case hasPrefixFold(t, "fw"):
+		t = t[len("fw"):]
+	default:
+		return s, false
+	}
+	t = strings.TrimLeft(t, " ")
+	if rest, ok := trimSubjectBlob(t); ok {
+		t = rest
+	}
+	if !strings.HasPrefix(t, ":") {
+		return s, false
+	}
+	return t[1:], true
+}
+
+// trimSubjectBlob removes a subj-blob prefix, if any.
+func trimSubjectBlob(s string) (rest string, ok bool) {
+	if !strings.HasPrefix(s, "[") {
+		return s, false
+	}
+	i := strings.IndexAny(s[1:], "[]")
+	if i < 0 || s[1+i] != ']' {
+		return s, false
+	}
+	return strings.TrimLeft(s[i+2:], " "), true
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
@@ -0,0 +1,352 @@
+package imapmemserver
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/imapserver"
+	gomessage "github.com/emersion/go-message"
+	"github.com/emersion/go-message/mail"
+	"github.com/emersion/go-message/textproto"
+)
+
+func (sess *UserSession) ThreadAlgorithms() []imap.ThreadAlgorithm {
+	return []imap.ThreadAlgorithm{imap.ThreadOrderedSubject, imap.ThreadReferences}
+}
+
+func (mbox *MailboxView) Thread(numKind imapserver.NumKind, algorithm imap.ThreadAlgorithm, searchCriteria *imap.SearchCriteria) ([]imap.ThreadData, error) {
+	mbox.mutex.Lock()
+	defer mbox.mutex.Unlock()
+
+	mbox.staticSearchCriteria(searchCriteria)
+
+	var msgs []*threadMessage
+	for i, msg := range mbox.l {
+		seqNum := mbox.tracker.EncodeSeqNum(uint32(i) + 1)
+
+		if !msg.search(seqNum, searchCriteria) {
+			continue
+		}
+
+		var num uint32
+		switch numKind {
+		case imapserver.NumKindSeq:
+			if seqNum == 0 {
+				continue
+			}
+			num = seqNum
+		case imapserver.NumKindUID:
+			num = uint32(msg.uid)
+		}
+		msgs = append(msgs, msg.threadMessage(num))
+	}
+
+	var roots []*threadContainer
+	switch algorithm {
+	case imap.ThreadOrderedSubject:
+		roots = threadOrderedSubject(msgs)
+	case imap.ThreadReferences:
+		roots = threadReferences(msgs)
+	default:
+		return nil, fmt.Errorf("unsupported threading algorithm: %v", algorithm)
+	}
+
+	threads := make([]imap.ThreadData, len(roots))
+	for i, root := range roots {
+		threads[i] = root.threadData()
+	}
+	return threads, nil
+}
+
+type threadMessage struct {
+	num       uint32
+	date      time.Time
+	subject   string // base subject, upper-cased
+	isReply   bool
+	messageID string
+	refs      []string
+}
+
+func (msg *message) threadMessage(num uint32) *threadMessage {
+	br := bufio.NewReader(bytes.NewReader(msg.buf))
+	rawHeader, _ := textproto.ReadHeader(br)
+	envelope := getEnvelope(rawHeader)
+
+	mh := mail.Header{Header: gomessage.Header{Header: rawHeader}}
+	subject, err := mh.Subject()
+	if err != nil {
+		subject = envelope.Subject
+	}
+	base, isReply := baseSubject(subject)
+
+	messageID, _ := mh.MessageID()
+
+	// If there is no References header field, use the first message ID of
+	// In-Reply-To instead
+	refs, _ := mh.MsgIDList("References")
+	if len(refs) == 0 {
+		inReplyTo, _ := mh.MsgIDList("In-Reply-To")
+		if len(inReplyTo) > 0 {
+			refs = inReplyTo[:1]
+		}
+	}
+
+	return &threadMessage{
+		num:       num,
+		date:      msg.sentDate(envelope),
+		subject:   strings.ToUpper(base),
+		isReply:   isReply,
+		messageID: messageID,
+		refs:      refs,
+	}
+}
+
+func threadMessageLess(a, b *threadMessage) bool {
+	if !a.date.Equal(b.date) {
+		return a.date.Before(b.date)
+	}
+	return a.num < b.num
+}
+
+type threadContainer struct {
+	msg      *threadMessage // nil for dummy containers
+	parent   *threadContainer
+	children []*threadContainer
+}
+
+func (c *threadContainer) addChild(child *threadContainer) {
+	child.parent = c
+	c.children = append(c.children, child)
+}
+
+func (c *threadContainer) removeChild(child *threadContainer) {
+	for i, other := range c.children {
+		if other == child {
+			c.children = append(c.children[:i], c.children[i+1:]...)
+			break
+		}
+	}
+	child.parent = nil
+}
+
+// isAncestorOf returns true if c is other or one of its ancestors.
+func (c *threadContainer) isAncestorOf(other *threadContainer) bool {
+	for ; other != nil; other = other.parent {
+		if other == c {
+			return true
+		}
+	}
+	return false
+}
+
+// firstMessage returns the message of the container, or the first message of
+// its children for dummy containers.
+func (c *threadContainer) firstMessage() *threadMessage {
+	for c.msg == nil {
+		c = c.children[0]
+	}
+	return c.msg
+}
+
+func (c *threadContainer) threadData() imap.ThreadData {
+	var data imap.ThreadData
+	for {
+		if c.msg != nil {
+			data.Chain = append(data.Chain, c.msg.num)
+		}
+		if len(c.children) != 1 {
+			break
+		}
+		c = c.children[0]
+	}
+	for _, child := range c.children {
+		data.SubThreads = append(data.SubThreads, child.threadData())
+	}
+	return data
+}
+
+// sortThreadContainers sorts containers and their children by sent date.
+func sortThreadContainers(l []*threadContainer) {
+	for _, c := range l {
+		sortThreadContainers(c.children)
+	}
+	sort.SliceStable(l, func(i, j int) bool {
+		return threadMessageLess(l[i].firstMessage(), l[j].firstMessage())
+	})
+}
+
+// threadOrderedSubject implements the ORDEREDSUBJECT algorithm, as defined in
+// RFC 5256 section 3.
+func threadOrderedSubject(msgs []*threadMessage) []*threadContainer {
+	sort.SliceStable(msgs, func(i, j int) bool {
+		if msgs[i].subject != msgs[j].subject {
+			return msgs[i].subject < msgs[j].subject
+		}
+		return threadMessageLess(msgs[i], msgs[j])
+	})
+
+	// The first message of each subject is the parent, subsequent messages
+	// are its children
+	var roots []*threadContainer
+	for _, msg := range msgs {
+		c := &threadContainer{msg: msg}
+		if n := len(roots); n > 0 && roots[n-1].msg.subject == msg.subject {
+			roots[n-1].addChild(c)
+		} else {
+			roots = append(roots, c)
+		}
+	}
+
+	sortThreadContainers(roots)
+	return roots
+}
+
+// threadReferences implements the REFERENCES algorithm, as defined in RFC 5256
+// section 3.
+func threadReferences(msgs []*threadMessage) []*threadContainer {
+	// (A) Build the parent/child relationships
+	var containers []*threadContainer
+	byID := make(map[string]*threadContainer)
+	getContainer := func(id string) *threadContainer {
+		c := byID[id]
+		if c == nil {
+			c = &threadContainer{}
+			byID[id] = c
+			containers = append(containers, c)
+		}
+		return c
+	}
+
+	for i, msg := range msgs {
+		id := msg.messageID
+		if c := byID[id]; id == "" || (c != nil && c.msg != nil) {
+			// Missing or duplicate Message-ID, generate a unique one (the NUL
+			// byte can't appear in a real Message-ID)
+			id = "\x00" + strconv.Itoa(i)
+		}
+		c := getContainer(id)
+		c.msg = msg
+
+		var prev *threadContainer
+		for _, ref := range msg.refs {
+			refContainer := getContainer(ref)
+			// Don't change existing links, and don't introduce loops
+			if prev != nil && refContainer.parent == nil && !refContainer.isAncestorOf(prev) {
+				prev.addChild(refContainer)
+			}
+			prev = refContainer
+		}
+
+		// The last reference is the parent of the message
+		if prev != nil && c.isAncestorOf(prev) {
+			prev = nil
+		}
+		if c.parent != nil {
+			c.parent.removeChild(c)
+		}
+		if prev != nil {
+			prev.addChild(c)
+		}
+	}
+
+	// (B) Gather the root set
+	var roots []*threadContainer
+	for _, c := range containers {
+		if c.parent == nil {
+			roots = append(roots, c)
+		}
+	}
+
+	// (C) Prune dummy containers
+	roots = pruneThreadContainers(roots, true)
+
+	// (D) Sort the root set
+	sortThreadContainers(roots)
+
+	// (E) Group the root set by subject
+	subjects := make(map[string]*threadContainer)
+	for _, c := range roots {
+		msg := c.firstMessage()
+		if msg.subject == "" {
+			continue
+		}
+		other := subjects[msg.subject]
+		if other == nil || (other.msg != nil && c.msg == nil) || (other.msg != nil && other.msg.isReply && c.msg != nil && !c.msg.isReply) {
+			subjects[msg.subject] = c
+		}
+	}
+
+	for i, c := range roots {
+		subject := c.firstMessage().subject
+		if subject == "" {
+			continue
+		}
+		other := subjects[subject]
+		if other == c {
+			continue
+		}
+
+		switch {
+		case other.msg == nil && c.msg == nil:
+			for _, child := range c.children {
+				other.addChild(child)
+			}
+		case other.msg == nil, !other.msg.isReply && c.msg != nil && c.msg.isReply:
+			other.addChild(c)
+		default:
+			dummy := &threadContainer{}
+			for j, root := range roots {
+				if root == other {
+					roots[j] = dummy
+				}
+			}
+			subjects[subject] = dummy
+			dummy.addChild(other)
+			dummy.addChild(c)
+		}
+		roots[i] = nil
+	}
+
+	var grouped []*threadContainer
+	for _, c := range roots {
+		if c != nil {
+			grouped = append(grouped, c)
+		}
+	}
+
+	// (F) Sort the children of each thread
+	for _, c := range grouped {
+		sortThreadContainers(c.children)
+	}
+
+	return grouped
+}
+
+// pruneThreadContainers removes dummy containers without children, and
+// promotes the children of dummy containers. Dummy containers in the root set
+// are kept if they have more than one child.
+func pruneThreadContainers(l []*threadContainer, root bool) []*threadContainer {
+	var pruned []*threadContainer
+	for _, c := range l {
+		c.children = pruneThreadContainers(c.children, false)
+		if c.msg == nil {
+			if len(c.children) == 0 {
+				continue
+			} else if !root || len(c.children) == 1 {
+				for _, child := range c.children {
+					child.parent = c.parent
+				}
+				pruned = append(pruned, c.children...)
+				continue
+			}
+		}
+		pruned = append(pruned, c)
+	}
+	return pruned
+}
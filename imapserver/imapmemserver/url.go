@@ -0,0 +1,142 @@
+package imapmemserver
+
+import (
+	"crypto/rand"
+	"strings"
+
+	"github.com/emersion/go-imap/v2"
+)
+
+var errBadURL = &imap.Error{
+	Type: imap.StatusResponseTypeNo,
+	Code: imap.ResponseCodeBadURL,
+	Text: "Invalid URL",
+}
+
+func (sess *UserSession) CatenateURL(url *imap.URL) ([]byte, error) {
+	return sess.user.fetchURL(url, sess.selected())
+}
+
+// fetchURL returns the message section referenced by an IMAP URL. URLs
+// without a mailbox are resolved against the selected mailbox.
+func (u *User) fetchURL(url *imap.URL, selected *Mailbox) ([]byte, error) {
+	if url.User != "" && url.User != u.username {
+		return nil, errBadURL
+	}
+	if url.UID == 0 {
+		return nil, errBadURL
+	}
+
+	mbox := selected
+	if url.Mailbox != "" {
+		var err error
+		if mbox, err = u.mailbox(url.Mailbox); err != nil {
+			return nil, errBadURL
+		}
+	} else if mbox == nil {
+		return nil, errBadURL
+	}
+	if err := mbox.checkRights(u.username, imap.RightRead); err != nil {
+		return nil, err
+	}
+
+	mbox.mutex.Lock()
+	defer mbox.mutex.Unlock()
+
+	if url.UIDValidity != 0 && url.UIDValidity != mbox.uidValidity {
+		return nil, errBadURL
+	}
+
+	section := url.Section
+	if section == nil {
+		section = &imap.FetchItemBodySection{}
+	}
+	for _, msg := range mbox.l {
+		if msg.uid == url.UID {
+			return msg.bodySection(section), nil
+		}
+	}
+	return nil, errBadURL
+}
+
+func (sess *UserSession) URLAuthKey(url *imap.URL, create bool) ([]byte, error) {
+	if url.User != sess.user.username {
+		return nil, errBadURL
+	}
+	return sess.user.urlAuthKey(url.Mailbox, create)
+}
+
+func (sess *UserSession) ResetURLAuthKey(mailbox string) error {
+	return sess.user.resetURLAuthKey(mailbox)
+}
+
+func (sess *UserSession) URLFetch(url *imap.URL) ([]byte, error) {
+	if url.User != sess.user.username {
+		return nil, errBadURL
+	}
+	return sess.user.urlFetch(url, sess.user.username, false)
+}
+
+func (u *User) urlAuthKey(name string, create bool) ([]byte, error) {
+	mbox, err := u.mailbox(name)
+	if err != nil {
+		return nil, err
+	}
+
+	mbox.mutex.Lock()
+	defer mbox.mutex.Unlock()
+
+	if mbox.accessKey == nil && create {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		mbox.accessKey = key
+	}
+	return mbox.accessKey, nil
+}
+
+func (u *User) resetURLAuthKey(name string) error {
+	var l []*Mailbox
+	if name != "" {
+		mbox, err := u.mailbox(name)
+		if err != nil {
+			return err
+		}
+		l = append(l, mbox)
+	} else {
+		u.mutex.Lock()
+		for _, mbox := range u.mailboxes {
+			l = append(l, mbox)
+		}
+		u.mutex.Unlock()
+	}
+
+	for _, mbox := range l {
+		mbox.mutex.Lock()
+		mbox.accessKey = nil
+		mbox.mutex.Unlock()
+	}
+	return nil
+}
+
+// urlFetch fetches a URLAUTH-authorized URL owned by u, on behalf of the user
+// named username. submit indicates whether that user is a submit server.
+func (u *User) urlFetch(url *imap.URL, username string, submit bool) ([]byte, error) {
+	access := strings.ToLower(url.Access)
+	switch {
+	case access == "authuser", access == "anonymous":
+		// allowed
+	case strings.HasPrefix(access, "submit+"):
+		if !submit {
+			return nil, errNoPerm
+		}
+	case strings.HasPrefix(access, "user+"):
+		if url.Access[len("user+"):] != username {
+			return nil, errNoPerm
+		}
+	default:
+		return nil, errBadURL
+	}
+	return u.fetchURL(url, nil)
+}
@@ -1,7 +1,9 @@
 package imapmemserver
 
 import (
+	"bytes"
 	"crypto/subtle"
+	"fmt"
 	"sort"
 	"strings"
 	"sync"
@@ -18,13 +20,19 @@ type User struct {
 	mutex           sync.Mutex
 	mailboxes       map[string]*Mailbox
 	prevUidValidity uint32
+	quotaLimits     map[imap.QuotaResourceType]int64
+	tracker         userTracker
+	// private entries by mailbox name
+	metadata       map[string]map[string][]byte
+	serverMetadata *serverMetadata
 }
 
 func NewUser(username, password string) *User {
 	return &User{
-		username:  username,
-		password:  password,
-		mailboxes: make(map[string]*Mailbox),
+		username:       username,
+		password:       password,
+		mailboxes:      make(map[string]*Mailbox),
+		serverMetadata: new(serverMetadata),
 	}
 }
 
@@ -61,6 +69,9 @@ func (u *User) Status(name string, options *imap.StatusOptions) (*imap.StatusDat
 	if err != nil {
 		return nil, err
 	}
+	if err := mbox.checkRights(u.username, imap.RightRead); err != nil {
+		return nil, err
+	}
 	return mbox.StatusData(options), nil
 }
 
@@ -79,6 +90,10 @@ func (u *User) List(w *imapserver.ListWriter, ref string, patterns []string, opt
 
 	var l []imap.ListData
 	for name, mbox := range u.mailboxes {
+		if !mbox.rights(u.username).Has(imap.RightLookup) {
+			continue
+		}
+
 		match := false
 		for _, pattern := range patterns {
 			match = imapserver.MatchList(name, mailboxDelim, ref, pattern)
@@ -90,7 +105,7 @@ func (u *User) List(w *imapserver.ListWriter, ref string, patterns []string, opt
 			continue
 		}
 
-		data := mbox.list(options)
+		data := mbox.list(u.username, options)
 		if data != nil {
 			l = append(l, *data)
 		}
@@ -118,7 +133,76 @@ func (u *User) Append(mailbox string, r imap.LiteralReader, options *imap.Append
 			Text: "No such mailbox",
 		}
 	}
-	return mbox.appendLiteral(r, options)
+	if err := mbox.checkRights(u.username, imap.RightInsert); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(r); err != nil {
+		return nil, err
+	}
+
+	unlock, err := mbox.lockQuota(1, int64(buf.Len()))
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	return mbox.appendBytes(buf.Bytes(), options), nil
+}
+
+func (u *User) MultiAppend(mailbox string, r *imapserver.MultiAppendReader) (*imap.MultiAppendData, error) {
+	mbox, err := u.mailbox(mailbox)
+	if err != nil {
+		return nil, &imap.Error{
+			Type: imap.StatusResponseTypeNo,
+			Code: imap.ResponseCodeTryCreate,
+			Text: "No such mailbox",
+		}
+	}
+	if err := mbox.checkRights(u.username, imap.RightInsert); err != nil {
+		return nil, err
+	}
+
+	// Read all messages before appending anything, so that the command is
+	// atomic
+	var (
+		bufs    [][]byte
+		options []*imap.AppendOptions
+		size    int64
+	)
+	for {
+		msg, err := r.Next()
+		if err != nil {
+			return nil, err
+		} else if msg == nil {
+			break
+		}
+
+		var buf bytes.Buffer
+		if _, err := buf.ReadFrom(msg.Literal); err != nil {
+			return nil, err
+		}
+		bufs = append(bufs, buf.Bytes())
+		options = append(options, msg.Options)
+		size += int64(buf.Len())
+	}
+
+	unlock, err := mbox.lockQuota(int64(len(bufs)), size)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	mbox.mutex.Lock()
+	defer mbox.mutex.Unlock()
+
+	data := imap.MultiAppendData{UIDValidity: mbox.uidValidity}
+	for i, buf := range bufs {
+		appendData := mbox.appendBytesLocked(buf, options[i])
+		data.UIDs.AddNum(appendData.UID)
+	}
+	return &data, nil
 }
 
 func (u *User) Create(name string, options *imap.CreateOptions) error {
@@ -134,11 +218,93 @@ func (u *User) Create(name string, options *imap.CreateOptions) error {
 			Text: "Mailbox already exists",
 		}
 	}
+	if options != nil {
+		if err := u.checkSpecialUseLocked(options.SpecialUse); err != nil {
+			return err
+		}
+	}
 
 	// UIDVALIDITY must change if a mailbox is deleted and re-created with the
 	// same name.
 	u.prevUidValidity++
-	u.mailboxes[name] = NewMailbox(name, u.prevUidValidity)
+	mbox := NewMailbox(name, u.prevUidValidity)
+	if options != nil {
+		mbox.specialUse = options.SpecialUse
+	}
+	mbox.initACLLocked(u.username)
+	mbox.owner = u
+	mbox.addUserTracker(&u.tracker)
+	u.mailboxes[name] = mbox
+	u.notifyMailboxLocked(imap.NotifyEventMailboxName, &imap.ListData{Mailbox: name})
+	return nil
+}
+
+// AddMailbox adds an existing mailbox to the user.
+//
+// This can be used to share a mailbox between multiple users. Access to the
+// mailbox is controlled by its ACL: a mailbox created with NewMailbox grants
+// all rights to everyone until its ACL is modified. The first user a mailbox
+// is added to becomes its owner, and its messages count towards that user's
+// quota.
+func (u *User) AddMailbox(mbox *Mailbox) error {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	mbox.mutex.Lock()
+	name := mbox.name
+	if mbox.owner == nil {
+		mbox.owner = u
+	}
+	mbox.mutex.Unlock()
+
+	if u.mailboxes[name] != nil {
+		return &imap.Error{
+			Type: imap.StatusResponseTypeNo,
+			Code: imap.ResponseCodeAlreadyExists,
+			Text: "Mailbox already exists",
+		}
+	}
+
+	mbox.addUserTracker(&u.tracker)
+	u.mailboxes[name] = mbox
+	u.notifyMailboxLocked(imap.NotifyEventMailboxName, &imap.ListData{Mailbox: name})
+	return nil
+}
+
+// checkSpecialUseLocked checks that special-use attributes are valid and not
+// already used by another mailbox.
+func (u *User) checkSpecialUseLocked(attrs []imap.MailboxAttr) error {
+	for i, attr := range attrs {
+		switch attr {
+		case imap.MailboxAttrAll, imap.MailboxAttrArchive, imap.MailboxAttrDrafts, imap.MailboxAttrFlagged, imap.MailboxAttrJunk, imap.MailboxAttrSent, imap.MailboxAttrTrash, imap.MailboxAttrImportant:
+			// ok
+		default:
+			return &imap.Error{
+				Type: imap.StatusResponseTypeNo,
+				Code: imap.ResponseCodeUseAttr,
+				Text: fmt.Sprintf("Unsupported special-use attribute %v", attr),
+			}
+		}
+
+		used := false
+		for _, other := range attrs[:i] {
+			if other == attr {
+				used = true
+			}
+		}
+		for _, mbox := range u.mailboxes {
+			if mbox.hasSpecialUse(attr) {
+				used = true
+			}
+		}
+		if used {
+			return &imap.Error{
+				Type: imap.StatusResponseTypeNo,
+				Code: imap.ResponseCodeUseAttr,
+				Text: fmt.Sprintf("Special-use attribute %v is already in use", attr),
+			}
+		}
+	}
 	return nil
 }
 
@@ -146,11 +312,21 @@ func (u *User) Delete(name string) error {
 	u.mutex.Lock()
 	defer u.mutex.Unlock()
 
-	if _, err := u.mailboxLocked(name); err != nil {
+	mbox, err := u.mailboxLocked(name)
+	if err != nil {
+		return err
+	}
+	if err := mbox.checkRights(u.username, imap.RightDeleteMailbox); err != nil {
 		return err
 	}
 
+	mbox.removeUserTracker(&u.tracker)
 	delete(u.mailboxes, name)
+	delete(u.metadata, name)
+	u.notifyMailboxLocked(imap.NotifyEventMailboxName, &imap.ListData{
+		Attrs:   []imap.MailboxAttr{imap.MailboxAttrNonExistent},
+		Mailbox: name,
+	})
 	return nil
 }
 
@@ -164,6 +340,9 @@ func (u *User) Rename(oldName, newName string) error {
 	if err != nil {
 		return err
 	}
+	if err := mbox.checkRights(u.username, imap.RightDeleteMailbox); err != nil {
+		return err
+	}
 
 	if u.mailboxes[newName] != nil {
 		return &imap.Error{
@@ -176,24 +355,40 @@ func (u *User) Rename(oldName, newName string) error {
 	mbox.rename(newName)
 	u.mailboxes[newName] = mbox
 	delete(u.mailboxes, oldName)
+	if entries, ok := u.metadata[oldName]; ok {
+		u.metadata[newName] = entries
+		delete(u.metadata, oldName)
+	}
+	u.notifyMailboxLocked(imap.NotifyEventMailboxName, &imap.ListData{
+		Mailbox: newName,
+		OldName: oldName,
+	})
 	return nil
 }
 
 func (u *User) Subscribe(name string) error {
-	mbox, err := u.mailbox(name)
-	if err != nil {
-		return err
-	}
-	mbox.SetSubscribed(true)
-	return nil
+	return u.setSubscribed(name, true)
 }
 
 func (u *User) Unsubscribe(name string) error {
-	mbox, err := u.mailbox(name)
+	return u.setSubscribed(name, false)
+}
+
+func (u *User) setSubscribed(name string, subscribed bool) error {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	mbox, err := u.mailboxLocked(name)
 	if err != nil {
 		return err
 	}
-	mbox.SetSubscribed(false)
+	mbox.SetSubscribed(subscribed)
+
+	data := imap.ListData{Mailbox: name}
+	if subscribed {
+		data.Attrs = []imap.MailboxAttr{imap.MailboxAttrSubscribed}
+	}
+	u.notifyMailboxLocked(imap.NotifyEventSubscriptionChange, &data)
 	return nil
 }
 
@@ -136,6 +136,8 @@ func readListCmd(dec *imapwire.Decoder) (ref string, patterns []string, options
 			options.SelectRemote = true
 		case "RECURSIVEMATCH":
 			options.SelectRecursiveMatch = true
+		case "SPECIAL-USE":
+			options.SelectSpecialUse = true
 		default:
 			return newClientBugError("Unknown LIST select option")
 		}
@@ -230,6 +232,8 @@ func readReturnOption(dec *imapwire.Decoder, options *imap.ListOptions, recent *
 		options.ReturnSubscribed = true
 	case "CHILDREN":
 		options.ReturnChildren = true
+	case "SPECIAL-USE":
+		options.ReturnSpecialUse = true
 	case "STATUS":
 		if !dec.ExpectSP() {
 			return dec.Err()
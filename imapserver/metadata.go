@@ -0,0 +1,305 @@
+package imapserver
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/internal/imapwire"
+)
+
+func (c *Conn) handleGetMetadata(tag string, dec *imapwire.Decoder) error {
+	var (
+		options    imap.GetMetadataOptions
+		hasOptions bool
+		mailbox    string
+	)
+	if !dec.ExpectSP() {
+		return dec.Err()
+	}
+	// RFC 5464 places options before the mailbox in the formal syntax, but
+	// after the mailbox in the examples: accept both
+	if isList, err := dec.List(func() error {
+		return readGetMetadataOption(dec, &options)
+	}); err != nil {
+		return err
+	} else if isList {
+		hasOptions = true
+		if !dec.ExpectSP() {
+			return dec.Err()
+		}
+	}
+	if !dec.ExpectMailbox(&mailbox) || !dec.ExpectSP() {
+		return dec.Err()
+	}
+
+	entries, isList, err := readMetadataEntries(dec)
+	if err != nil {
+		return err
+	}
+	// A list followed by another argument is an options list
+	if isList && !hasOptions && dec.SP() {
+		if len(entries)%2 != 0 {
+			return newClientBugError("Missing GETMETADATA option value")
+		}
+		for i := 0; i < len(entries); i += 2 {
+			if err := parseGetMetadataOption(&options, entries[i], entries[i+1]); err != nil {
+				return err
+			}
+		}
+		if entries, _, err = readMetadataEntries(dec); err != nil {
+			return err
+		}
+	}
+
+	if !dec.ExpectCRLF() {
+		return dec.Err()
+	}
+
+	for i, entry := range entries {
+		entry, err := checkMetadataEntry(entry)
+		if err != nil {
+			return err
+		}
+		entries[i] = entry
+	}
+
+	session, err := c.metadataSession(mailbox)
+	if err != nil {
+		return err
+	}
+
+	data, err := session.GetMetadata(mailbox, entries, &options)
+	if err != nil {
+		return err
+	}
+
+	// Values larger than MAXSIZE are omitted, and the size of the largest one
+	// is reported in the LONGENTRIES response code
+	var longEntries uint32
+	if options.MaxSize != nil {
+		for name, value := range data.Entries {
+			if value == nil || len(*value) <= int(*options.MaxSize) {
+				continue
+			}
+			if n := uint32(len(*value)); n > longEntries {
+				longEntries = n
+			}
+			delete(data.Entries, name)
+		}
+	}
+
+	if len(data.Entries) > 0 {
+		if err := c.writeMetadata(mailbox, data.Entries); err != nil {
+			return err
+		}
+	}
+
+	return c.writeGetMetadataOK(tag, longEntries)
+}
+
+func readMetadataEntries(dec *imapwire.Decoder) (entries []string, isList bool, err error) {
+	isList, err = dec.List(func() error {
+		var s string
+		if !dec.ExpectAString(&s) {
+			return dec.Err()
+		}
+		entries = append(entries, s)
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	} else if !isList {
+		var s string
+		if !dec.ExpectAString(&s) {
+			return nil, false, dec.Err()
+		}
+		entries = append(entries, s)
+	}
+	return entries, isList, nil
+}
+
+func readGetMetadataOption(dec *imapwire.Decoder, options *imap.GetMetadataOptions) error {
+	var name, value string
+	if !dec.ExpectAtom(&name) || !dec.ExpectSP() || !dec.ExpectAtom(&value) {
+		return dec.Err()
+	}
+	return parseGetMetadataOption(options, name, value)
+}
+
+func parseGetMetadataOption(options *imap.GetMetadataOptions, name, value string) error {
+	switch strings.ToUpper(name) {
+	case "MAXSIZE":
+		n, err := parseMetadataNumber(value)
+		if err != nil {
+			return err
+		}
+		options.MaxSize = &n
+	case "DEPTH":
+		switch strings.ToLower(value) {
+		case "0":
+			options.Depth = imap.GetMetadataDepthZero
+		case "1":
+			options.Depth = imap.GetMetadataDepthOne
+		case "infinity":
+			options.Depth = imap.GetMetadataDepthInfinity
+		default:
+			return newClientBugError("Invalid GETMETADATA depth")
+		}
+	default:
+		return newClientBugError("Unknown GETMETADATA option")
+	}
+	return nil
+}
+
+func parseMetadataNumber(s string) (uint32, error) {
+	n, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return 0, newClientBugError("Invalid GETMETADATA MAXSIZE")
+	}
+	return uint32(n), nil
+}
+
+func (c *Conn) handleSetMetadata(dec *imapwire.Decoder) error {
+	var mailbox string
+	if !dec.ExpectSP() || !dec.ExpectMailbox(&mailbox) || !dec.ExpectSP() {
+		return dec.Err()
+	}
+
+	entries := make(map[string]*[]byte)
+	err := dec.ExpectList(func() error {
+		var name string
+		if !dec.ExpectAString(&name) || !dec.ExpectSP() {
+			return dec.Err()
+		}
+		name, err := checkMetadataEntry(name)
+		if err != nil {
+			return err
+		}
+
+		var s string
+		if dec.Atom(&s) {
+			if !dec.Expect(strings.EqualFold(s, "NIL"), "nstring") {
+				return dec.Err()
+			}
+			entries[name] = nil
+		} else if !dec.ExpectString(&s) {
+			return dec.Err()
+		} else {
+			b := []byte(s)
+			entries[name] = &b
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if !dec.ExpectCRLF() {
+		return dec.Err()
+	}
+
+	session, err := c.metadataSession(mailbox)
+	if err != nil {
+		return err
+	}
+
+	return session.SetMetadata(mailbox, entries)
+}
+
+func (c *Conn) metadataSession(mailbox string) (SessionMetadata, error) {
+	if err := c.checkState(imap.ConnStateAuthenticated); err != nil {
+		return nil, err
+	}
+
+	caps := c.server.options.caps()
+	supported := caps.Has(imap.CapMetadata)
+	if mailbox == "" {
+		supported = supported || caps.Has(imap.CapMetadataServer)
+	}
+	session, ok := c.session.(SessionMetadata)
+	if !ok || !supported {
+		return nil, newClientBugError("METADATA is not supported")
+	}
+	return session, nil
+}
+
+// checkMetadataEntry validates and normalizes a metadata entry name.
+func checkMetadataEntry(name string) (string, error) {
+	// Entry names are case-insensitive
+	name = strings.ToLower(name)
+
+	var rest string
+	switch {
+	case strings.HasPrefix(name, "/private"):
+		rest = strings.TrimPrefix(name, "/private")
+	case strings.HasPrefix(name, "/shared"):
+		rest = strings.TrimPrefix(name, "/shared")
+	default:
+		return "", newClientBugError("Metadata entry names must start with /private or /shared")
+	}
+	if rest != "" && !strings.HasPrefix(rest, "/") {
+		return "", newClientBugError("Metadata entry names must start with /private or /shared")
+	}
+	if strings.HasSuffix(name, "/") || strings.Contains(name, "//") || strings.ContainsAny(name, "*%") {
+		return "", newClientBugError("Invalid metadata entry name")
+	}
+	for _, ch := range []byte(name) {
+		if ch < 0x20 || ch == 0x7F {
+			return "", newClientBugError("Invalid metadata entry name")
+		}
+	}
+	return name, nil
+}
+
+func (c *Conn) writeMetadata(mailbox string, entries map[string]*[]byte) error {
+	enc := newResponseEncoder(c)
+	defer enc.end()
+
+	var names []string
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	enc.Atom("*").SP().Atom("METADATA").SP().Mailbox(mailbox).SP()
+	listEnc := enc.BeginList()
+	for _, name := range names {
+		listEnc.Item().String(name).SP()
+		if value := entries[name]; value != nil {
+			enc.String(string(*value))
+		} else {
+			enc.NIL()
+		}
+	}
+	listEnc.End()
+	return enc.CRLF()
+}
+
+func (c *Conn) writeMetadataEntryList(mailbox string, entries []string) error {
+	enc := newResponseEncoder(c)
+	defer enc.end()
+
+	enc.Atom("*").SP().Atom("METADATA").SP().Mailbox(mailbox)
+	for _, name := range entries {
+		enc.SP().String(name)
+	}
+	return enc.CRLF()
+}
+
+func (c *Conn) writeGetMetadataOK(tag string, longEntries uint32) error {
+	if err := c.poll("GETMETADATA"); err != nil {
+		return err
+	}
+
+	enc := newResponseEncoder(c)
+	defer enc.end()
+
+	enc.Atom(tag).SP().Atom("OK").SP()
+	if longEntries > 0 {
+		enc.Special('[').Atom("METADATA").SP().Atom("LONGENTRIES").SP().Number(longEntries).Special(']').SP()
+	}
+	enc.Text("GETMETADATA completed")
+	return enc.CRLF()
+}
@@ -13,6 +13,9 @@ func (c *Conn) handleMove(dec *imapwire.Decoder, numKind NumKind) error {
 	if err := c.checkState(imap.ConnStateSelected); err != nil {
 		return err
 	}
+	if err := c.checkUIDOnly(numKind); err != nil {
+		return err
+	}
 	session, ok := c.session.(SessionMove)
 	if !ok {
 		return newClientBugError("MOVE is not supported")
@@ -38,3 +41,11 @@ func (w *MoveWriter) WriteCopyData(data *imap.CopyData) error {
 func (w *MoveWriter) WriteExpunge(seqNum uint32) error {
 	return w.conn.writeExpunge(seqNum)
 }
+
+// WriteExpungeUID is like WriteExpunge, but also provides the message's UID.
+//
+// A VANISHED response is written instead of EXPUNGE if the client has enabled
+// QRESYNC or UIDONLY.
+func (w *MoveWriter) WriteExpungeUID(seqNum uint32, uid imap.UID) error {
+	return w.conn.writeExpungeUID(seqNum, uid)
+}
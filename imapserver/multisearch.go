@@ -0,0 +1,230 @@
+package imapserver
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/internal/imapwire"
+)
+
+func (c *Conn) handleMultiSearch(tag string, dec *imapwire.Decoder) error {
+	if !dec.ExpectSP() {
+		return dec.Err()
+	}
+
+	var (
+		atom    string
+		sources []imap.MultiSearchSource
+		options imap.SearchOptions
+	)
+	maybeReadSearchKeyAtom(dec, &atom)
+	if strings.EqualFold(atom, "IN") {
+		if !dec.ExpectSP() {
+			return dec.Err()
+		}
+		var err error
+		sources, err = readMultiSearchSources(dec)
+		if err != nil {
+			return fmt.Errorf("in esearch-source-opts: %w", err)
+		}
+		if !dec.ExpectSP() {
+			return dec.Err()
+		}
+		atom = ""
+		maybeReadSearchKeyAtom(dec, &atom)
+	}
+	if strings.EqualFold(atom, "RETURN") {
+		if err := readSearchReturnOpts(dec, &options); err != nil {
+			return fmt.Errorf("in search-return-opts: %w", err)
+		}
+		if !dec.ExpectSP() {
+			return dec.Err()
+		}
+		atom = ""
+		maybeReadSearchKeyAtom(dec, &atom)
+	}
+	if strings.EqualFold(atom, "CHARSET") {
+		var charset string
+		if !dec.ExpectSP() || !dec.ExpectAString(&charset) || !dec.ExpectSP() {
+			return dec.Err()
+		}
+		if err := checkSearchCharset(charset); err != nil {
+			return err
+		}
+		atom = ""
+		maybeReadSearchKeyAtom(dec, &atom)
+	}
+
+	var criteria imap.SearchCriteria
+	if err := readSearchCriteria(&criteria, dec, atom); err != nil {
+		return err
+	}
+
+	if !dec.ExpectCRLF() {
+		return dec.Err()
+	}
+
+	// Without any source, the selected mailbox is searched
+	state := imap.ConnStateAuthenticated
+	if len(sources) == 0 {
+		state = imap.ConnStateSelected
+	}
+	onlySelected := true
+	for _, source := range sources {
+		if source.Filter == imap.MultiSearchFilterSelected {
+			state = imap.ConnStateSelected
+		} else {
+			onlySelected = false
+		}
+	}
+	if err := c.checkState(state); err != nil {
+		return err
+	}
+	// Message sequence numbers and the saved result refer to the selected
+	// mailbox (RFC 7377 section 2)
+	if !onlySelected && (searchCriteriaHasSeqNum(&criteria) || searchCriteriaHasSearchRes(&criteria)) {
+		return newClientBugError("Message sequence numbers and $ can only be used with the selected mailbox")
+	}
+	if err := c.expandSearchFilters(&criteria); err != nil {
+		return err
+	}
+	if err := c.checkUIDOnlySearch(&criteria); err != nil {
+		return err
+	}
+
+	session, ok := c.session.(SessionMultiSearch)
+	if !ok || !c.server.options.caps().Has(imap.CapMultiSearch) {
+		return newClientBugError("MULTISEARCH is not supported")
+	}
+	if options.ReturnSave || options.ReturnUpdate {
+		return newClientBugError("SAVE and UPDATE are not supported with ESEARCH")
+	}
+
+	if !options.ReturnMin && !options.ReturnMax && !options.ReturnAll && !options.ReturnCount && options.ReturnPartial == nil {
+		options.ReturnAll = true
+	}
+	if options.ReturnRelevancy {
+		options.ReturnAll = true
+	}
+
+	if searchCriteriaHasModSeq(&criteria) {
+		c.enableCondStore()
+	}
+
+	w := &MultiSearchWriter{conn: c, tag: tag, options: &options}
+	return session.MultiSearch(w, sources, &criteria, &options)
+}
+
+func readMultiSearchSources(dec *imapwire.Decoder) ([]imap.MultiSearchSource, error) {
+	if !dec.ExpectSpecial('(') {
+		return nil, dec.Err()
+	}
+	var sources []imap.MultiSearchSource
+	for {
+		if dec.Special('(') {
+			return nil, newClientBugError("ESEARCH scope options are not supported")
+		}
+		source, err := readMultiSearchSource(dec)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, *source)
+		if !dec.SP() {
+			break
+		}
+	}
+	if !dec.ExpectSpecial(')') {
+		return nil, dec.Err()
+	}
+	return sources, nil
+}
+
+func readMultiSearchSource(dec *imapwire.Decoder) (*imap.MultiSearchSource, error) {
+	var filter string
+	if !dec.ExpectAtom(&filter) {
+		return nil, dec.Err()
+	}
+	source := imap.MultiSearchSource{Filter: imap.MultiSearchFilter(strings.ToLower(filter))}
+	switch source.Filter {
+	case imap.MultiSearchFilterSelected, imap.MultiSearchFilterInboxes, imap.MultiSearchFilterPersonal, imap.MultiSearchFilterSubscribed:
+		// nothing to do
+	case imap.MultiSearchFilterSubtree, imap.MultiSearchFilterSubtreeOne, imap.MultiSearchFilterMailboxes:
+		if !dec.ExpectSP() {
+			return nil, dec.Err()
+		}
+		isList, err := dec.List(func() error {
+			var mailbox string
+			if !dec.ExpectMailbox(&mailbox) {
+				return dec.Err()
+			}
+			source.Mailboxes = append(source.Mailboxes, mailbox)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		} else if !isList {
+			var mailbox string
+			if !dec.ExpectMailbox(&mailbox) {
+				return nil, dec.Err()
+			}
+			source.Mailboxes = append(source.Mailboxes, mailbox)
+		}
+		if len(source.Mailboxes) == 0 {
+			return nil, newClientBugError("Expected at least one mailbox")
+		}
+	default:
+		return nil, newClientBugError("Unknown ESEARCH source filter")
+	}
+	return &source, nil
+}
+
+// MultiSearchWriter writes ESEARCH responses for the ESEARCH command.
+type MultiSearchWriter struct {
+	conn    *Conn
+	tag     string
+	options *imap.SearchOptions
+}
+
+// WriteSearch writes the search results for a mailbox. Mailboxes without any
+// matching message should be omitted.
+func (w *MultiSearchWriter) WriteSearch(data *imap.MultiSearchData) error {
+	enc := newResponseEncoder(w.conn)
+	defer enc.end()
+
+	enc.Atom("*").SP().Atom("ESEARCH").SP().Special('(')
+	enc.Atom("TAG").SP().String(w.tag)
+	enc.SP().Atom("MAILBOX").SP().Mailbox(data.Mailbox)
+	enc.SP().Atom("UIDVALIDITY").SP().Number(data.UIDValidity)
+	enc.Special(')')
+	searchData := data.Data
+	searchData.UID = true
+	writeESearchData(enc.Encoder, &searchData, w.options)
+	return enc.CRLF()
+}
+
+func searchCriteriaHasSearchRes(criteria *imap.SearchCriteria) bool {
+	for _, uidSet := range criteria.UID {
+		if imap.IsSearchRes(uidSet) {
+			return true
+		}
+	}
+	for i := range criteria.Not {
+		if searchCriteriaHasSearchRes(&criteria.Not[i]) {
+			return true
+		}
+	}
+	for i := range criteria.Or {
+		for j := range criteria.Or[i] {
+			if searchCriteriaHasSearchRes(&criteria.Or[i][j]) {
+				return true
+			}
+		}
+	}
+	for i := range criteria.Fuzzy {
+		if searchCriteriaHasSearchRes(&criteria.Fuzzy[i]) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,193 @@
+package imapserver
+
+import (
+	"strings"
+
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/internal/imapwire"
+)
+
+func (c *Conn) handleNotify(tag string, dec *imapwire.Decoder) error {
+	var options *imap.NotifyOptions
+	var kind string
+	if !dec.ExpectSP() || !dec.ExpectAtom(&kind) {
+		return dec.Err()
+	}
+	switch strings.ToUpper(kind) {
+	case "NONE":
+		// options is nil
+	case "SET":
+		options = new(imap.NotifyOptions)
+		if !dec.ExpectSP() {
+			return dec.Err()
+		}
+		var status string
+		if dec.Atom(&status) {
+			if !strings.EqualFold(status, "STATUS") {
+				return newClientBugError("Unknown NOTIFY SET indicator")
+			}
+			options.Status = true
+			if !dec.ExpectSP() {
+				return dec.Err()
+			}
+		}
+		for {
+			group, err := readNotifyGroup(dec)
+			if err != nil {
+				return err
+			}
+			options.Groups = append(options.Groups, *group)
+			if !dec.SP() {
+				break
+			}
+		}
+	default:
+		return newClientBugError("Expected SET or NONE")
+	}
+
+	if !dec.ExpectCRLF() {
+		return dec.Err()
+	}
+
+	if err := c.checkState(imap.ConnStateAuthenticated); err != nil {
+		return err
+	}
+
+	session, ok := c.session.(SessionNotify)
+	if !ok || !c.server.options.caps().Has(imap.CapNotify) {
+		return newClientBugError("NOTIFY is not supported")
+	}
+
+	if options != nil {
+		supported := session.NotifyEvents()
+		for i := range options.Groups {
+			group := &options.Groups[i]
+			for j, ev := range group.Events {
+				ev, ok := findNotifyEvent(supported, ev)
+				if !ok {
+					return c.writeBadEvent(tag, supported)
+				}
+				group.Events[j] = ev
+			}
+			if err := checkNotifyGroup(group); err != nil {
+				return err
+			}
+		}
+	}
+
+	w := &UpdateWriter{conn: c, allowExpunge: true}
+	if err := session.Notify(w, options); err != nil {
+		return err
+	}
+
+	if err := c.poll("NOTIFY"); err != nil {
+		return err
+	}
+	return c.writeStatusResp(tag, &imap.StatusResponse{
+		Type: imap.StatusResponseTypeOK,
+		Text: "NOTIFY completed",
+	})
+}
+
+func readNotifyGroup(dec *imapwire.Decoder) (*imap.NotifyGroup, error) {
+	var group imap.NotifyGroup
+
+	var filter string
+	if !dec.ExpectSpecial('(') || !dec.ExpectAtom(&filter) {
+		return nil, dec.Err()
+	}
+	group.Filter = imap.NotifyFilter(strings.ToLower(filter))
+	switch group.Filter {
+	case imap.NotifyFilterSelected, imap.NotifyFilterSelectedDelayed, imap.NotifyFilterInboxes, imap.NotifyFilterPersonal, imap.NotifyFilterSubscribed:
+		// nothing to do
+	case imap.NotifyFilterSubtree, imap.NotifyFilterMailboxes:
+		if !dec.ExpectSP() {
+			return nil, dec.Err()
+		}
+		isList, err := dec.List(func() error {
+			var mailbox string
+			if !dec.ExpectMailbox(&mailbox) {
+				return dec.Err()
+			}
+			group.Mailboxes = append(group.Mailboxes, mailbox)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		} else if !isList {
+			var mailbox string
+			if !dec.ExpectMailbox(&mailbox) {
+				return nil, dec.Err()
+			}
+			group.Mailboxes = append(group.Mailboxes, mailbox)
+		}
+		if len(group.Mailboxes) == 0 {
+			return nil, newClientBugError("Expected at least one mailbox")
+		}
+	default:
+		return nil, newClientBugError("Unknown NOTIFY filter")
+	}
+
+	if !dec.ExpectSP() {
+		return nil, dec.Err()
+	}
+
+	var none string
+	if dec.Atom(&none) {
+		if !strings.EqualFold(none, "NONE") {
+			return nil, newClientBugError("Expected event list or NONE")
+		}
+	} else {
+		err := dec.ExpectList(func() error {
+			var ev string
+			if !dec.ExpectAtom(&ev) {
+				return dec.Err()
+			}
+			group.Events = append(group.Events, imap.NotifyEvent(ev))
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !dec.ExpectSpecial(')') {
+		return nil, dec.Err()
+	}
+	return &group, nil
+}
+
+func findNotifyEvent(supported []imap.NotifyEvent, ev imap.NotifyEvent) (imap.NotifyEvent, bool) {
+	for _, s := range supported {
+		if strings.EqualFold(string(s), string(ev)) {
+			return s, true
+		}
+	}
+	return "", false
+}
+
+// checkNotifyGroup checks the dependencies between events, see RFC 5465
+// section 5.
+func checkNotifyGroup(group *imap.NotifyGroup) error {
+	hasMessageNew := group.Has(imap.NotifyEventMessageNew)
+	hasMessageExpunge := group.Has(imap.NotifyEventMessageExpunge)
+	if hasMessageNew != hasMessageExpunge {
+		return newClientBugError("MessageNew and MessageExpunge must be specified together")
+	}
+	if (group.Has(imap.NotifyEventFlagChange) || group.Has(imap.NotifyEventAnnotationChange)) && !hasMessageNew {
+		return newClientBugError("FlagChange and AnnotationChange require MessageNew and MessageExpunge")
+	}
+	return nil
+}
+
+func (c *Conn) writeBadEvent(tag string, supported []imap.NotifyEvent) error {
+	enc := newResponseEncoder(c)
+	defer enc.end()
+
+	enc.Atom(tag).SP().Atom("NO").SP().Special('[').Atom("BADEVENT").SP()
+	enc.List(len(supported), func(i int) {
+		enc.Atom(string(supported[i]))
+	})
+	enc.Special(']').SP().Text("Unsupported NOTIFY event")
+	return enc.CRLF()
+}
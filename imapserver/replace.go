@@ -0,0 +1,106 @@
+package imapserver
+
+import (
+	"io"
+
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/internal/imapwire"
+)
+
+func (c *Conn) handleReplace(tag string, dec *imapwire.Decoder, numKind NumKind) error {
+	var (
+		numSet  imap.NumSet
+		num     uint32
+		mailbox string
+	)
+	if !dec.ExpectSP() {
+		return dec.Err()
+	}
+	switch numKind {
+	case NumKindSeq:
+		if !dec.ExpectNumber(&num) {
+			return dec.Err()
+		}
+		numSet = imap.SeqSetNum(num)
+	case NumKindUID:
+		var uid imap.UID
+		if !dec.ExpectUID(&uid) {
+			return dec.Err()
+		}
+		num = uint32(uid)
+		numSet = imap.UIDSetNum(uid)
+	}
+	if !dec.ExpectSP() || !dec.ExpectMailbox(&mailbox) || !dec.ExpectSP() {
+		return dec.Err()
+	}
+
+	r := &MultiAppendReader{conn: c, dec: dec}
+	msg, err := r.readMessage()
+	if err != nil {
+		return err
+	}
+	r.cur = msg
+
+	c.setReadTimeout(literalReadTimeout)
+	defer c.setReadTimeout(cmdReadTimeout)
+
+	if err := c.checkState(imap.ConnStateSelected); err != nil {
+		r.discard()
+		dec.CRLF()
+		return err
+	}
+	// Zero would be interpreted as "*"
+	if num == 0 {
+		r.discard()
+		dec.CRLF()
+		return newClientBugError("Invalid message number")
+	}
+	if err := c.checkUIDOnly(numKind); err != nil {
+		r.discard()
+		dec.CRLF()
+		return err
+	}
+	session, ok := c.session.(SessionReplace)
+	if !ok || !c.server.options.caps().Has(imap.CapReplace) {
+		r.discard()
+		dec.CRLF()
+		return newClientBugError("REPLACE is not supported")
+	}
+
+	data, replaceErr := session.Replace(numSet, mailbox, msg.Literal, msg.Options)
+	if _, err := io.Copy(io.Discard, msg.Literal); err != nil {
+		return err
+	}
+	if r.dataExt && !dec.ExpectSpecial(')') {
+		return dec.Err()
+	}
+	if !dec.ExpectCRLF() {
+		return dec.Err()
+	}
+	if replaceErr != nil {
+		return replaceErr
+	}
+
+	if data != nil {
+		if err := c.writeReplaceAppendUID(data); err != nil {
+			return err
+		}
+	}
+	if err := c.poll("REPLACE"); err != nil {
+		return err
+	}
+	return c.writeStatusResp(tag, &imap.StatusResponse{
+		Type: imap.StatusResponseTypeOK,
+		Text: "REPLACE completed",
+	})
+}
+
+func (c *Conn) writeReplaceAppendUID(data *imap.AppendData) error {
+	enc := newResponseEncoder(c)
+	defer enc.end()
+
+	enc.Atom("*").SP().Atom("OK").SP().Special('[')
+	enc.Atom("APPENDUID").SP().Number(data.UIDValidity).SP().UID(data.UID)
+	enc.Special(']').SP().Text("Replacement message appended")
+	return enc.CRLF()
+}
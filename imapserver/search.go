@@ -35,36 +35,16 @@ func (c *Conn) handleSearch(tag string, dec *imapwire.Decoder, numKind NumKind)
 		if !dec.ExpectSP() || !dec.ExpectAString(&charset) || !dec.ExpectSP() {
 			return dec.Err()
 		}
-		switch strings.ToUpper(charset) {
-		case "US-ASCII", "UTF-8":
-			// nothing to do
-		default:
-			return &imap.Error{
-				Type: imap.StatusResponseTypeNo,
-				Code: imap.ResponseCodeBadCharset, // TODO: return list of supported charsets
-				Text: "Only US-ASCII and UTF-8 are supported SEARCH charsets",
-			}
+		if err := checkSearchCharset(charset); err != nil {
+			return err
 		}
 		atom = ""
 		maybeReadSearchKeyAtom(dec, &atom)
 	}
 
 	var criteria imap.SearchCriteria
-	for {
-		var err error
-		if atom != "" {
-			err = readSearchKeyWithAtom(&criteria, dec, atom)
-			atom = ""
-		} else {
-			err = readSearchKey(&criteria, dec)
-		}
-		if err != nil {
-			return fmt.Errorf("in search-key: %w", err)
-		}
-
-		if !dec.SP() {
-			break
-		}
+	if err := readSearchCriteria(&criteria, dec, atom); err != nil {
+		return err
 	}
 
 	if !dec.ExpectCRLF() {
@@ -74,22 +54,65 @@ func (c *Conn) handleSearch(tag string, dec *imapwire.Decoder, numKind NumKind)
 	if err := c.checkState(imap.ConnStateSelected); err != nil {
 		return err
 	}
+	if err := c.expandSearchFilters(&criteria); err != nil {
+		return err
+	}
+	if err := c.checkUIDOnly(numKind); err != nil {
+		return err
+	}
+	if err := c.checkUIDOnlySearch(&criteria); err != nil {
+		return err
+	}
 
-	// If no return option is specified, ALL is assumed
-	if !options.ReturnMin && !options.ReturnMax && !options.ReturnAll && !options.ReturnCount {
+	// If no return option is specified, ALL is assumed. Relevancy scores are
+	// meaningless without ALL.
+	if !options.ReturnMin && !options.ReturnMax && !options.ReturnAll && !options.ReturnCount && options.ReturnPartial == nil {
 		options.ReturnAll = true
 	}
+	if options.ReturnRelevancy {
+		options.ReturnAll = true
+	}
+
+	if options.ReturnUpdate && !c.server.options.caps().Has(imap.CapContextSearch) {
+		return newClientBugError("CONTEXT=SEARCH is not supported")
+	}
+
+	if searchCriteriaHasModSeq(&criteria) {
+		c.enableCondStore()
+	}
+
+	// The full result is needed to compute updates
+	searchOptions := options
+	if options.ReturnUpdate {
+		searchOptions.ReturnAll = true
+	}
 
-	data, err := c.session.Search(numKind, &criteria, &options)
+	data, err := c.session.Search(numKind, &criteria, &searchOptions)
 	if err != nil {
 		return err
 	}
 
 	if c.enabled.Has(imap.CapIMAP4rev2) || extended {
-		return c.writeESearch(tag, data, &options)
+		err = c.writeESearch(tag, data, &options)
 	} else {
-		return c.writeSearch(data.All)
+		err = c.writeSearch(data.All, data.ModSeq)
 	}
+	if err != nil {
+		return err
+	}
+
+	if options.ReturnUpdate {
+		nums, err := searchDataNums(data)
+		if err != nil {
+			return err
+		}
+		return c.addSearchContext(&searchContext{
+			tag:      tag,
+			numKind:  numKind,
+			criteria: &criteria,
+		}, nums)
+	}
+	return nil
 }
 
 func (c *Conn) writeESearch(tag string, data *imap.SearchData, options *imap.SearchOptions) error {
@@ -100,6 +123,11 @@ func (c *Conn) writeESearch(tag string, data *imap.SearchData, options *imap.Sea
 	if tag != "" {
 		enc.SP().Special('(').Atom("TAG").SP().Atom(tag).Special(')')
 	}
+	writeESearchData(enc.Encoder, data, options)
+	return enc.CRLF()
+}
+
+func writeESearchData(enc *imapwire.Encoder, data *imap.SearchData, options *imap.SearchOptions) {
 	if data.UID {
 		enc.SP().Atom("UID")
 	}
@@ -117,7 +145,51 @@ func (c *Conn) writeESearch(tag string, data *imap.SearchData, options *imap.Sea
 	if options.ReturnCount {
 		enc.SP().Atom("COUNT").SP().Number(data.Count)
 	}
-	return enc.CRLF()
+	if data.ModSeq > 0 {
+		enc.SP().Atom("MODSEQ").SP().ModSeq(data.ModSeq)
+	}
+	if options.ReturnRelevancy && !isNumSetEmpty(data.All) {
+		enc.SP().Atom("RELEVANCY").SP().List(len(data.Relevancy), func(i int) {
+			enc.Number(uint32(data.Relevancy[i]))
+		})
+	}
+	if options.ReturnPartial != nil {
+		r := *options.ReturnPartial
+		var all imap.NumSet
+		if data.Partial != nil {
+			r = data.Partial.Range
+			all = data.Partial.All
+		}
+		enc.SP().Atom("PARTIAL").SP().Special('(').PartialRange(r).SP()
+		if all == nil || isNumSetEmpty(all) {
+			enc.NIL()
+		} else {
+			enc.NumSet(all)
+		}
+		enc.Special(')')
+	}
+}
+
+// searchDataNums returns the message numbers in the ALL result.
+func searchDataNums(data *imap.SearchData) ([]uint32, error) {
+	var (
+		nums []uint32
+		ok   = true
+	)
+	switch all := data.All.(type) {
+	case imap.SeqSet:
+		nums, ok = all.Nums()
+	case imap.UIDSet:
+		var uids []imap.UID
+		uids, ok = all.Nums()
+		for _, uid := range uids {
+			nums = append(nums, uint32(uid))
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("imapserver: failed to enumerate message numbers in SEARCH result")
+	}
+	return nums, nil
 }
 
 func isNumSetEmpty(numSet imap.NumSet) bool {
@@ -131,7 +203,7 @@ func isNumSetEmpty(numSet imap.NumSet) bool {
 	}
 }
 
-func (c *Conn) writeSearch(numSet imap.NumSet) error {
+func (c *Conn) writeSearch(numSet imap.NumSet, modSeq uint64) error {
 	enc := newResponseEncoder(c)
 	defer enc.end()
 
@@ -154,6 +226,9 @@ func (c *Conn) writeSearch(numSet imap.NumSet) error {
 	if !ok {
 		return fmt.Errorf("imapserver: failed to enumerate message numbers in SEARCH response")
 	}
+	if modSeq > 0 && !isNumSetEmpty(numSet) {
+		enc.SP().Special('(').Atom("MODSEQ").SP().ModSeq(modSeq).Special(')')
+	}
 	return enc.CRLF()
 }
 
@@ -177,6 +252,18 @@ func readSearchReturnOpts(dec *imapwire.Decoder, options *imap.SearchOptions) er
 			options.ReturnCount = true
 		case "SAVE":
 			options.ReturnSave = true
+		case "RELEVANCY":
+			options.ReturnRelevancy = true
+		case "PARTIAL":
+			var r imap.PartialRange
+			if !dec.ExpectSP() || !dec.ExpectPartialRange(&r) {
+				return dec.Err()
+			}
+			options.ReturnPartial = &r
+		case "UPDATE":
+			options.ReturnUpdate = true
+		case "CONTEXT":
+			// Only a hint, ignore it
 		default:
 			return newClientBugError("unknown SEARCH RETURN option")
 		}
@@ -184,6 +271,40 @@ func readSearchReturnOpts(dec *imapwire.Decoder, options *imap.SearchOptions) er
 	})
 }
 
+func checkSearchCharset(charset string) error {
+	switch strings.ToUpper(charset) {
+	case "US-ASCII", "UTF-8":
+		return nil
+	default:
+		return &imap.Error{
+			Type: imap.StatusResponseTypeNo,
+			Code: imap.ResponseCodeBadCharset, // TODO: return list of supported charsets
+			Text: "Only US-ASCII and UTF-8 are supported SEARCH charsets",
+		}
+	}
+}
+
+// readSearchCriteria reads a list of space-separated search keys. If atom is
+// non-empty, it's used as the first search key's atom.
+func readSearchCriteria(criteria *imap.SearchCriteria, dec *imapwire.Decoder, atom string) error {
+	for {
+		var err error
+		if atom != "" {
+			err = readSearchKeyWithAtom(criteria, dec, atom)
+			atom = ""
+		} else {
+			err = readSearchKey(criteria, dec)
+		}
+		if err != nil {
+			return fmt.Errorf("in search-key: %w", err)
+		}
+
+		if !dec.SP() {
+			return nil
+		}
+	}
+}
+
 func maybeReadSearchKeyAtom(dec *imapwire.Decoder, ptr *string) bool {
 	return dec.Func(ptr, func(ch byte) bool {
 		return ch == '*' || imapwire.IsAtomChar(ch)
@@ -253,7 +374,7 @@ func readSearchKeyWithAtom(criteria *imap.SearchCriteria, dec *imapwire.Decoder,
 			Key:   key,
 			Value: value,
 		})
-	case "SINCE", "BEFORE", "ON", "SENTSINCE", "SENTBEFORE", "SENTON":
+	case "SINCE", "BEFORE", "ON", "SENTSINCE", "SENTBEFORE", "SENTON", "SAVEDSINCE", "SAVEDBEFORE", "SAVEDON":
 		if !dec.ExpectSP() {
 			return dec.Err()
 		}
@@ -277,6 +398,13 @@ func readSearchKeyWithAtom(criteria *imap.SearchCriteria, dec *imapwire.Decoder,
 		case "SENTON":
 			dateCriteria.SentSince = t
 			dateCriteria.SentBefore = t.Add(24 * time.Hour)
+		case "SAVEDSINCE":
+			dateCriteria.SavedSince = t
+		case "SAVEDBEFORE":
+			dateCriteria.SavedBefore = t
+		case "SAVEDON":
+			dateCriteria.SavedSince = t
+			dateCriteria.SavedBefore = t.Add(24 * time.Hour)
 		}
 		criteria.And(&dateCriteria)
 	case "BODY":
@@ -291,6 +419,21 @@ func readSearchKeyWithAtom(criteria *imap.SearchCriteria, dec *imapwire.Decoder,
 			return dec.Err()
 		}
 		criteria.Text = append(criteria.Text, text)
+	case "OLDER", "YOUNGER":
+		var n uint32
+		if !dec.ExpectSP() || !dec.ExpectNumber(&n) {
+			return dec.Err()
+		}
+		if n == 0 {
+			return newClientBugError(key + " requires a non-zero interval")
+		}
+		d := time.Duration(n) * time.Second
+		switch key {
+		case "OLDER":
+			criteria.And(&imap.SearchCriteria{Older: d})
+		case "YOUNGER":
+			criteria.And(&imap.SearchCriteria{Younger: d})
+		}
 	case "LARGER", "SMALLER":
 		var n int64
 		if !dec.ExpectSP() || !dec.ExpectNumber64(&n) {
@@ -326,6 +469,50 @@ func readSearchKeyWithAtom(criteria *imap.SearchCriteria, dec *imapwire.Decoder,
 			return nil
 		}
 		criteria.Or = append(criteria.Or, or)
+	case "MODSEQ":
+		var modSeq imap.SearchCriteriaModSeq
+		if !dec.ExpectSP() {
+			return dec.Err()
+		}
+		if dec.String(&modSeq.MetadataName) {
+			var typ string
+			if !dec.ExpectSP() || !dec.ExpectAtom(&typ) || !dec.ExpectSP() {
+				return dec.Err()
+			}
+			modSeq.MetadataType = imap.SearchCriteriaMetadataType(strings.ToLower(typ))
+		}
+		if !dec.ExpectModSeq(&modSeq.ModSeq) {
+			return dec.Err()
+		}
+		criteria.ModSeq = &modSeq
+	case "SAVEDATESUPPORTED":
+		criteria.SaveDateSupported = true
+	case "EMAILID", "THREADID":
+		var id string
+		if !dec.ExpectSP() || !dec.ExpectAtom(&id) {
+			return dec.Err()
+		}
+		switch key {
+		case "EMAILID":
+			criteria.EmailID = append(criteria.EmailID, id)
+		case "THREADID":
+			criteria.ThreadID = append(criteria.ThreadID, id)
+		}
+	case "FILTER":
+		var name string
+		if !dec.ExpectSP() || !dec.ExpectAtom(&name) {
+			return dec.Err()
+		}
+		criteria.Filter = append(criteria.Filter, name)
+	case "FUZZY":
+		if !dec.ExpectSP() {
+			return dec.Err()
+		}
+		var fuzzy imap.SearchCriteria
+		if err := readSearchKey(&fuzzy, dec); err != nil {
+			return err
+		}
+		criteria.Fuzzy = append(criteria.Fuzzy, fuzzy)
 	case "$":
 		criteria.UID = append(criteria.UID, imap.SearchRes())
 	default:
@@ -338,6 +525,28 @@ func readSearchKeyWithAtom(criteria *imap.SearchCriteria, dec *imapwire.Decoder,
 	return nil
 }
 
+func searchCriteriaHasModSeq(criteria *imap.SearchCriteria) bool {
+	if criteria.ModSeq != nil {
+		return true
+	}
+	for i := range criteria.Not {
+		if searchCriteriaHasModSeq(&criteria.Not[i]) {
+			return true
+		}
+	}
+	for i := range criteria.Or {
+		if searchCriteriaHasModSeq(&criteria.Or[i][0]) || searchCriteriaHasModSeq(&criteria.Or[i][1]) {
+			return true
+		}
+	}
+	for i := range criteria.Fuzzy {
+		if searchCriteriaHasModSeq(&criteria.Fuzzy[i]) {
+			return true
+		}
+	}
+	return false
+}
+
 func searchKeyFlag(key string) imap.Flag {
 	return imap.Flag("\\" + strings.Title(strings.ToLower(key)))
 }
@@ -2,6 +2,7 @@ package imapserver
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/emersion/go-imap/v2"
 	"github.com/emersion/go-imap/v2/internal/imapwire"
@@ -9,7 +10,21 @@ import (
 
 func (c *Conn) handleSelect(tag string, dec *imapwire.Decoder, readOnly bool) error {
 	var mailbox string
-	if !dec.ExpectSP() || !dec.ExpectMailbox(&mailbox) || !dec.ExpectCRLF() {
+	if !dec.ExpectSP() || !dec.ExpectMailbox(&mailbox) {
+		return dec.Err()
+	}
+
+	options := imap.SelectOptions{ReadOnly: readOnly}
+	if dec.SP() {
+		err := dec.ExpectList(func() error {
+			return c.readSelectParam(dec, &options)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if !dec.ExpectCRLF() {
 		return dec.Err()
 	}
 
@@ -22,6 +37,7 @@ func (c *Conn) handleSelect(tag string, dec *imapwire.Decoder, readOnly bool) er
 			return err
 		}
 		c.state = imap.ConnStateAuthenticated
+		c.clearSearchContexts()
 		err := c.writeStatusResp("", &imap.StatusResponse{
 			Type: imap.StatusResponseTypeOK,
 			Code: "CLOSED",
@@ -32,7 +48,13 @@ func (c *Conn) handleSelect(tag string, dec *imapwire.Decoder, readOnly bool) er
 		}
 	}
 
-	options := imap.SelectOptions{ReadOnly: readOnly}
+	if options.CondStore {
+		c.enableCondStore()
+	}
+	if options.QResync != nil && !c.qresyncEnabled() {
+		return newClientBugError("QRESYNC must be enabled before being used in SELECT")
+	}
+
 	data, err := c.session.Select(mailbox, &options)
 	if err != nil {
 		return err
@@ -63,10 +85,38 @@ func (c *Conn) handleSelect(tag string, dec *imapwire.Decoder, readOnly bool) er
 			return err
 		}
 	}
+	if c.server.options.caps().Has(imap.CapCondStore) {
+		if err := c.writeHighestModSeq(data.HighestModSeq); err != nil {
+			return err
+		}
+	}
+	if data.MailboxID != "" {
+		if err := c.writeMailboxID(data.MailboxID); err != nil {
+			return err
+		}
+	}
 
 	c.state = imap.ConnStateSelected
 	// TODO: forbid write commands in read-only mode
 
+	if qresync := options.QResync; qresync != nil && qresync.UIDValidity == data.UIDValidity && data.HighestModSeq > 0 {
+		var uids imap.NumSet = qresync.KnownUIDs
+		if qresync.KnownUIDs == nil {
+			uids = imap.UIDSet{imap.UIDRange{Start: 1, Stop: 0}}
+		}
+		w := &FetchWriter{conn: c}
+		err := c.session.Fetch(w, uids, &imap.FetchOptions{
+			UID:          true,
+			Flags:        true,
+			ModSeq:       true,
+			ChangedSince: qresync.ModSeq,
+			Vanished:     true,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
 	var (
 		cmdName string
 		code    imap.ResponseCode
@@ -85,6 +135,74 @@ func (c *Conn) handleSelect(tag string, dec *imapwire.Decoder, readOnly bool) er
 	})
 }
 
+func (c *Conn) readSelectParam(dec *imapwire.Decoder, options *imap.SelectOptions) error {
+	var name string
+	if !dec.ExpectAtom(&name) {
+		return dec.Err()
+	}
+	switch strings.ToUpper(name) {
+	case "CONDSTORE":
+		if !c.server.options.caps().Has(imap.CapCondStore) {
+			return newClientBugError("CONDSTORE is not supported")
+		}
+		options.CondStore = true
+	case "QRESYNC":
+		if !c.server.options.caps().Has(imap.CapQResync) {
+			return newClientBugError("QRESYNC is not supported")
+		}
+		var qresync imap.SelectQResync
+		if !dec.ExpectSP() {
+			return dec.Err()
+		}
+		if err := readSelectQResync(dec, &qresync); err != nil {
+			return err
+		}
+		options.QResync = &qresync
+	default:
+		return newClientBugError("Unknown SELECT parameter")
+	}
+	return nil
+}
+
+func readSelectQResync(dec *imapwire.Decoder, qresync *imap.SelectQResync) error {
+	if !dec.ExpectSpecial('(') || !dec.ExpectNumber(&qresync.UIDValidity) || !dec.ExpectSP() || !dec.ExpectModSeq(&qresync.ModSeq) {
+		return dec.Err()
+	}
+
+	hasSeqMatch := false
+	if dec.SP() {
+		if dec.Special('(') {
+			hasSeqMatch = true
+		} else {
+			if !dec.ExpectUIDSet(&qresync.KnownUIDs) {
+				return dec.Err()
+			}
+			if dec.SP() {
+				if !dec.ExpectSpecial('(') {
+					return dec.Err()
+				}
+				hasSeqMatch = true
+			}
+		}
+	}
+	if hasSeqMatch {
+		var (
+			seqMatch imap.SelectQResyncSeqMatch
+			seqNums  imap.NumSet
+		)
+		if !dec.ExpectNumSet(imapwire.NumKindSeq, &seqNums) || !dec.ExpectSP() || !dec.ExpectUIDSet(&seqMatch.UIDs) || !dec.ExpectSpecial(')') {
+			return dec.Err()
+		}
+		seqMatch.SeqNums = seqNums.(imap.SeqSet)
+		qresync.SeqMatch = &seqMatch
+	}
+
+	if !dec.ExpectSpecial(')') {
+		return dec.Err()
+	}
+	return nil
+}
+
 func (c *Conn) handleUnselect(dec *imapwire.Decoder, expunge bool) error {
 	if !dec.ExpectCRLF() {
 		return dec.Err()
@@ -106,10 +224,12 @@ func (c *Conn) handleUnselect(dec *imapwire.Decoder, expunge bool) error {
 	}
 
 	c.state = imap.ConnStateAuthenticated
+	c.clearSearchContexts()
 	return nil
 }
 
 func (c *Conn) writeExists(numMessages uint32) error {
+	c.invalidateSearchContexts()
 	enc := newResponseEncoder(c)
 	defer enc.end()
 	return enc.Atom("*").SP().Number(numMessages).SP().Atom("EXISTS").CRLF()
@@ -158,3 +278,26 @@ func (c *Conn) writePermanentFlags(flags []imap.Flag) error {
 	enc.SP().Text("Permanent flags")
 	return enc.CRLF()
 }
+
+func (c *Conn) writeHighestModSeq(highestModSeq uint64) error {
+	enc := newResponseEncoder(c)
+	defer enc.end()
+	enc.Atom("*").SP().Atom("OK").SP()
+	if highestModSeq == 0 {
+		enc.Special('[').Atom("NOMODSEQ").Special(']')
+		enc.SP().Text("Mod-sequences are not supported")
+	} else {
+		enc.Special('[').Atom("HIGHESTMODSEQ").SP().ModSeq(highestModSeq).Special(']')
+		enc.SP().Text("Highest mod-sequence")
+	}
+	return enc.CRLF()
+}
+
+func (c *Conn) writeMailboxID(id string) error {
+	enc := newResponseEncoder(c)
+	defer enc.end()
+	enc.Atom("*").SP().Atom("OK").SP()
+	enc.Special('[').Atom("MAILBOXID").SP().Special('(').Atom(id).Special(')').Special(']')
+	enc.SP().Text("Mailbox ID")
+	return enc.CRLF()
+}
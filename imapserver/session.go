@@ -92,6 +92,35 @@ type SessionMove interface {
 	Move(w *MoveWriter, numSet imap.NumSet, dest string) error
 }
 
+// SessionCondStore is an IMAP session which supports CONDSTORE.
+//
+// In addition to implementing StoreUnchangedSince, the session is expected to
+// handle the mod-sequence options in imap.SelectOptions, imap.FetchOptions and
+// imap.SearchCriteria, and to populate HighestModSeq in imap.SelectData and
+// imap.StatusData.
+type SessionCondStore interface {
+	Session
+
+	// Selected state
+
+	// StoreUnchangedSince is like Store, but only alters messages whose
+	// mod-sequence is lower or equal to options.UnchangedSince. The other
+	// messages are left untouched and returned in the modified set.
+	StoreUnchangedSince(w *FetchWriter, numSet imap.NumSet, flags *imap.StoreFlags, options *imap.StoreOptions) (modified imap.NumSet, err error)
+}
+
+// SessionSort is an IMAP session which supports SORT.
+type SessionSort interface {
+	Session
+
+	// Selected state
+
+	// Sort searches messages matching the search criteria, and returns their
+	// message sequence numbers or UIDs (depending on kind) ordered according
+	// to the sort criteria.
+	Sort(kind NumKind, criteria []imap.SortCriterion, searchCriteria *imap.SearchCriteria) ([]uint32, error)
+}
+
 // SessionIMAP4rev2 is an IMAP session which supports IMAP4rev2.
 type SessionIMAP4rev2 interface {
 	Session
@@ -114,3 +143,190 @@ type SessionUnauthenticate interface {
 	// Authenticated state
 	Unauthenticate() error
 }
+
+// SessionThread is an IMAP session which supports THREAD.
+type SessionThread interface {
+	Session
+
+	// Authenticated state
+
+	// ThreadAlgorithms returns the list of supported threading algorithms.
+	ThreadAlgorithms() []imap.ThreadAlgorithm
+
+	// Selected state
+
+	// Thread searches messages matching the search criteria, and returns
+	// their message sequence numbers or UIDs (depending on kind) organized
+	// into threads according to the algorithm.
+	Thread(kind NumKind, algorithm imap.ThreadAlgorithm, searchCriteria *imap.SearchCriteria) ([]imap.ThreadData, error)
+}
+
+// SessionQuota is an IMAP session which supports QUOTA.
+type SessionQuota interface {
+	Session
+
+	// Authenticated state
+
+	// QuotaResourceTypes returns the list of supported resource types.
+	QuotaResourceTypes() []imap.QuotaResourceType
+	GetQuota(root string) (*imap.QuotaData, error)
+	// GetQuotaRoot returns the quota roots of a mailbox, along with their
+	// data.
+	GetQuotaRoot(mailbox string) ([]imap.QuotaData, error)
+}
+
+// SessionQuotaSet is an IMAP session which supports QUOTASET.
+type SessionQuotaSet interface {
+	SessionQuota
+
+	// Authenticated state
+
+	// SetQuota replaces the resource limits of a quota root, and returns the
+	// updated quota root data.
+	SetQuota(root string, limits map[imap.QuotaResourceType]int64) (*imap.QuotaData, error)
+}
+
+// SessionMetadata is an IMAP session which supports METADATA.
+//
+// An empty mailbox name refers to server entries. Entry names are normalized
+// to lower-case and start with either "/private" or "/shared".
+type SessionMetadata interface {
+	Session
+
+	// Authenticated state
+
+	// GetMetadata returns the values of the requested entries. Depending on
+	// options.Depth, descendants of the requested entries are returned as
+	// well. Entries without a value may be returned with a nil value.
+	GetMetadata(mailbox string, entries []string, options *imap.GetMetadataOptions) (*imap.GetMetadataData, error)
+	// SetMetadata sets the values of entries. A nil value removes the entry.
+	SetMetadata(mailbox string, entries map[string]*[]byte) error
+}
+
+// SessionACL is an IMAP session which supports ACL.
+type SessionACL interface {
+	Session
+
+	// Authenticated state
+	SetACL(mailbox string, ri imap.RightsIdentifier, rm imap.RightModification, rs imap.RightSet) error
+	DeleteACL(mailbox string, ri imap.RightsIdentifier) error
+	GetACL(mailbox string) (*imap.GetACLData, error)
+	ListRights(mailbox string, ri imap.RightsIdentifier) (*imap.ListRightsData, error)
+	MyRights(mailbox string) (*imap.MyRightsData, error)
+}
+
+// SessionID is an IMAP session which supports ID.
+type SessionID interface {
+	Session
+
+	// Not authenticated state
+
+	// ID is called when the client sends its ID fields. clientID is nil if
+	// the client didn't disclose any information. The returned fields are
+	// sent back to the client.
+	ID(clientID map[string]string) (serverID map[string]string, err error)
+}
+
+// SessionNotify is an IMAP session which supports NOTIFY.
+//
+// Events for the selected mailbox are delivered as usual. Events for other
+// mailboxes are delivered by Poll and Idle via UpdateWriter.WriteStatus and
+// UpdateWriter.WriteList.
+type SessionNotify interface {
+	Session
+
+	// Authenticated state
+
+	// NotifyEvents returns the list of supported events.
+	NotifyEvents() []imap.NotifyEvent
+	// Notify replaces the events the client is interested in. A nil options
+	// disables notifications. If options.Status is set, a STATUS response
+	// must be written for each mailbox with message events.
+	Notify(w *UpdateWriter, options *imap.NotifyOptions) error
+}
+
+// SessionMultiAppend is an IMAP session which supports MULTIAPPEND.
+//
+// If MULTIAPPEND is advertised, MultiAppend is used instead of Session.Append
+// for all APPEND commands.
+type SessionMultiAppend interface {
+	Session
+
+	// Authenticated state
+
+	// MultiAppend appends all messages read from r to a mailbox. Either all
+	// messages must be appended, or none of them.
+	MultiAppend(mailbox string, r *MultiAppendReader) (*imap.MultiAppendData, error)
+}
+
+// SessionCatenate is an IMAP session which supports CATENATE.
+type SessionCatenate interface {
+	Session
+
+	// Authenticated state
+
+	// CatenateURL returns the contents of the message or message section
+	// referenced by an IMAP URL in an APPEND command.
+	CatenateURL(url *imap.URL) ([]byte, error)
+}
+
+// SessionURLAuth is an IMAP session which supports URLAUTH.
+//
+// URLAUTH tokens are computed by the server with HMAC-SHA256, using mailbox
+// access keys stored by the session.
+type SessionURLAuth interface {
+	Session
+
+	// Authenticated state
+
+	// URLAuthKey returns the access key of the mailbox referenced by a URL.
+	// If create is true, the URL must refer to a mailbox owned by the user,
+	// and a new random key must be generated if the mailbox doesn't have one
+	// yet. Otherwise, nil is returned if the mailbox doesn't have a key.
+	URLAuthKey(url *imap.URL, create bool) ([]byte, error)
+	// ResetURLAuthKey discards the access key of a mailbox owned by the user,
+	// invalidating all URLs authorized with it. If mailbox is empty, the keys
+	// of all mailboxes are discarded.
+	ResetURLAuthKey(mailbox string) error
+	// URLFetch returns the contents referenced by a URL. The URL token has
+	// already been verified, but the session must check that the user is
+	// allowed by the URL access identifier.
+	URLFetch(url *imap.URL) ([]byte, error)
+}
+
+// SessionReplace is an IMAP session which supports REPLACE.
+type SessionReplace interface {
+	Session
+
+	// Selected state
+
+	// Replace appends a message to a mailbox, and expunges the message
+	// referenced by numSet from the selected mailbox. Both operations must
+	// happen atomically. numSet contains a single message.
+	Replace(numSet imap.NumSet, mailbox string, r imap.LiteralReader, options *imap.AppendOptions) (*imap.AppendData, error)
+}
+
+// SessionMultiSearch is an IMAP session which supports MULTISEARCH.
+type SessionMultiSearch interface {
+	Session
+
+	// Authenticated state
+
+	// MultiSearch searches messages in the mailboxes matching sources,
+	// without selecting them, and writes the results for each mailbox. If
+	// sources is empty, the selected mailbox is searched. Messages are
+	// identified by UID.
+	MultiSearch(w *MultiSearchWriter, sources []imap.MultiSearchSource, criteria *imap.SearchCriteria, options *imap.SearchOptions) error
+}
+
+// SessionUIDOnly is an IMAP session which supports UIDONLY.
+//
+// The session must write FETCH responses with FetchWriter.CreateMessageUID
+// and expunge updates with their UID variants, because message sequence
+// numbers can't be sent to clients which have enabled UIDONLY.
+type SessionUIDOnly interface {
+	Session
+
+	// SupportsUIDOnly is a marker method, it is never called.
+	SupportsUIDOnly()
+}
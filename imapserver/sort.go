@@ -0,0 +1,206 @@
+package imapserver
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/internal/imapwire"
+)
+
+func (c *Conn) handleSort(tag string, dec *imapwire.Decoder, numKind NumKind) error {
+	if !dec.ExpectSP() {
+		return dec.Err()
+	}
+
+	var (
+		atom     string
+		options  imap.SearchOptions
+		extended bool
+	)
+	if maybeReadSearchKeyAtom(dec, &atom) {
+		if !strings.EqualFold(atom, "RETURN") {
+			return newClientBugError("Expected RETURN or sort criteria")
+		}
+		if err := readSearchReturnOpts(dec, &options); err != nil {
+			return err
+		}
+		if !dec.ExpectSP() {
+			return dec.Err()
+		}
+		extended = true
+	}
+
+	var sortCriteria []imap.SortCriterion
+	err := dec.ExpectList(func() error {
+		criterion, err := readSortCriterion(dec)
+		if err != nil {
+			return err
+		}
+		sortCriteria = append(sortCriteria, *criterion)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var charset string
+	if !dec.ExpectSP() || !dec.ExpectAString(&charset) || !dec.ExpectSP() {
+		return dec.Err()
+	}
+	if err := checkSearchCharset(charset); err != nil {
+		return err
+	}
+
+	var criteria imap.SearchCriteria
+	if err := readSearchCriteria(&criteria, dec, ""); err != nil {
+		return err
+	}
+
+	if !dec.ExpectCRLF() {
+		return dec.Err()
+	}
+
+	if err := c.checkState(imap.ConnStateSelected); err != nil {
+		return err
+	}
+	if err := c.expandSearchFilters(&criteria); err != nil {
+		return err
+	}
+	if err := c.checkUIDOnly(numKind); err != nil {
+		return err
+	}
+	if err := c.checkUIDOnlySearch(&criteria); err != nil {
+		return err
+	}
+
+	session, ok := c.session.(SessionSort)
+	if !ok {
+		return newClientBugError("SORT is not supported")
+	}
+	if extended && !c.server.options.caps().Has(imap.CapESort) {
+		return newClientBugError("ESORT is not supported")
+	}
+	if options.ReturnSave {
+		return newClientBugError("SAVE is not supported with SORT")
+	}
+	if options.ReturnUpdate && !c.server.options.caps().Has(imap.CapContextSort) {
+		return newClientBugError("CONTEXT=SORT is not supported")
+	}
+
+	nums, err := session.Sort(numKind, sortCriteria, &criteria)
+	if err != nil {
+		return err
+	}
+
+	if extended {
+		// If no return option is specified, ALL is assumed
+		if !options.ReturnMin && !options.ReturnMax && !options.ReturnAll && !options.ReturnCount {
+			options.ReturnAll = true
+		}
+		err = c.writeESort(tag, numKind, nums, &options)
+	} else {
+		err = c.writeSort(nums)
+	}
+	if err != nil {
+		return err
+	}
+
+	if options.ReturnUpdate {
+		return c.addSearchContext(&searchContext{
+			tag:          tag,
+			numKind:      numKind,
+			criteria:     &criteria,
+			sortCriteria: sortCriteria,
+		}, nums)
+	}
+	return nil
+}
+
+func readSortCriterion(dec *imapwire.Decoder) (*imap.SortCriterion, error) {
+	var criterion imap.SortCriterion
+
+	var key string
+	if !dec.ExpectAtom(&key) {
+		return nil, dec.Err()
+	}
+	if strings.EqualFold(key, "REVERSE") {
+		criterion.Reverse = true
+		if !dec.ExpectSP() || !dec.ExpectAtom(&key) {
+			return nil, dec.Err()
+		}
+	}
+
+	switch k := imap.SortKey(strings.ToUpper(key)); k {
+	case imap.SortKeyArrival, imap.SortKeyCc, imap.SortKeyDate, imap.SortKeyFrom, imap.SortKeySize, imap.SortKeySubject, imap.SortKeyTo:
+		criterion.Key = k
+	default:
+		return nil, newClientBugError("Unknown sort key")
+	}
+
+	return &criterion, nil
+}
+
+func (c *Conn) writeSort(nums []uint32) error {
+	enc := newResponseEncoder(c)
+	defer enc.end()
+
+	enc.Atom("*").SP().Atom("SORT")
+	for _, num := range nums {
+		enc.SP().Number(num)
+	}
+	return enc.CRLF()
+}
+
+func (c *Conn) writeESort(tag string, numKind NumKind, nums []uint32, options *imap.SearchOptions) error {
+	enc := newResponseEncoder(c)
+	defer enc.end()
+
+	enc.Atom("*").SP().Atom("ESEARCH")
+	if tag != "" {
+		enc.SP().Special('(').Atom("TAG").SP().Atom(tag).Special(')')
+	}
+	if numKind == NumKindUID {
+		enc.SP().Atom("UID")
+	}
+	if len(nums) > 0 {
+		// MIN and MAX refer to the first and last messages in sort order
+		if options.ReturnMin {
+			enc.SP().Atom("MIN").SP().Number(nums[0])
+		}
+		if options.ReturnMax {
+			enc.SP().Atom("MAX").SP().Number(nums[len(nums)-1])
+		}
+		// ALL needs to preserve the sort order, so we can't use imap.NumSet
+		// here
+		if options.ReturnAll {
+			enc.SP().Atom("ALL").SP().Atom(sortedSeqSetString(nums))
+		}
+	}
+	if options.ReturnCount {
+		enc.SP().Atom("COUNT").SP().Number(uint32(len(nums)))
+	}
+	return enc.CRLF()
+}
+
+// sortedSeqSetString formats a list of numbers into a sequence set, keeping
+// the original order. Consecutive numbers are merged into ranges.
+func sortedSeqSetString(nums []uint32) string {
+	var sb strings.Builder
+	for i := 0; i < len(nums); i++ {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(strconv.FormatUint(uint64(nums[i]), 10))
+		j := i
+		for j+1 < len(nums) && nums[j+1] == nums[j]+1 {
+			j++
+		}
+		if j > i {
+			sb.WriteByte(':')
+			sb.WriteString(strconv.FormatUint(uint64(nums[j]), 10))
+			i = j
+		}
+	}
+	return sb.String()
+}
@@ -36,6 +36,10 @@ func (c *Conn) handleStatus(dec *imapwire.Decoder) error {
 		return err
 	}
 
+	if options.HighestModSeq {
+		c.enableCondStore()
+	}
+
 	data, err := c.session.Status(mailbox, &options)
 	if err != nil {
 		return err
@@ -79,6 +83,12 @@ func (c *Conn) writeStatus(data *imap.StatusData, options *imap.StatusOptions, r
 	if options.DeletedStorage {
 		listEnc.Item().Atom("DELETED-STORAGE").SP().Number64(*data.DeletedStorage)
 	}
+	if options.HighestModSeq {
+		listEnc.Item().Atom("HIGHESTMODSEQ").SP().ModSeq(data.HighestModSeq)
+	}
+	if options.MailboxID {
+		listEnc.Item().Atom("MAILBOXID").SP().Special('(').Atom(data.MailboxID).Special(')')
+	}
 	if recent {
 		listEnc.Item().Atom("RECENT").SP().Number(0)
 	}
@@ -109,6 +119,10 @@ func readStatusItem(dec *imapwire.Decoder, options *imap.StatusOptions) (isRecen
 		options.AppendLimit = true
 	case "DELETED-STORAGE":
 		options.DeletedStorage = true
+	case "HIGHESTMODSEQ":
+		options.HighestModSeq = true
+	case "MAILBOXID":
+		options.MailboxID = true
 	case "RECENT":
 		isRecent = true
 	default:
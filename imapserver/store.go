@@ -8,16 +8,29 @@ import (
 	"github.com/emersion/go-imap/v2/internal/imapwire"
 )
 
-func (c *Conn) handleStore(dec *imapwire.Decoder, numKind NumKind) error {
+func (c *Conn) handleStore(tag string, dec *imapwire.Decoder, numKind NumKind) error {
 	var (
-		numSet imap.NumSet
-		item   string
+		numSet            imap.NumSet
+		item              string
+		options           imap.StoreOptions
+		hasUnchangedSince bool // UNCHANGEDSINCE 0 is valid
 	)
-	if !dec.ExpectSP() || !dec.ExpectNumSet(numKind.wire(), &numSet) || !dec.ExpectSP() || !dec.ExpectAtom(&item) || !dec.ExpectSP() {
+	if !dec.ExpectSP() || !dec.ExpectNumSet(numKind.wire(), &numSet) || !dec.ExpectSP() {
 		return dec.Err()
 	}
-	var flags []imap.Flag
 	isList, err := dec.List(func() error {
+		return c.readStoreModifier(dec, &options, &hasUnchangedSince)
+	})
+	if err != nil {
+		return err
+	} else if isList && !dec.ExpectSP() {
+		return dec.Err()
+	}
+	if !dec.ExpectAtom(&item) || !dec.ExpectSP() {
+		return dec.Err()
+	}
+	var flags []imap.Flag
+	isList, err = dec.List(func() error {
 		flag, err := internal.ExpectFlag(dec)
 		if err != nil {
 			return err
@@ -67,12 +80,76 @@ func (c *Conn) handleStore(dec *imapwire.Decoder, numKind NumKind) error {
 	if err := c.checkState(imap.ConnStateSelected); err != nil {
 		return err
 	}
+	if err := c.checkUIDOnly(numKind); err != nil {
+		return err
+	}
 
-	w := &FetchWriter{conn: c}
-	options := imap.StoreOptions{}
-	return c.session.Store(w, numSet, &imap.StoreFlags{
+	storeFlags := &imap.StoreFlags{
 		Op:     op,
 		Silent: silent,
 		Flags:  flags,
-	}, &options)
+	}
+	w := &FetchWriter{conn: c}
+	if !hasUnchangedSince {
+		if err := c.session.Store(w, numSet, storeFlags, &options); err != nil {
+			return err
+		}
+		// Flag changes made by this connection aren't reported by the session
+		c.invalidateSearchContexts()
+		return c.writeStoreOK(tag, numKind, nil)
+	}
+
+	c.enableCondStore()
+	session, ok := c.session.(SessionCondStore)
+	if !ok {
+		return newClientBugError("CONDSTORE is not supported")
+	}
+	modified, err := session.StoreUnchangedSince(w, numSet, storeFlags, &options)
+	if err != nil {
+		return err
+	}
+	c.invalidateSearchContexts()
+	return c.writeStoreOK(tag, numKind, modified)
+}
+
+func (c *Conn) readStoreModifier(dec *imapwire.Decoder, options *imap.StoreOptions, hasUnchangedSince *bool) error {
+	var name string
+	if !dec.ExpectAtom(&name) || !dec.ExpectSP() {
+		return dec.Err()
+	}
+	switch strings.ToUpper(name) {
+	case "UNCHANGEDSINCE":
+		if !c.server.options.caps().Has(imap.CapCondStore) {
+			return newClientBugError("CONDSTORE is not supported")
+		}
+		if !dec.ExpectModSeq(&options.UnchangedSince) {
+			return dec.Err()
+		}
+		*hasUnchangedSince = true
+	default:
+		return newClientBugError("Unknown STORE modifier")
+	}
+	return nil
+}
+
+func (c *Conn) writeStoreOK(tag string, numKind NumKind, modified imap.NumSet) error {
+	cmdName := "STORE"
+	if numKind == NumKindUID {
+		cmdName = "UID STORE"
+	}
+	if err := c.poll(cmdName); err != nil {
+		return err
+	}
+
+	enc := newResponseEncoder(c)
+	defer enc.end()
+
+	enc.Atom(tag).SP().Atom("OK").SP()
+	if modified != nil && !isNumSetEmpty(modified) {
+		enc.Special('[').Atom("MODIFIED").SP().NumSet(modified).Special(']').SP()
+		enc.Text("Conditional STORE failed")
+	} else {
+		enc.Text(cmdName + " completed")
+	}
+	return enc.CRLF()
 }
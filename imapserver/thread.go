@@ -0,0 +1,112 @@
+package imapserver
+
+import (
+	"strings"
+
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/internal/imapwire"
+)
+
+func (c *Conn) handleThread(dec *imapwire.Decoder, numKind NumKind) error {
+	var algorithmStr, charset string
+	if !dec.ExpectSP() || !dec.ExpectAtom(&algorithmStr) || !dec.ExpectSP() || !dec.ExpectAString(&charset) || !dec.ExpectSP() {
+		return dec.Err()
+	}
+	if err := checkSearchCharset(charset); err != nil {
+		return err
+	}
+
+	var criteria imap.SearchCriteria
+	if err := readSearchCriteria(&criteria, dec, ""); err != nil {
+		return err
+	}
+
+	if !dec.ExpectCRLF() {
+		return dec.Err()
+	}
+
+	if err := c.checkState(imap.ConnStateSelected); err != nil {
+		return err
+	}
+	if err := c.expandSearchFilters(&criteria); err != nil {
+		return err
+	}
+	if err := c.checkUIDOnly(numKind); err != nil {
+		return err
+	}
+	if err := c.checkUIDOnlySearch(&criteria); err != nil {
+		return err
+	}
+
+	session, ok := c.session.(SessionThread)
+	if !ok {
+		return newClientBugError("THREAD is not supported")
+	}
+
+	algorithm := imap.ThreadAlgorithm(strings.ToUpper(algorithmStr))
+	supported := false
+	for _, alg := range c.threadAlgorithms() {
+		if alg == algorithm {
+			supported = true
+			break
+		}
+	}
+	if !supported {
+		return newClientBugError("Unsupported threading algorithm")
+	}
+
+	threads, err := session.Thread(numKind, algorithm, &criteria)
+	if err != nil {
+		return err
+	}
+
+	return c.writeThread(threads)
+}
+
+// threadAlgorithms returns the threading algorithms supported by both the
+// session and the server capabilities.
+func (c *Conn) threadAlgorithms() []imap.ThreadAlgorithm {
+	session, ok := c.session.(SessionThread)
+	if !ok {
+		return nil
+	}
+	available := c.server.options.caps()
+	var l []imap.ThreadAlgorithm
+	for _, alg := range session.ThreadAlgorithms() {
+		if available.Has(imap.Cap("THREAD=" + alg)) {
+			l = append(l, alg)
+		}
+	}
+	return l
+}
+
+func (c *Conn) writeThread(threads []imap.ThreadData) error {
+	enc := newResponseEncoder(c)
+	defer enc.end()
+
+	enc.Atom("*").SP().Atom("THREAD")
+	if len(threads) > 0 {
+		enc.SP()
+	}
+	for i := range threads {
+		writeThreadList(enc.Encoder, &threads[i])
+	}
+	return enc.CRLF()
+}
+
+func writeThreadList(enc *imapwire.Encoder, data *imap.ThreadData) {
+	enc.Special('(')
+	for i, num := range data.Chain {
+		if i > 0 {
+			enc.SP()
+		}
+		enc.Number(num)
+	}
+	if len(data.Chain) > 0 && len(data.SubThreads) > 0 {
+		enc.SP()
+	}
+	for i := range data.SubThreads {
+		writeThreadList(enc, &data.SubThreads[i])
+	}
+	enc.Special(')')
+}
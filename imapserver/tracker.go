@@ -16,6 +16,18 @@ type MailboxTracker struct {
 	mutex       sync.Mutex
 	numMessages uint32
 	sessions    map[*SessionTracker]struct{}
+	vanished    []trackerVanished
+	// highest mod-sequence of the entries dropped from vanished
+	vanishedTrimmedModSeq uint64
+}
+
+// maxTrackerVanished is the maximum number of expunged UIDs remembered by a
+// MailboxTracker.
+const maxTrackerVanished = 1024
+
+type trackerVanished struct {
+	uid    imap.UID
+	modSeq uint64
 }
 
 // NewMailboxTracker creates a new mailbox tracker.
@@ -65,6 +77,9 @@ func (t *MailboxTracker) queueUpdate(update *trackerUpdate, source *SessionTrack
 }
 
 // QueueExpunge queues a new EXPUNGE update.
+//
+// Sessions which have enabled UIDONLY can't receive this update, use
+// QueueExpungeUID instead.
 func (t *MailboxTracker) QueueExpunge(seqNum uint32) {
 	if seqNum == 0 {
 		panic("imapserver: invalid expunge message sequence number")
@@ -72,6 +87,51 @@ func (t *MailboxTracker) QueueExpunge(seqNum uint32) {
 	t.queueUpdate(&trackerUpdate{expunge: seqNum}, nil)
 }
 
+// QueueExpungeUID queues a new EXPUNGE update for a message with a known UID.
+//
+// Sessions which have enabled QRESYNC or UIDONLY will receive a VANISHED
+// response instead. The UID is remembered along with the mod-sequence of the expunge
+// operation, see ExpungedSince.
+func (t *MailboxTracker) QueueExpungeUID(seqNum uint32, uid imap.UID, modSeq uint64) {
+	if seqNum == 0 || uid == 0 {
+		panic("imapserver: invalid expunge message sequence number or UID")
+	}
+	t.mutex.Lock()
+	t.vanished = append(t.vanished, trackerVanished{uid: uid, modSeq: modSeq})
+	if n := len(t.vanished) - maxTrackerVanished; n > 0 {
+		for _, v := range t.vanished[:n] {
+			if v.modSeq > t.vanishedTrimmedModSeq {
+				t.vanishedTrimmedModSeq = v.modSeq
+			}
+		}
+		t.vanished = append([]trackerVanished(nil), t.vanished[n:]...)
+	}
+	t.mutex.Unlock()
+	t.queueUpdate(&trackerUpdate{expunge: seqNum, expungeUID: uid}, nil)
+}
+
+// ExpungedSince returns the UIDs of the messages which have been expunged with
+// QueueExpungeUID and a mod-sequence strictly greater than modSeq.
+//
+// Only a limited number of expunged UIDs are remembered. If some of the
+// messages expunged since modSeq have been forgotten, false is returned.
+func (t *MailboxTracker) ExpungedSince(modSeq uint64) (imap.UIDSet, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if modSeq < t.vanishedTrimmedModSeq {
+		return nil, false
+	}
+
+	var uids imap.UIDSet
+	for _, v := range t.vanished {
+		if v.modSeq > modSeq {
+			uids.AddNum(v.uid)
+		}
+	}
+	return uids, true
+}
+
 // QueueNumMessages queues a new EXISTS update.
 func (t *MailboxTracker) QueueNumMessages(n uint32) {
 	// TODO: merge consecutive NumMessages updates
@@ -90,24 +150,52 @@ func (t *MailboxTracker) QueueMailboxFlags(flags []imap.Flag) {
 //
 // If source is not nil, the update won't be dispatched to it.
 func (t *MailboxTracker) QueueMessageFlags(seqNum uint32, uid imap.UID, flags []imap.Flag, source *SessionTracker) {
+	t.QueueMessageFlagsModSeq(seqNum, uid, flags, 0, source)
+}
+
+// QueueMessageFlagsModSeq queues a new FETCH FLAGS update with the message's
+// new mod-sequence.
+//
+// If source is not nil, the update won't be dispatched to it.
+func (t *MailboxTracker) QueueMessageFlagsModSeq(seqNum uint32, uid imap.UID, flags []imap.Flag, modSeq uint64, source *SessionTracker) {
 	t.queueUpdate(&trackerUpdate{fetch: &trackerUpdateFetch{
 		seqNum: seqNum,
 		uid:    uid,
 		flags:  flags,
+		modSeq: modSeq,
+	}}, source)
+}
+
+// QueueMetadata queues a new METADATA update, indicating that the values of
+// some mailbox entries have changed.
+//
+// If source is not nil, the update won't be dispatched to it.
+func (t *MailboxTracker) QueueMetadata(mailbox string, entries []string, source *SessionTracker) {
+	t.queueUpdate(&trackerUpdate{metadata: &trackerUpdateMetadata{
+		mailbox: mailbox,
+		entries: entries,
 	}}, source)
 }
 
 type trackerUpdate struct {
 	expunge      uint32
+	expungeUID   imap.UID
 	numMessages  uint32
 	mailboxFlags []imap.Flag
 	fetch        *trackerUpdateFetch
+	metadata     *trackerUpdateMetadata
 }
 
 type trackerUpdateFetch struct {
 	seqNum uint32
 	uid    imap.UID
 	flags  []imap.Flag
+	modSeq uint64
+}
+
+type trackerUpdateMetadata struct {
+	mailbox string
+	entries []string
 }
 
 // SessionTracker tracks the state of a mailbox for an IMAP client.
@@ -171,6 +259,8 @@ func (t *SessionTracker) Poll(w *UpdateWriter, allowExpunge bool) error {
 	for _, update := range updates {
 		var err error
 		switch {
+		case update.expunge != 0 && update.expungeUID != 0:
+			err = w.WriteExpungeUID(update.expunge, update.expungeUID)
 		case update.expunge != 0:
 			err = w.WriteExpunge(update.expunge)
 		case update.numMessages != 0:
@@ -178,7 +268,9 @@ func (t *SessionTracker) Poll(w *UpdateWriter, allowExpunge bool) error {
 		case update.mailboxFlags != nil:
 			err = w.WriteMailboxFlags(update.mailboxFlags)
 		case update.fetch != nil:
-			err = w.WriteMessageFlags(update.fetch.seqNum, update.fetch.uid, update.fetch.flags)
+			err = w.WriteMessageFlagsModSeq(update.fetch.seqNum, update.fetch.uid, update.fetch.flags, update.fetch.modSeq)
+		case update.metadata != nil:
+			err = w.WriteMetadata(update.metadata.mailbox, update.metadata.entries)
 		default:
 			panic(fmt.Errorf("imapserver: unknown tracker update %#v", update))
 		}
@@ -218,6 +310,9 @@ func (t *SessionTracker) Idle(w *UpdateWriter, stop <-chan struct{}) error {
 			if err := t.Poll(w, true); err != nil {
 				return err
 			}
+			if err := w.conn.updateSearchContexts(); err != nil {
+				return err
+			}
 		case <-stop:
 			return nil
 		}
@@ -3,6 +3,7 @@ package imapserver_test
 import (
 	"testing"
 
+	"github.com/emersion/go-imap/v2"
 	"github.com/emersion/go-imap/v2/imapserver"
 )
 
@@ -153,3 +154,18 @@ func TestSessionTracker(t *testing.T) {
 		})
 	}
 }
+
+func TestMailboxTracker_expungedSince(t *testing.T) {
+	const n = 2000
+	mboxTracker := imapserver.NewMailboxTracker(n)
+	for i := uint32(1); i <= n; i++ {
+		mboxTracker.QueueExpungeUID(n+1-i, imap.UID(i), uint64(i))
+	}
+
+	if uids, ok := mboxTracker.ExpungedSince(n - 10); !ok || uids.String() != "1991:2000" {
+		t.Errorf("ExpungedSince(%v) = %v, %v, want 1991:2000, true", n-10, uids, ok)
+	}
+	if _, ok := mboxTracker.ExpungedSince(1); ok {
+		t.Errorf("ExpungedSince(1) succeeded, want incomplete history")
+	}
+}
@@ -0,0 +1,52 @@
+package imapserver
+
+import (
+	"github.com/emersion/go-imap/v2"
+)
+
+var errUIDRequired = &imap.Error{
+	Type: imap.StatusResponseTypeBad,
+	Code: imap.ResponseCodeUIDRequired,
+	Text: "Message sequence numbers cannot be used with UIDONLY",
+}
+
+// checkUIDOnly returns an error if the client has enabled UIDONLY and the
+// command refers to messages by sequence number.
+func (c *Conn) checkUIDOnly(numKind NumKind) error {
+	if numKind == NumKindSeq && c.uidOnlyEnabled() {
+		return errUIDRequired
+	}
+	return nil
+}
+
+// checkUIDOnlySearch is like checkUIDOnly, but checks search criteria.
+func (c *Conn) checkUIDOnlySearch(criteria *imap.SearchCriteria) error {
+	if searchCriteriaHasSeqNum(criteria) && c.uidOnlyEnabled() {
+		return errUIDRequired
+	}
+	return nil
+}
+
+func searchCriteriaHasSeqNum(criteria *imap.SearchCriteria) bool {
+	if len(criteria.SeqNum) > 0 {
+		return true
+	}
+	for i := range criteria.Not {
+		if searchCriteriaHasSeqNum(&criteria.Not[i]) {
+			return true
+		}
+	}
+	for i := range criteria.Or {
+		for j := range criteria.Or[i] {
+			if searchCriteriaHasSeqNum(&criteria.Or[i][j]) {
+				return true
+			}
+		}
+	}
+	for i := range criteria.Fuzzy {
+		if searchCriteriaHasSeqNum(&criteria.Fuzzy[i]) {
+			return true
+		}
+	}
+	return false
+}
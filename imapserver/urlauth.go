@@ -0,0 +1,236 @@
+package imapserver
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/internal/imapwire"
+)
+
+func (c *Conn) handleGenURLAuth(dec *imapwire.Decoder) error {
+	type genURLAuthReq struct {
+		rump      string
+		mechanism imap.URLAuthMechanism
+	}
+	var reqs []genURLAuthReq
+	for dec.SP() {
+		var req genURLAuthReq
+		var mechanism string
+		if !dec.ExpectAString(&req.rump) || !dec.ExpectSP() || !dec.ExpectAtom(&mechanism) {
+			return dec.Err()
+		}
+		req.mechanism = imap.URLAuthMechanism(strings.ToUpper(mechanism))
+		reqs = append(reqs, req)
+	}
+	if !dec.ExpectCRLF() {
+		return dec.Err()
+	}
+	if len(reqs) == 0 {
+		return newClientBugError("GENURLAUTH requires at least one URL")
+	}
+
+	session, err := c.urlAuthSession()
+	if err != nil {
+		return err
+	}
+
+	urls := make([]string, len(reqs))
+	for i, req := range reqs {
+		if err := checkURLAuthMechanism(req.mechanism); err != nil {
+			return err
+		}
+
+		u, err := imap.ParseURL(req.rump)
+		if err != nil || u.Access == "" || u.Mechanism != "" || u.UID == 0 || urlAuthRump(u) != req.rump {
+			return &imap.Error{
+				Type: imap.StatusResponseTypeBad,
+				Text: "Invalid URLAUTH rump",
+			}
+		}
+
+		key, err := session.URLAuthKey(u, true)
+		if err != nil {
+			return err
+		} else if key == nil {
+			return errors.New("missing URLAUTH access key")
+		}
+
+		urls[i] = req.rump + ":" + string(req.mechanism) + ":" + urlAuthToken(key, req.rump)
+	}
+
+	enc := newResponseEncoder(c)
+	defer enc.end()
+	enc.Atom("*").SP().Atom("GENURLAUTH")
+	for _, url := range urls {
+		enc.SP().String(url)
+	}
+	return enc.CRLF()
+}
+
+func (c *Conn) handleResetKey(dec *imapwire.Decoder) error {
+	var mailbox string
+	if dec.SP() {
+		if !dec.ExpectMailbox(&mailbox) {
+			return dec.Err()
+		}
+		for dec.SP() {
+			var mechanism string
+			if !dec.ExpectAtom(&mechanism) {
+				return dec.Err()
+			}
+			if err := checkURLAuthMechanism(imap.URLAuthMechanism(strings.ToUpper(mechanism))); err != nil {
+				return err
+			}
+		}
+	}
+	if !dec.ExpectCRLF() {
+		return dec.Err()
+	}
+
+	session, err := c.urlAuthSession()
+	if err != nil {
+		return err
+	}
+	return session.ResetURLAuthKey(mailbox)
+}
+
+func (c *Conn) handleURLFetch(dec *imapwire.Decoder) error {
+	var urls []string
+	for dec.SP() {
+		var url string
+		if !dec.ExpectAString(&url) {
+			return dec.Err()
+		}
+		urls = append(urls, url)
+	}
+	if !dec.ExpectCRLF() {
+		return dec.Err()
+	}
+	if len(urls) == 0 {
+		return newClientBugError("URLFETCH requires at least one URL")
+	}
+
+	session, err := c.urlAuthSession()
+	if err != nil {
+		return err
+	}
+
+	for _, url := range urls {
+		b, err := urlFetch(session, url)
+		if err != nil {
+			return err
+		}
+		if err := c.writeURLFetch(url, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Conn) urlAuthSession() (SessionURLAuth, error) {
+	if err := c.checkState(imap.ConnStateAuthenticated); err != nil {
+		return nil, err
+	}
+	session, ok := c.session.(SessionURLAuth)
+	if !ok || !c.server.options.caps().Has(imap.CapURLAuth) {
+		return nil, newClientBugError("URLAUTH is not supported")
+	}
+	return session, nil
+}
+
+func (c *Conn) writeURLFetch(url string, b []byte) error {
+	enc := newResponseEncoder(c)
+	defer enc.end()
+
+	enc.Atom("*").SP().Atom("URLFETCH").SP().String(url).SP()
+	if b == nil {
+		enc.NIL()
+	} else {
+		w := enc.Literal(int64(len(b)))
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+	}
+	return enc.CRLF()
+}
+
+// urlFetch verifies the authorization of a URL and fetches its contents. Nil
+// is returned if the URL is invalid or unauthorized.
+func urlFetch(session SessionURLAuth, rawURL string) ([]byte, error) {
+	u, err := imap.ParseURL(rawURL)
+	if err != nil || u.Mechanism == "" || checkURLAuthMechanism(u.Mechanism) != nil {
+		return nil, nil
+	}
+	if !u.Expire.IsZero() && time.Now().After(u.Expire) {
+		return nil, nil
+	}
+
+	key, err := session.URLAuthKey(u, false)
+	if err != nil || key == nil {
+		return nil, ignoreIMAPError(err)
+	}
+
+	// The token is computed over the URL rump, which is everything before
+	// ":<mechanism>:<token>". The rump must match the parsed URL, so that the
+	// token can't be re-used for another message.
+	i := strings.LastIndexByte(rawURL, ':')
+	i = strings.LastIndexByte(rawURL[:i], ':')
+	rump := rawURL[:i]
+	if rump != urlAuthRump(u) {
+		return nil, nil
+	}
+	if !hmac.Equal([]byte(strings.ToLower(u.Token)), []byte(urlAuthToken(key, rump))) {
+		return nil, nil
+	}
+
+	b, err := session.URLFetch(u)
+	if err != nil {
+		return nil, ignoreIMAPError(err)
+	}
+	if b == nil {
+		b = []byte{}
+	}
+	return b, nil
+}
+
+// ignoreIMAPError returns nil if err is an IMAP error.
+func ignoreIMAPError(err error) error {
+	var imapErr *imap.Error
+	if errors.As(err, &imapErr) {
+		return nil
+	}
+	return err
+}
+
+func checkURLAuthMechanism(mechanism imap.URLAuthMechanism) error {
+	if mechanism != imap.URLAuthMechanismInternal {
+		return &imap.Error{
+			Type: imap.StatusResponseTypeNo,
+			Text: "Unsupported URLAUTH mechanism",
+		}
+	}
+	return nil
+}
+
+// urlAuthRump formats the rump of a URLAUTH-authorized URL.
+func urlAuthRump(u *imap.URL) string {
+	rump := *u
+	rump.Mechanism = ""
+	rump.Token = ""
+	return rump.String()
+}
+
+// urlAuthToken computes a URLAUTH token for the INTERNAL mechanism.
+func urlAuthToken(key []byte, rump string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(rump))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,54 @@
+package internal
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+	"net"
+)
+
+// DeflateConn is a connection compressed with DEFLATE, as defined in RFC 4978.
+type DeflateConn struct {
+	net.Conn
+	r io.ReadCloser
+	w *flate.Writer
+}
+
+// NewDeflateConn wraps a connection with DEFLATE compression.
+//
+// buffered contains data which has already been read from the connection but
+// hasn't been consumed yet.
+func NewDeflateConn(conn net.Conn, buffered []byte) *DeflateConn {
+	var r io.Reader = conn
+	if len(buffered) > 0 {
+		r = io.MultiReader(bytes.NewReader(buffered), conn)
+	}
+	w, err := flate.NewWriter(conn, flate.DefaultCompression)
+	if err != nil {
+		panic(err) // unreachable
+	}
+	return &DeflateConn{
+		Conn: conn,
+		r:    flate.NewReader(r),
+		w:    w,
+	}
+}
+
+// NetConn returns the underlying connection.
+func (conn *DeflateConn) NetConn() net.Conn {
+	return conn.Conn
+}
+
+func (conn *DeflateConn) Read(b []byte) (int, error) {
+	return conn.r.Read(b)
+}
+
+// Write compresses data and flushes it, so that the other end can decompress
+// it right away.
+func (conn *DeflateConn) Write(b []byte) (int, error) {
+	n, err := conn.w.Write(b)
+	if err != nil {
+		return n, err
+	}
+	return n, conn.w.Flush()
+}
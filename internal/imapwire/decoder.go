@@ -120,6 +120,23 @@ func (dec *Decoder) EOF() bool {
 	return false
 }
 
+// PeekFold returns true if the next bytes match s, ignoring ASCII case. The
+// bytes are not consumed.
+func (dec *Decoder) PeekFold(s string) bool {
+	if dec.literal {
+		return false
+	}
+	// Peek one byte at a time to avoid blocking on data which hasn't been
+	// sent by the other side
+	for i := 1; i <= len(s); i++ {
+		b, err := dec.r.Peek(i)
+		if err != nil || !strings.EqualFold(string(b[i-1]), s[i-1:i]) {
+			return false
+		}
+	}
+	return true
+}
+
 // Expect sets the decoder error if ok is false.
 func (dec *Decoder) Expect(ok bool, name string) bool {
 	if !ok {
@@ -551,6 +568,63 @@ func (dec *Decoder) ExpectUIDSet(ptr *imap.UIDSet) bool {
 	return ok
 }
 
+func (dec *Decoder) ExpectPartialRange(ptr *imap.PartialRange) bool {
+	var s string
+	if !dec.ExpectAtom(&s) {
+		return false
+	}
+	r, err := parsePartialRange(s)
+	if err != nil {
+		return dec.returnErr(err)
+	}
+	*ptr = r
+	return true
+}
+
+func parsePartialRange(s string) (imap.PartialRange, error) {
+	firstStr, lastStr, ok := strings.Cut(s, ":")
+	if !ok {
+		return imap.PartialRange{}, fmt.Errorf("imapwire: invalid partial range %q", s)
+	}
+	first, err := parsePartialRangeNum(firstStr)
+	if err != nil {
+		return imap.PartialRange{}, fmt.Errorf("imapwire: invalid partial range %q: %v", s, err)
+	}
+	last, err := parsePartialRangeNum(lastStr)
+	if err != nil {
+		return imap.PartialRange{}, fmt.Errorf("imapwire: invalid partial range %q: %v", s, err)
+	}
+	if first == 0 || last == 0 || (first < 0) != (last < 0) {
+		return imap.PartialRange{}, fmt.Errorf("imapwire: invalid partial range %q", s)
+	}
+
+	neg := first < 0
+	if neg {
+		first, last = -first, -last
+	}
+	if first > last {
+		first, last = last, first
+	}
+	r := imap.PartialRange{Offset: first - 1, Count: uint32(last - first + 1)}
+	if neg {
+		r.Offset = -first
+	}
+	return r, nil
+}
+
+// parsePartialRangeNum parses a 32-bit number, optionally negative.
+func parsePartialRangeNum(s string) (int64, error) {
+	neg := strings.HasPrefix(s, "-")
+	n, err := strconv.ParseUint(strings.TrimPrefix(s, "-"), 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	if neg {
+		return -int64(n), nil
+	}
+	return int64(n), nil
+}
+
 func isNumSetChar(ch byte) bool {
 	return ch == '*' || IsAtomChar(ch)
 }
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"math"
 	"strconv"
 	"strings"
 	"unicode"
@@ -167,6 +168,18 @@ func (enc *Encoder) NumSet(numSet imap.NumSet) *Encoder {
 	return enc.writeString(s)
 }
 
+func (enc *Encoder) PartialRange(r imap.PartialRange) *Encoder {
+	first, last := r.Offset+1, r.Offset+int64(r.Count)
+	if r.Offset < 0 {
+		first, last = r.Offset, r.Offset-int64(r.Count)+1
+	}
+	if r.Count == 0 || last > math.MaxUint32 || last < -math.MaxUint32 {
+		enc.setErr(fmt.Errorf("imapwire: invalid partial range %+v", r))
+		return enc
+	}
+	return enc.writeString(strconv.FormatInt(first, 10) + ":" + strconv.FormatInt(last, 10))
+}
+
 func (enc *Encoder) Flag(flag imap.Flag) *Encoder {
 	if flag != "\\*" && !isValidFlag(string(flag)) {
 		enc.setErr(fmt.Errorf("imapwire: invalid flag %q", flag))
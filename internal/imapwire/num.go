@@ -37,3 +37,8 @@ func ParseSeqSet(s string) (imap.SeqSet, error) {
 	numSet, err := imapnum.ParseSet(s)
 	return seqSetFromNumSet(numSet), err
 }
+
+func ParseUIDSet(s string) (imap.UIDSet, error) {
+	numSet, err := imapnum.ParseSet(s)
+	return uidSetFromNumSet(numSet), err
+}
@@ -0,0 +1,32 @@
+package imap
+
+// MultiSearchFilter selects the mailboxes searched by the ESEARCH command.
+type MultiSearchFilter string
+
+const (
+	MultiSearchFilterSelected   MultiSearchFilter = "selected"
+	MultiSearchFilterInboxes    MultiSearchFilter = "inboxes"
+	MultiSearchFilterPersonal   MultiSearchFilter = "personal"
+	MultiSearchFilterSubscribed MultiSearchFilter = "subscribed"
+	MultiSearchFilterSubtree    MultiSearchFilter = "subtree"
+	MultiSearchFilterSubtreeOne MultiSearchFilter = "subtree-one"
+	MultiSearchFilterMailboxes  MultiSearchFilter = "mailboxes"
+)
+
+// MultiSearchSource is a set of mailboxes for the ESEARCH command.
+type MultiSearchSource struct {
+	Filter MultiSearchFilter
+	// Mailboxes is only used with MultiSearchFilterSubtree,
+	// MultiSearchFilterSubtreeOne and MultiSearchFilterMailboxes
+	Mailboxes []string
+}
+
+// MultiSearchData is the data returned by the ESEARCH command for a single
+// mailbox.
+//
+// Messages are always identified by UID.
+type MultiSearchData struct {
+	Mailbox     string
+	UIDValidity uint32
+	Data        SearchData
+}
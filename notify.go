@@ -0,0 +1,57 @@
+package imap
+
+// NotifyEvent is an event which can be requested with the NOTIFY command.
+type NotifyEvent string
+
+const (
+	NotifyEventMessageNew            NotifyEvent = "MessageNew"
+	NotifyEventMessageExpunge        NotifyEvent = "MessageExpunge"
+	NotifyEventFlagChange            NotifyEvent = "FlagChange"
+	NotifyEventAnnotationChange      NotifyEvent = "AnnotationChange"
+	NotifyEventMailboxName           NotifyEvent = "MailboxName"
+	NotifyEventSubscriptionChange    NotifyEvent = "SubscriptionChange"
+	NotifyEventMailboxMetadataChange NotifyEvent = "MailboxMetadataChange" // requires METADATA
+	NotifyEventServerMetadataChange  NotifyEvent = "ServerMetadataChange"  // requires METADATA-SERVER
+)
+
+// NotifyFilter selects the mailboxes a NOTIFY event group applies to.
+type NotifyFilter string
+
+const (
+	NotifyFilterSelected        NotifyFilter = "selected"
+	NotifyFilterSelectedDelayed NotifyFilter = "selected-delayed"
+	NotifyFilterInboxes         NotifyFilter = "inboxes"
+	NotifyFilterPersonal        NotifyFilter = "personal"
+	NotifyFilterSubscribed      NotifyFilter = "subscribed"
+	NotifyFilterSubtree         NotifyFilter = "subtree"
+	NotifyFilterMailboxes       NotifyFilter = "mailboxes"
+)
+
+// NotifyOptions contains options for the NOTIFY SET command.
+type NotifyOptions struct {
+	// Immediately send a STATUS response for each mailbox with message events
+	Status bool
+	Groups []NotifyGroup
+}
+
+// NotifyGroup is an event group for the NOTIFY command.
+//
+// If a mailbox matches multiple groups, the first one takes precedence.
+type NotifyGroup struct {
+	Filter NotifyFilter
+	// Mailboxes is only used with NotifyFilterSubtree and
+	// NotifyFilterMailboxes
+	Mailboxes []string
+	// Events is empty if the client isn't interested in any event
+	Events []NotifyEvent
+}
+
+// Has checks whether the group contains an event.
+func (group *NotifyGroup) Has(event NotifyEvent) bool {
+	for _, ev := range group.Events {
+		if ev == event {
+			return true
+		}
+	}
+	return false
+}
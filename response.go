@@ -47,8 +47,27 @@ const (
 	ResponseCodeTooMany   ResponseCode = "TOOMANY"
 	ResponseCodeNoPrivate ResponseCode = "NOPRIVATE"
 
+	// COMPRESS
+	ResponseCodeCompressionActive ResponseCode = "COMPRESSIONACTIVE"
+
 	// APPENDLIMIT
 	ResponseCodeTooBig ResponseCode = "TOOBIG"
+
+	// NOTIFY
+	ResponseCodeBadEvent             ResponseCode = "BADEVENT"
+	ResponseCodeNotificationOverflow ResponseCode = "NOTIFICATIONOVERFLOW"
+
+	// CATENATE
+	ResponseCodeBadURL ResponseCode = "BADURL"
+
+	// SPECIAL-USE
+	ResponseCodeUseAttr ResponseCode = "USEATTR"
+
+	// FILTERS
+	ResponseCodeUndefinedFilter ResponseCode = "UNDEFINED-FILTER"
+
+	// UIDONLY
+	ResponseCodeUIDRequired ResponseCode = "UIDREQUIRED"
 )
 
 // StatusResponse is a generic status response.
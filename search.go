@@ -14,6 +14,26 @@ type SearchOptions struct {
 	ReturnCount bool
 	// Requires IMAP4rev2 or SEARCHRES
 	ReturnSave bool
+	// Requires SEARCH=FUZZY
+	ReturnRelevancy bool
+	// Requires PARTIAL
+	ReturnPartial *PartialRange
+	// Requires CONTEXT=SEARCH or CONTEXT=SORT
+	ReturnUpdate bool
+}
+
+// PartialRange is a window into a list of messages.
+type PartialRange struct {
+	// Offset is the position of the first message of the window. A positive
+	// Offset is zero-based and counts from the start of the list: 0 is the
+	// first message. A negative Offset counts backwards from the end of the
+	// list: -1 is the last message. For instance, an Offset of -1 and a Count
+	// of 10 selects the last 10 messages.
+	//
+	// The absolute value of Offset must fit in 32 bits.
+	Offset int64
+	// Count is the number of messages in the window, it must be non-zero.
+	Count uint32
 }
 
 // SearchCriteria is a criteria for the SEARCH command.
@@ -35,6 +55,9 @@ type SearchOptions struct {
 //		{Body: []string{"hello"}},
 //		{Body: []string{"world"}},
 //	}}}
+//
+// Fuzzy matches messages which approximately match a criteria. The server
+// decides how approximate matching works.
 type SearchCriteria struct {
 	SeqNum []SeqSet
 	UID    []UIDSet
@@ -45,6 +68,11 @@ type SearchCriteria struct {
 	SentSince  time.Time
 	SentBefore time.Time
 
+	// Requires WITHIN, relative to the server's current time and rounded to
+	// seconds
+	Older   time.Duration
+	Younger time.Duration
+
 	Header []SearchCriteriaHeaderField
 	Body   []string
 	Text   []string
@@ -55,10 +83,23 @@ type SearchCriteria struct {
 	Larger  int64
 	Smaller int64
 
-	Not []SearchCriteria
-	Or  [][2]SearchCriteria
+	Not   []SearchCriteria
+	Or    [][2]SearchCriteria
+	Fuzzy []SearchCriteria // requires SEARCH=FUZZY
 
 	ModSeq *SearchCriteriaModSeq // requires CONDSTORE
+
+	// Requires OBJECTID
+	EmailID  []string
+	ThreadID []string
+
+	// Requires SAVEDATE, only the date is used
+	SavedSince        time.Time
+	SavedBefore       time.Time
+	SaveDateSupported bool
+
+	// Requires FILTERS, names of filters defined via METADATA
+	Filter []string
 }
 
 // And intersects two search criteria.
@@ -71,6 +112,13 @@ func (criteria *SearchCriteria) And(other *SearchCriteria) {
 	criteria.SentSince = intersectSince(criteria.SentSince, other.SentSince)
 	criteria.SentBefore = intersectBefore(criteria.SentBefore, other.SentBefore)
 
+	if other.Older > criteria.Older {
+		criteria.Older = other.Older
+	}
+	if criteria.Younger == 0 || (other.Younger != 0 && other.Younger < criteria.Younger) {
+		criteria.Younger = other.Younger
+	}
+
 	criteria.Header = append(criteria.Header, other.Header...)
 	criteria.Body = append(criteria.Body, other.Body...)
 	criteria.Text = append(criteria.Text, other.Text...)
@@ -81,12 +129,22 @@ func (criteria *SearchCriteria) And(other *SearchCriteria) {
 	if criteria.Larger == 0 || other.Larger > criteria.Larger {
 		criteria.Larger = other.Larger
 	}
-	if criteria.Smaller == 0 || other.Smaller < criteria.Smaller {
+	if criteria.Smaller == 0 || (other.Smaller != 0 && other.Smaller < criteria.Smaller) {
 		criteria.Smaller = other.Smaller
 	}
 
 	criteria.Not = append(criteria.Not, other.Not...)
 	criteria.Or = append(criteria.Or, other.Or...)
+	criteria.Fuzzy = append(criteria.Fuzzy, other.Fuzzy...)
+
+	criteria.EmailID = append(criteria.EmailID, other.EmailID...)
+	criteria.ThreadID = append(criteria.ThreadID, other.ThreadID...)
+
+	criteria.SavedSince = intersectSince(criteria.SavedSince, other.SavedSince)
+	criteria.SavedBefore = intersectBefore(criteria.SavedBefore, other.SavedBefore)
+	criteria.SaveDateSupported = criteria.SaveDateSupported || other.SaveDateSupported
+
+	criteria.Filter = append(criteria.Filter, other.Filter...)
 }
 
 func intersectSince(t1, t2 time.Time) time.Time {
@@ -145,6 +203,42 @@ type SearchData struct {
 
 	// requires CONDSTORE
 	ModSeq uint64
+
+	// requires SEARCH=FUZZY, one score between 1 and 100 for each message in
+	// All, in ascending order
+	Relevancy []uint8
+
+	// requires PARTIAL
+	Partial *SearchPartialData
+}
+
+// SearchPartialData is the data returned for the PARTIAL search return
+// option.
+type SearchPartialData struct {
+	Range PartialRange
+	All   NumSet // nil or empty if no message is in the range
+}
+
+// SearchUpdate is an update of the result of a SEARCH or SORT command which
+// used the UPDATE return option.
+//
+// Messages in RemoveFrom need to be removed first, then messages in AddTo need
+// to be added.
+type SearchUpdate struct {
+	Tag        string // tag of the SEARCH or SORT command
+	UID        bool
+	AddTo      []SearchUpdateRange
+	RemoveFrom []SearchUpdateRange
+}
+
+// SearchUpdateRange is a list of messages added to or removed from a search
+// result.
+//
+// For SORT, Position is the one-based position of the first message in the
+// result. For SEARCH, Position is zero.
+type SearchUpdateRange struct {
+	Position uint32
+	Nums     []uint32
 }
 
 // AllSeqNums returns All as a slice of sequence numbers.
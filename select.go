@@ -3,7 +3,26 @@ package imap
 // SelectOptions contains options for the SELECT or EXAMINE command.
 type SelectOptions struct {
 	ReadOnly  bool
-	CondStore bool // requires CONDSTORE
+	CondStore bool           // requires CONDSTORE
+	QResync   *SelectQResync // requires QRESYNC
+}
+
+// SelectQResync contains the QRESYNC parameter of the SELECT command.
+//
+// UIDValidity and ModSeq are the last known values for the mailbox. They are
+// mandatory, the other fields are optional.
+type SelectQResync struct {
+	UIDValidity uint32
+	ModSeq      uint64
+	KnownUIDs   UIDSet
+	SeqMatch    *SelectQResyncSeqMatch
+}
+
+// SelectQResyncSeqMatch contains message sequence number to UID mappings
+// known by the client.
+type SelectQResyncSeqMatch struct {
+	SeqNums SeqSet
+	UIDs    UIDSet
 }
 
 // SelectData is the data returned by a SELECT command.
@@ -22,4 +41,5 @@ type SelectData struct {
 	List *ListData // requires IMAP4rev2
 
 	HighestModSeq uint64 // requires CONDSTORE
+	MailboxID     string // requires OBJECTID
 }
@@ -12,6 +12,7 @@ type StatusOptions struct {
 	AppendLimit    bool // requires APPENDLIMIT
 	DeletedStorage bool // requires QUOTA=RES-STORAGE
 	HighestModSeq  bool // requires CONDSTORE
+	MailboxID      bool // requires OBJECTID
 }
 
 // StatusData is the data returned by a STATUS command.
@@ -30,4 +31,5 @@ type StatusData struct {
 	AppendLimit    *uint32
 	DeletedStorage *int64
 	HighestModSeq  uint64
+	MailboxID      string
 }
@@ -7,3 +7,13 @@ const (
 	ThreadOrderedSubject ThreadAlgorithm = "ORDEREDSUBJECT"
 	ThreadReferences     ThreadAlgorithm = "REFERENCES"
 )
+
+// ThreadData represents a thread returned by the THREAD command.
+//
+// Chain contains the message numbers of a thread branch, each message being
+// the parent of the next one. SubThreads contains the children of the last
+// message of Chain, if any.
+type ThreadData struct {
+	Chain      []uint32
+	SubThreads []ThreadData
+}
@@ -0,0 +1,362 @@
+package imap
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// URL is an IMAP URL referencing a message or a message section.
+//
+// See RFC 5092.
+type URL struct {
+	// Server, empty for relative URLs
+	User string
+	Host string // host[:port]
+
+	// Mailbox is empty for URLs relative to the selected mailbox
+	Mailbox     string
+	UIDValidity uint32 // optional
+	UID         UID
+	Section     *FetchItemBodySection // optional, Peek is ignored
+
+	// requires URLAUTH
+	Expire    time.Time        // optional
+	Access    string           // e.g. "submit+fred", "user+fred", "authuser" or "anonymous"
+	Mechanism URLAuthMechanism // empty for URL rumps
+	Token     string           // hex-encoded
+}
+
+// URLAuthMechanism is a URLAUTH authorization mechanism.
+type URLAuthMechanism string
+
+const (
+	URLAuthMechanismInternal URLAuthMechanism = "INTERNAL"
+)
+
+// ParseURL parses an IMAP URL.
+//
+// Absolute URLs ("imap://host/mailbox/;UID=1") and relative URLs
+// ("/mailbox/;UID=1" and "/;UID=1") are accepted.
+func ParseURL(s string) (*URL, error) {
+	var u URL
+
+	const scheme = "imap://"
+	if len(s) >= len(scheme) && strings.EqualFold(s[:len(scheme)], scheme) {
+		s = s[len(scheme):]
+
+		authority := s
+		if i := strings.IndexByte(s, '/'); i >= 0 {
+			authority, s = s[:i], s[i:]
+		} else {
+			s = ""
+		}
+
+		if i := strings.LastIndexByte(authority, '@'); i >= 0 {
+			user := authority[:i]
+			authority = authority[i+1:]
+			// Strip the optional ";AUTH=" part
+			if j := strings.IndexByte(user, ';'); j >= 0 {
+				user = user[:j]
+			}
+			var err error
+			if u.User, err = urlUnescape(user); err != nil {
+				return nil, fmt.Errorf("imap: invalid URL user: %v", err)
+			}
+		}
+		if authority == "" {
+			return nil, fmt.Errorf("imap: missing URL host")
+		}
+		u.Host = authority
+	}
+
+	if s == "" {
+		return &u, nil
+	} else if s[0] != '/' {
+		return nil, fmt.Errorf("imap: invalid URL path")
+	}
+
+	params := strings.Split(s[1:], ";")
+	for i := 0; i < len(params)-1; i++ {
+		// Parameters may be separated by a slash
+		params[i] = strings.TrimSuffix(params[i], "/")
+	}
+
+	var err error
+	if u.Mailbox, err = urlUnescape(params[0]); err != nil {
+		return nil, fmt.Errorf("imap: invalid URL mailbox: %v", err)
+	}
+
+	for _, param := range params[1:] {
+		if u.Access != "" {
+			return nil, fmt.Errorf("imap: URLAUTH must be the last URL component")
+		}
+		k, v, ok := strings.Cut(param, "=")
+		if !ok {
+			return nil, fmt.Errorf("imap: invalid URL parameter %q", param)
+		}
+		switch strings.ToUpper(k) {
+		case "UIDVALIDITY":
+			n, err := strconv.ParseUint(v, 10, 32)
+			if err != nil || n == 0 {
+				return nil, fmt.Errorf("imap: invalid URL UIDVALIDITY %q", v)
+			}
+			u.UIDValidity = uint32(n)
+		case "UID":
+			n, err := strconv.ParseUint(v, 10, 32)
+			if err != nil || n == 0 {
+				return nil, fmt.Errorf("imap: invalid URL UID %q", v)
+			}
+			u.UID = UID(n)
+		case "SECTION":
+			v, err := urlUnescape(v)
+			if err != nil {
+				return nil, fmt.Errorf("imap: invalid URL section: %v", err)
+			}
+			section, err := parseURLSection(v)
+			if err != nil {
+				return nil, err
+			}
+			if u.Section != nil {
+				section.Partial = u.Section.Partial
+			}
+			u.Section = section
+		case "PARTIAL":
+			partial, err := parseURLPartial(v)
+			if err != nil {
+				return nil, err
+			}
+			if u.Section == nil {
+				u.Section = &FetchItemBodySection{}
+			}
+			u.Section.Partial = partial
+		case "EXPIRE":
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return nil, fmt.Errorf("imap: invalid URL expiration: %v", err)
+			}
+			u.Expire = t
+		case "URLAUTH":
+			l := strings.Split(v, ":")
+			if len(l) != 1 && len(l) != 3 {
+				return nil, fmt.Errorf("imap: invalid URLAUTH %q", v)
+			}
+			if u.Access, err = urlUnescape(l[0]); err != nil || u.Access == "" {
+				return nil, fmt.Errorf("imap: invalid URLAUTH access identifier %q", l[0])
+			}
+			if len(l) == 3 {
+				u.Mechanism = URLAuthMechanism(strings.ToUpper(l[1]))
+				u.Token = l[2]
+				if u.Mechanism == "" || u.Token == "" {
+					return nil, fmt.Errorf("imap: invalid URLAUTH %q", v)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("imap: unknown URL parameter %q", k)
+		}
+	}
+
+	return &u, nil
+}
+
+// String formats the URL.
+func (u *URL) String() string {
+	var sb strings.Builder
+	if u.Host != "" {
+		sb.WriteString("imap://")
+		if u.User != "" {
+			sb.WriteString(urlEscape(u.User))
+			sb.WriteByte('@')
+		}
+		sb.WriteString(u.Host)
+	}
+
+	sb.WriteByte('/')
+	sb.WriteString(urlEscape(u.Mailbox))
+	if u.UIDValidity != 0 {
+		fmt.Fprintf(&sb, ";UIDVALIDITY=%v", u.UIDValidity)
+	}
+	if u.UID != 0 {
+		if u.Mailbox != "" {
+			sb.WriteByte('/')
+		}
+		fmt.Fprintf(&sb, ";UID=%v", u.UID)
+	}
+	if u.Section != nil {
+		if section := formatURLSection(u.Section); section != "" {
+			sb.WriteString("/;SECTION=")
+			sb.WriteString(urlEscape(section))
+		}
+		if partial := u.Section.Partial; partial != nil {
+			fmt.Fprintf(&sb, "/;PARTIAL=%v", partial.Offset)
+			if partial.Size > 0 {
+				fmt.Fprintf(&sb, ".%v", partial.Size)
+			}
+		}
+	}
+	if !u.Expire.IsZero() {
+		sb.WriteString(";EXPIRE=")
+		sb.WriteString(u.Expire.Format(time.RFC3339))
+	}
+	if u.Access != "" {
+		sb.WriteString(";URLAUTH=")
+		sb.WriteString(urlEscape(u.Access))
+		if u.Mechanism != "" {
+			fmt.Fprintf(&sb, ":%v:%v", u.Mechanism, u.Token)
+		}
+	}
+	return sb.String()
+}
+
+func parseURLSection(s string) (*FetchItemBodySection, error) {
+	var section FetchItemBodySection
+
+	spec, headerList, hasHeaderList := strings.Cut(s, " ")
+
+	l := strings.Split(spec, ".")
+	for len(l) > 0 {
+		n, err := strconv.ParseUint(l[0], 10, 31)
+		if err != nil {
+			break
+		}
+		if n == 0 {
+			return nil, fmt.Errorf("imap: invalid URL section part %q", l[0])
+		}
+		section.Part = append(section.Part, int(n))
+		l = l[1:]
+	}
+
+	switch specifier := strings.ToUpper(strings.Join(l, ".")); specifier {
+	case "":
+		// nothing to do
+	case string(PartSpecifierHeader), string(PartSpecifierText):
+		section.Specifier = PartSpecifier(specifier)
+	case string(PartSpecifierMIME):
+		if len(section.Part) == 0 {
+			return nil, fmt.Errorf("imap: URL section MIME requires a part number")
+		}
+		section.Specifier = PartSpecifierMIME
+	case "HEADER.FIELDS", "HEADER.FIELDS.NOT":
+		if !hasHeaderList {
+			return nil, fmt.Errorf("imap: missing URL section header list")
+		}
+		headerList = strings.TrimSpace(headerList)
+		if !strings.HasPrefix(headerList, "(") || !strings.HasSuffix(headerList, ")") {
+			return nil, fmt.Errorf("imap: invalid URL section header list")
+		}
+		var fields []string
+		for _, field := range strings.Fields(headerList[1 : len(headerList)-1]) {
+			fields = append(fields, strings.Trim(field, `"`))
+		}
+		section.Specifier = PartSpecifierHeader
+		if specifier == "HEADER.FIELDS" {
+			section.HeaderFields = fields
+		} else {
+			section.HeaderFieldsNot = fields
+		}
+		return &section, nil
+	default:
+		return nil, fmt.Errorf("imap: unknown URL section specifier %q", specifier)
+	}
+
+	if hasHeaderList {
+		return nil, fmt.Errorf("imap: unexpected URL section header list")
+	}
+	return &section, nil
+}
+
+func formatURLSection(section *FetchItemBodySection) string {
+	var l []string
+	for _, part := range section.Part {
+		l = append(l, strconv.Itoa(part))
+	}
+
+	specifier := string(section.Specifier)
+	var fields []string
+	if len(section.HeaderFields) > 0 {
+		specifier = "HEADER.FIELDS"
+		fields = section.HeaderFields
+	} else if len(section.HeaderFieldsNot) > 0 {
+		specifier = "HEADER.FIELDS.NOT"
+		fields = section.HeaderFieldsNot
+	}
+	if specifier != "" {
+		l = append(l, specifier)
+	}
+
+	s := strings.Join(l, ".")
+	if fields != nil {
+		s += " (" + strings.Join(fields, " ") + ")"
+	}
+	return s
+}
+
+func parseURLPartial(s string) (*SectionPartial, error) {
+	offsetStr, sizeStr, hasSize := strings.Cut(s, ".")
+	offset, err := strconv.ParseInt(offsetStr, 10, 64)
+	if err != nil || offset < 0 {
+		return nil, fmt.Errorf("imap: invalid URL partial offset %q", offsetStr)
+	}
+	partial := &SectionPartial{Offset: offset}
+	if hasSize {
+		size, err := strconv.ParseInt(sizeStr, 10, 64)
+		if err != nil || size <= 0 {
+			return nil, fmt.Errorf("imap: invalid URL partial length %q", sizeStr)
+		}
+		partial.Size = size
+	}
+	return partial, nil
+}
+
+// urlEscape percent-encodes a string, leaving characters allowed in IMAP URL
+// path components (bchar) as-is.
+func urlEscape(s string) string {
+	const hex = "0123456789ABCDEF"
+
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if isURLChar(ch) {
+			sb.WriteByte(ch)
+		} else {
+			sb.WriteByte('%')
+			sb.WriteByte(hex[ch>>4])
+			sb.WriteByte(hex[ch&0xF])
+		}
+	}
+	return sb.String()
+}
+
+func isURLChar(ch byte) bool {
+	switch {
+	case ch >= 'a' && ch <= 'z', ch >= 'A' && ch <= 'Z', ch >= '0' && ch <= '9':
+		return true
+	}
+	return strings.IndexByte("-._~!$'()*+,&=:@/", ch) >= 0
+}
+
+func urlUnescape(s string) (string, error) {
+	if strings.IndexByte(s, '%') < 0 {
+		return s, nil
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if ch != '%' {
+			sb.WriteByte(ch)
+			continue
+		}
+		if i+2 >= len(s) {
+			return "", fmt.Errorf("truncated percent-encoding")
+		}
+		v, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+		if err != nil {
+			return "", fmt.Errorf("invalid percent-encoding %q", s[i:i+3])
+		}
+		sb.WriteByte(byte(v))
+		i += 2
+	}
+	return sb.String(), nil
+}